@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vovere/internal/app/services"
+)
+
+// runBackup writes a zip archive of a repository.
+// It returns 0 on success and 2 on errors.
+func runBackup(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("o", "", "Archive to write, defaults to <name>-<time>.zip in the current directory")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: vovere backup [-o archive] <repository>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		fmt.Fprintf(stderr, "%s is not a directory\n", path)
+		return 2
+	}
+	repo := services.NewRepository(path)
+
+	archive := *output
+	if archive == "" {
+		config, err := repo.Config()
+		if err != nil {
+			fmt.Fprintf(stderr, "backup failed: %v\n", err)
+			return 2
+		}
+		archive = fmt.Sprintf("%s-%s.zip", filepath.Base(config.Name), time.Now().UTC().Format("20060102-150405"))
+	}
+
+	if err := services.NewBackupService(repo).WriteFile(archive); err != nil {
+		fmt.Fprintf(stderr, "backup failed: %v\n", err)
+		return 2
+	}
+	fmt.Fprintf(stdout, "Backed up %s to %s\n", path, archive)
+	return 0
+}
+
+// runRestore unpacks a backup archive into a new repository directory.
+// It returns 0 on success and 2 on errors, including archives that fail validation.
+func runRestore(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: vovere restore <archive> <directory>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	if err := services.RestoreBackup(flags.Arg(0), flags.Arg(1)); err != nil {
+		fmt.Fprintf(stderr, "restore failed: %v\n", err)
+		return 2
+	}
+	fmt.Fprintf(stdout, "Restored %s to %s\n", flags.Arg(0), flags.Arg(1))
+	return 0
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"vovere/internal/app/services"
+)
+
+// runExportSite writes a repository as a static HTML site.
+// It returns 0 on success, 1 when items were skipped and 2 on errors.
+func runExportSite(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export-site", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	tags := flags.String("tag", "", "Only export items with one of these comma-separated tags, e.g. public")
+	name := flags.String("name", "", "Site name shown on every page, defaults to the repository name")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: vovere export-site [-tag tags] [-name name] <repository> <directory>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		fmt.Fprintf(stderr, "%s is not a directory\n", path)
+		return 2
+	}
+
+	options := services.SiteOptions{Name: *name}
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimLeft(strings.TrimSpace(tag), "#"); tag != "" {
+			options.Tags = append(options.Tags, tag)
+		}
+	}
+
+	repo := services.NewRepository(path)
+	if err := repo.CheckSchema(); err != nil {
+		fmt.Fprintf(stderr, "export-site failed: %v\n", err)
+		return 2
+	}
+	report, err := services.NewExportService(repo).ExportSite(flags.Arg(1), options)
+	if err != nil {
+		fmt.Fprintf(stderr, "export-site failed: %v\n", err)
+		return 2
+	}
+
+	fmt.Fprintf(stdout, "Exported %d items, %d tags and %d files to %s\n", report.Items, report.Tags, report.Files, flags.Arg(1))
+	if report.Encrypted > 0 {
+		fmt.Fprintf(stdout, "Left out %d encrypted items\n", report.Encrypted)
+	}
+	if len(report.Skipped) > 0 {
+		fmt.Fprintf(stdout, "\nSkipped %d unreadable items, run fsck to check them\n", len(report.Skipped))
+		for _, item := range report.Skipped {
+			fmt.Fprintf(stdout, "  %s\n", item)
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"vovere/internal/app/services"
+)
+
+// runFsck checks a repository for inconsistencies and optionally repairs them.
+// It returns 0 when the repository is consistent, 1 when issues remain and 2 on errors.
+func runFsck(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	repair := flags.Bool("repair", false, "Repair issues and rebuild the tag index from content")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: vovere fsck [-repair] <repository>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		fmt.Fprintf(stderr, "%s is not a directory\n", path)
+		return 2
+	}
+
+	fsck := services.NewFsckService(services.NewRepository(path))
+	check := fsck.Check
+	if *repair {
+		check = fsck.Repair
+	}
+	report, err := check()
+	if err != nil {
+		fmt.Fprintf(stderr, "fsck failed: %v\n", err)
+		return 2
+	}
+
+	printFsckReport(stdout, path, report)
+
+	if report.Remaining() > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printFsckReport writes the issues of a report grouped by category
+func printFsckReport(w io.Writer, path string, report *services.FsckReport) {
+	fmt.Fprintf(w, "Checked %d items in %s\n", report.Items, path)
+
+	groups := report.ByCategory()
+	categories := make([]string, 0, len(groups))
+	for category := range groups {
+		categories = append(categories, string(category))
+	}
+	sort.Strings(categories)
+
+	for _, category := range categories {
+		issues := groups[services.IssueCategory(category)]
+		fmt.Fprintf(w, "\n%s (%d)\n", category, len(issues))
+		for _, issue := range issues {
+			status := ""
+			if issue.Repaired {
+				status = " [repaired]"
+			}
+			fmt.Fprintf(w, "  %s: %s%s\n", issue.Path, issue.Message, status)
+		}
+	}
+
+	switch remaining := report.Remaining(); {
+	case len(report.Issues) == 0:
+		fmt.Fprintln(w, "\nNo issues found")
+	case remaining == 0:
+		fmt.Fprintf(w, "\nRepaired %d issues\n", len(report.Issues))
+	case report.Repaired:
+		fmt.Fprintf(w, "\n%d of %d issues could not be repaired\n", remaining, len(report.Issues))
+	default:
+		fmt.Fprintf(w, "\n%d issues found, run with -repair to fix them\n", remaining)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"vovere/internal/app/services"
+)
+
+// runImport imports an Obsidian vault or a folder of markdown files into a repository.
+// It returns 0 when everything was imported, 1 when files were skipped or links left
+// unresolved, and 2 on errors.
+func runImport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	jsonOutput := flags.Bool("json", false, "Print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: vovere import [-json] <repository> <directory>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		fmt.Fprintf(stderr, "%s is not a directory\n", path)
+		return 2
+	}
+
+	repo := services.NewRepository(path)
+	if err := repo.CheckSchema(); err != nil {
+		fmt.Fprintf(stderr, "import failed: %v\n", err)
+		return 2
+	}
+	report, err := services.NewImportService(repo).ImportDirectory(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "import failed: %v\n", err)
+		return 2
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		printImportReport(stdout, flags.Arg(1), report)
+	}
+
+	if len(report.Skipped) > 0 || len(report.Unresolved) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printImportReport writes the number of imported items, the skipped files and the unresolved links
+func printImportReport(w io.Writer, dir string, report *services.ImportReport) {
+	fmt.Fprintf(w, "Imported %d notes and %d files from %s\n", report.Notes, report.Files, dir)
+
+	if len(report.Skipped) > 0 {
+		fmt.Fprintf(w, "\nSkipped %d files\n", len(report.Skipped))
+		for _, skipped := range report.Skipped {
+			fmt.Fprintf(w, "  %s: %s\n", skipped.Path, skipped.Reason)
+		}
+	}
+
+	if len(report.Unresolved) > 0 {
+		fmt.Fprintf(w, "\n%d unresolved links, left as written\n", len(report.Unresolved))
+		for _, link := range report.Unresolved {
+			fmt.Fprintf(w, "  %s: [[%s]]\n", link.Source, link.Target)
+		}
+	}
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -19,8 +24,14 @@ import (
 	"vovere/internal/app/services"
 )
 
+// shutdownTimeout is how long requests in flight get to finish when the server stops
+const shutdownTimeout = 10 * time.Second
+
 var (
-	port = flag.Int("port", 9090, "Port to run the server on")
+	port          = flag.Int("port", 9090, "Port to run the server on")
+	watchInterval = flag.Duration("watch-interval", 2*time.Second, "How often repositories are checked for external changes, 0 disables it")
+	repositories  = flag.String("repositories", "", "File listing the known repositories, vovere/repositories.json in the user config directory by default")
+	allowedRoots  = flag.String("allowed-roots", "", "Comma-separated directories repositories must be under, the user's home directory when empty")
 )
 
 // customErrorHandler wraps the notFound handler to use custom error pages
@@ -53,44 +64,53 @@ func customErrorHandler(tmpl *template.Template) func(http.Handler) http.Handler
 	}
 }
 
-// repositoryMiddleware ensures a repository is selected
-func repositoryMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("repository")
-		if err != nil || cookie.Value == "" {
-			http.Redirect(w, r, "/api/repository", http.StatusSeeOther)
-			return
-		}
+// repositoryMiddleware ensures a repository is selected. The cookie holds the ID of a
+// known repository, its path comes from the server's repository store.
+func repositoryMiddleware(store *services.RepositoryStore, registry *services.Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie("repository")
+			if err != nil || cookie.Value == "" {
+				http.Redirect(w, r, "/api/repository", http.StatusSeeOther)
+				return
+			}
 
-		// Initialize repository service
-		repo := services.NewRepository(cookie.Value)
+			// Reuse the repository service, and its caches, opened by earlier requests
+			known, err := store.Get(cookie.Value)
+			var repo *services.Repository
+			if err == nil {
+				repo, err = registry.Open(known.Path)
+			}
+			if err != nil {
+				// Forget the repository, or the selection screen sends us straight back
+				http.SetCookie(w, &http.Cookie{
+					Name:     "repository",
+					Value:    "",
+					Path:     "/",
+					MaxAge:   -1,
+					HttpOnly: true,
+					SameSite: http.SameSiteStrictMode,
+				})
+				http.Redirect(w, r, "/api/repository?error="+url.QueryEscape("Cannot open repository: "+err.Error()), http.StatusSeeOther)
+				return
+			}
 
-		// Store repository service in context
-		ctx := services.WithRepository(r.Context(), repo)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+			// Store repository service in context
+			ctx := services.WithRepository(r.Context(), repo)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
 // getRepositoryName gets the name of the repository from config or path
 func getRepositoryName(repoPath string) string {
 	// Default repository name is the last part of the path
-	repoName := filepath.Base(repoPath)
-
-	// Try to load config file for custom name
-	configPath := filepath.Join(repoPath, "config.json")
-	if configFile, err := os.Open(configPath); err == nil {
-		defer configFile.Close()
-
-		// Parse config to get name
-		var config handlers.RepositoryConfig
-		if err := json.NewDecoder(configFile).Decode(&config); err == nil {
-			if config.Name != "" {
-				repoName = config.Name
-			}
-		}
+	config, err := services.NewRepository(repoPath).Config()
+	if err != nil {
+		return filepath.Base(repoPath)
 	}
 
-	return repoName
+	return config.Name
 }
 
 // itemHandler wraps the item handler with repository context
@@ -116,6 +136,24 @@ func (h *dashboardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	// Subcommands work on a repository path instead of starting the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fsck":
+			os.Exit(runFsck(os.Args[2:], os.Stdout, os.Stderr))
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:], os.Stdout, os.Stderr))
+		case "import":
+			os.Exit(runImport(os.Args[2:], os.Stdout, os.Stderr))
+		case "export-site":
+			os.Exit(runExportSite(os.Args[2:], os.Stdout, os.Stderr))
+		case "backup":
+			os.Exit(runBackup(os.Args[2:], os.Stdout, os.Stderr))
+		case "restore":
+			os.Exit(runRestore(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	flag.Parse()
 
 	// Setup router
@@ -141,14 +179,39 @@ func main() {
 	filesDir := filepath.Join(workDir, "web/static")
 	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir(filesDir))))
 
+	// Known repositories are kept by the server, browsers only see their IDs
+	storePath := *repositories
+	if storePath == "" {
+		var err error
+		if storePath, err = services.DefaultRepositoryStorePath(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var roots []string
+	for _, root := range strings.Split(*allowedRoots, ",") {
+		if root = strings.TrimSpace(root); root != "" {
+			roots = append(roots, root)
+		}
+	}
+	if len(roots) == 0 {
+		var err error
+		if roots, err = services.DefaultAllowedRoots(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	store := services.NewRepositoryStore(storePath, roots)
+
 	// Repository selection handler
-	repoHandler := handlers.NewRepositoryHandler(tmpl)
+	repoHandler := handlers.NewRepositoryHandler(tmpl, store)
 	r.Mount("/api/repository", repoHandler.Routes())
 
+	// Repositories live as long as the server, edits made outside Vovere are picked up by their watchers
+	registry := services.NewRegistry(*watchInterval)
+
 	// Main application routes
 	r.Group(func(r chi.Router) {
 		// Add repository middleware
-		r.Use(repositoryMiddleware)
+		r.Use(repositoryMiddleware(store, registry))
 
 		// Dashboard - shows recent items of all types
 		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -157,6 +220,7 @@ func main() {
 
 			data := map[string]interface{}{
 				"RepositoryName": repoName,
+				"Types":          repo.Types(),
 				"PageTitle":      "Home",
 				"ViewType":       "dashboard",
 			}
@@ -167,16 +231,28 @@ func main() {
 			}
 		})
 
-		// Type-specific listing pages
-		r.Get("/notes", func(w http.ResponseWriter, r *http.Request) {
+		// Listing pages of every item type, such as /notes or the /meetings of a declared type
+		r.Get("/{types}", func(w http.ResponseWriter, r *http.Request) {
 			repo := services.RepositoryFromContext(r.Context())
 			repoName := getRepositoryName(repo.BasePath())
 
+			name, ok := strings.CutSuffix(chi.URLParam(r, "types"), "s")
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			def, err := repo.Type(models.ItemType(name))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+
 			data := map[string]interface{}{
 				"RepositoryName": repoName,
-				"PageTitle":      "Notes",
+				"Types":          repo.Types(),
+				"PageTitle":      def.Plural,
 				"ViewType":       "list",
-				"ItemType":       models.TypeNote,
+				"ItemType":       def.Name,
 			}
 
 			if err := tmpl.ExecuteTemplate(w, "index.html", data); err != nil {
@@ -185,15 +261,15 @@ func main() {
 			}
 		})
 
-		r.Get("/bookmarks", func(w http.ResponseWriter, r *http.Request) {
+		r.Get("/inbox", func(w http.ResponseWriter, r *http.Request) {
 			repo := services.RepositoryFromContext(r.Context())
 			repoName := getRepositoryName(repo.BasePath())
 
 			data := map[string]interface{}{
 				"RepositoryName": repoName,
-				"PageTitle":      "Bookmarks",
-				"ViewType":       "list",
-				"ItemType":       models.TypeBookmark,
+				"Types":          repo.Types(),
+				"PageTitle":      "Inbox",
+				"ViewType":       "inbox",
 			}
 
 			if err := tmpl.ExecuteTemplate(w, "index.html", data); err != nil {
@@ -202,15 +278,15 @@ func main() {
 			}
 		})
 
-		r.Get("/tasks", func(w http.ResponseWriter, r *http.Request) {
+		r.Get("/trash", func(w http.ResponseWriter, r *http.Request) {
 			repo := services.RepositoryFromContext(r.Context())
 			repoName := getRepositoryName(repo.BasePath())
 
 			data := map[string]interface{}{
 				"RepositoryName": repoName,
-				"PageTitle":      "Tasks",
-				"ViewType":       "list",
-				"ItemType":       models.TypeTask,
+				"Types":          repo.Types(),
+				"PageTitle":      "Trash",
+				"ViewType":       "trash",
 			}
 
 			if err := tmpl.ExecuteTemplate(w, "index.html", data); err != nil {
@@ -219,15 +295,16 @@ func main() {
 			}
 		})
 
-		r.Get("/workstreams", func(w http.ResponseWriter, r *http.Request) {
+		r.Get("/graph", func(w http.ResponseWriter, r *http.Request) {
 			repo := services.RepositoryFromContext(r.Context())
 			repoName := getRepositoryName(repo.BasePath())
 
 			data := map[string]interface{}{
 				"RepositoryName": repoName,
-				"PageTitle":      "Workstreams",
-				"ViewType":       "list",
-				"ItemType":       models.TypeWorkstream,
+				"Types":          repo.Types(),
+				"PageTitle":      "Graph",
+				"ViewType":       "graph",
+				"Focus":          r.URL.Query().Get("focus"),
 			}
 
 			if err := tmpl.ExecuteTemplate(w, "index.html", data); err != nil {
@@ -240,9 +317,14 @@ func main() {
 		r.Get("/items/{type}/{id}", func(w http.ResponseWriter, r *http.Request) {
 			repo := services.RepositoryFromContext(r.Context())
 			repoName := getRepositoryName(repo.BasePath())
+			if err := repo.ValidateItemRef(chi.URLParam(r, "id"), models.ItemType(chi.URLParam(r, "type"))); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
 			data := map[string]interface{}{
 				"RepositoryName": repoName,
+				"Types":          repo.Types(),
 				"ViewType":       "detail",
 				"ItemID":         chi.URLParam(r, "id"),
 				"ItemType":       chi.URLParam(r, "type"),
@@ -257,9 +339,14 @@ func main() {
 		r.Get("/items/{type}/{id}/edit", func(w http.ResponseWriter, r *http.Request) {
 			repo := services.RepositoryFromContext(r.Context())
 			repoName := getRepositoryName(repo.BasePath())
+			if err := repo.ValidateItemRef(chi.URLParam(r, "id"), models.ItemType(chi.URLParam(r, "type"))); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
 			data := map[string]interface{}{
 				"RepositoryName": repoName,
+				"Types":          repo.Types(),
 				"ViewType":       "edit",
 				"ItemID":         chi.URLParam(r, "id"),
 				"ItemType":       chi.URLParam(r, "type"),
@@ -282,11 +369,71 @@ func main() {
 			tagHandler.Routes().ServeHTTP(w, r)
 		}))
 
+		// Full-text search
+		r.Mount("/api/search", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repo := services.RepositoryFromContext(r.Context())
+			searchHandler := handlers.NewSearchHandler(repo)
+			searchHandler.Routes().ServeHTTP(w, r)
+		}))
+
+		// Graph of connected items
+		r.Mount("/api/graph", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repo := services.RepositoryFromContext(r.Context())
+			graphHandler := handlers.NewGraphHandler(repo)
+			graphHandler.Routes().ServeHTTP(w, r)
+		}))
+
+		// Inbox of unprocessed items
+		r.Mount("/api/inbox", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repo := services.RepositoryFromContext(r.Context())
+			inboxHandler := handlers.NewInboxHandler(repo)
+			inboxHandler.Routes().ServeHTTP(w, r)
+		}))
+
+		// Repository maintenance
+		r.Mount("/api/admin", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repo := services.RepositoryFromContext(r.Context())
+			adminHandler := handlers.NewAdminHandler(repo)
+			adminHandler.Routes().ServeHTTP(w, r)
+		}))
+
+		// Repository passphrase of encrypted items
+		r.Mount("/api/encryption", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repo := services.RepositoryFromContext(r.Context())
+			encryptionHandler := handlers.NewEncryptionHandler(repo)
+			encryptionHandler.Routes().ServeHTTP(w, r)
+		}))
+
+		// Import of zipped Obsidian vaults and markdown folders
+		r.Mount("/api/import", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repo := services.RepositoryFromContext(r.Context())
+			importHandler := handlers.NewImportHandler(repo)
+			importHandler.Routes().ServeHTTP(w, r)
+		}))
+
+		// Trash of deleted items
+		r.Mount("/api/trash", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repo := services.RepositoryFromContext(r.Context())
+			trashHandler := handlers.NewTrashHandler(repo)
+			trashHandler.Routes().ServeHTTP(w, r)
+		}))
+
+		// Uploads, downloads and previews of the stored files of file items
+		r.Mount("/api/files", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repo := services.RepositoryFromContext(r.Context())
+			fileHandler := handlers.NewFileHandler(repo)
+			fileHandler.Routes().ServeHTTP(w, r)
+		}))
+
 		// API tag route for HTMX
 		r.Get("/api/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
 			// Get repository and create an item handler
 			repo := services.RepositoryFromContext(r.Context())
 			tag := chi.URLParam(r, "tag")
+			if err := services.ValidateTag(tag); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
 			// Create a new request with adjusted path to match the ItemHandler's route pattern
 			newURL := fmt.Sprintf("/tags/%s", tag)
@@ -326,6 +473,7 @@ func main() {
 
 			data := map[string]interface{}{
 				"RepositoryName": repoName,
+				"Types":          repo.Types(),
 				"PageTitle":      "Tags",
 				"ViewType":       "tags",
 				"TagListHTML":    template.HTML(tagListHTML),    // Pre-rendered HTML
@@ -343,6 +491,10 @@ func main() {
 			repo := services.RepositoryFromContext(r.Context())
 			repoName := getRepositoryName(repo.BasePath())
 			tag := chi.URLParam(r, "tag")
+			if err := services.ValidateTag(tag); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
 			// Create breadcrumb HTML for tag detail page
 			breadcrumbHTML := fmt.Sprintf(`
@@ -359,6 +511,7 @@ func main() {
 
 			data := map[string]interface{}{
 				"RepositoryName": repoName,
+				"Types":          repo.Types(),
 				"PageTitle":      "Tag: #" + tag,
 				"ViewType":       "list",
 				"Tag":            tag,
@@ -378,8 +531,34 @@ func main() {
 		tmpl.ExecuteTemplate(w, "errors/404.html", nil)
 	})
 
-	// Start server
+	// Start server, until it fails or the process is asked to stop
 	addr := fmt.Sprintf(":%d", *port)
-	log.Printf("Starting server on %s", addr)
-	log.Fatal(http.ListenAndServe(addr, r))
+	server := &http.Server{Addr: addr, Handler: r}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server on %s", addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		registry.Close()
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	// A second signal stops the process right away
+	stop()
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+
+	// Stop the watchers and backup schedulers and commit saves still waiting for their quiet period
+	registry.Close()
 }
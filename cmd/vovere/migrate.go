@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"vovere/internal/app/services"
+)
+
+// runMigrate upgrades a repository to the current schema version.
+// It returns 0 on success and 2 on errors.
+func runMigrate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dryRun := flags.Bool("dry-run", false, "Report what would change without writing anything")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: vovere migrate [-dry-run] <repository>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		fmt.Fprintf(stderr, "%s is not a directory\n", path)
+		return 2
+	}
+
+	report, err := services.NewMigrationService(services.NewRepository(path)).Migrate(*dryRun)
+	if err != nil {
+		fmt.Fprintf(stderr, "migrate failed: %v\n", err)
+		return 2
+	}
+
+	printMigrationReport(stdout, path, report)
+	return 0
+}
+
+// printMigrationReport writes the migrations and the changes to each item
+func printMigrationReport(w io.Writer, path string, report *services.MigrationReport) {
+	fmt.Fprintf(w, "Repository %s is at schema version %d, current is %d\n", path, report.From, report.To)
+	for _, step := range report.Steps {
+		fmt.Fprintf(w, "  %d: %s\n", step.Version, step.Description)
+	}
+
+	verb := "Migrated"
+	if report.DryRun {
+		verb = "Would migrate"
+	}
+	fmt.Fprintf(w, "\n%s %d of %d items\n", verb, len(report.Migrated), report.Items)
+	for _, item := range report.Migrated {
+		fmt.Fprintf(w, "  %s (version %d)\n", item.Path, item.From)
+		for _, change := range item.Changes {
+			fmt.Fprintf(w, "    %s\n", change)
+		}
+	}
+
+	if len(report.Skipped) > 0 {
+		fmt.Fprintf(w, "\nSkipped %d unreadable metadata files, run fsck -repair to fix them\n", len(report.Skipped))
+		for _, path := range report.Skipped {
+			fmt.Fprintf(w, "  %s\n", path)
+		}
+	}
+
+	if report.DryRun {
+		fmt.Fprintln(w, "\nDry run, nothing was written")
+	}
+}
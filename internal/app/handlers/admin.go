@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/services"
+)
+
+// AdminHandler handles HTTP requests for repository maintenance
+type AdminHandler struct {
+	fsck *services.FsckService
+	git  *services.GitService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(repo *services.Repository) *AdminHandler {
+	return &AdminHandler{
+		fsck: services.NewFsckService(repo),
+		git:  repo.Git(),
+	}
+}
+
+// Routes returns the router for admin endpoints
+func (h *AdminHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/fsck", h.checkRepository)
+	r.Post("/fsck/repair", h.repairRepository)
+	r.Get("/git", h.gitStatus)
+	r.Post("/git/commit", h.gitCommit)
+
+	return r
+}
+
+// checkRepository reports the inconsistencies in the repository as JSON
+func (h *AdminHandler) checkRepository(w http.ResponseWriter, r *http.Request) {
+	report, err := h.fsck.Check()
+	if err != nil {
+		http.Error(w, "Failed to check repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// repairRepository repairs the inconsistencies in the repository and reports them as JSON
+func (h *AdminHandler) repairRepository(w http.ResponseWriter, r *http.Request) {
+	report, err := h.fsck.Repair()
+	if err != nil {
+		http.Error(w, "Failed to repair repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// gitStatus reports the uncommitted changes of the repository as JSON
+func (h *AdminHandler) gitStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.git.Status()
+	if err != nil {
+		http.Error(w, "Failed to read git status: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// gitCommit commits the pending changes without waiting for the quiet period and reports the new status
+func (h *AdminHandler) gitCommit(w http.ResponseWriter, r *http.Request) {
+	if err := h.git.Commit(); err != nil {
+		http.Error(w, "Failed to commit: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.gitStatus(w, r)
+}
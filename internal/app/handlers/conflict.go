@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/models"
+	"vovere/internal/app/services"
+)
+
+// conflictVersion is one side of a conflicting content update
+type conflictVersion struct {
+	ETag     string     `json:"etag,omitempty"`
+	Modified *time.Time `json:"modified,omitempty"`
+	Content  string     `json:"content"`
+}
+
+// writeConflict answers a rejected content update with 409, the saved version and the
+// submitted one. HTMX requests get a merge view, everything else gets JSON.
+func (h *ItemHandler) writeConflict(w http.ResponseWriter, r *http.Request, yours string) {
+	id := chi.URLParam(r, "id")
+	itemType := models.ItemType(chi.URLParam(r, "type"))
+
+	item, saved, err := h.repo.LoadItem(id, itemType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	etag := services.ItemETag(item, saved)
+	diff := services.UnifiedDiff("saved", "yours", saved, yours)
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("HX-Request") != "true" || wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "The item was modified since it was loaded",
+			"current": conflictVersion{
+				ETag:     etag,
+				Modified: &item.Modified,
+				Content:  saved,
+			},
+			"yours": conflictVersion{
+				Content: yours,
+			},
+			"diff": diff,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusConflict)
+
+	fmt.Fprintf(w, `
+	<div class="bg-yellow-50 dark:bg-gray-800 p-4 rounded-lg border border-yellow-300 dark:border-yellow-700 class-merge-view">
+		<h3 class="text-lg font-semibold mb-1 dark:text-gray-200">This item was changed elsewhere</h3>
+		<p class="text-sm text-gray-600 dark:text-gray-400 mb-3">
+			It was saved on %s after you started editing. Merge the changes in the editor and save again,
+			or keep one of the versions.
+		</p>
+		<div class="grid gap-2 md:grid-cols-2 mb-3">
+			<div>
+				<h4 class="text-sm font-medium mb-1 dark:text-gray-300">Saved version</h4>
+				<pre id="merge-saved" class="text-xs font-mono whitespace-pre-wrap max-h-64 overflow-auto p-2 bg-white dark:bg-gray-900 rounded border border-gray-200 dark:border-gray-700 class-merge-saved">%s</pre>
+			</div>
+			<div>
+				<h4 class="text-sm font-medium mb-1 dark:text-gray-300">Your version</h4>
+				<pre class="text-xs font-mono whitespace-pre-wrap max-h-64 overflow-auto p-2 bg-white dark:bg-gray-900 rounded border border-gray-200 dark:border-gray-700 class-merge-yours">%s</pre>
+			</div>
+		</div>`,
+		item.Modified.Local().Format("Jan 2, 2006 3:04:05 PM"),
+		html.EscapeString(saved),
+		html.EscapeString(yours))
+
+	if strings.TrimSpace(diff) != "" {
+		fmt.Fprint(w, `
+		<h4 class="text-sm font-medium mb-1 dark:text-gray-300">Changes from the saved version</h4>`)
+		renderDiff(w, diff)
+	}
+
+	fmt.Fprint(w, `
+		<div class="flex gap-2 mt-3 class-merge-actions">
+			<button
+				type="button"
+				class="px-3 py-1 text-sm bg-gray-100 text-gray-800 dark:bg-gray-700 dark:text-gray-200 rounded hover:bg-gray-200 dark:hover:bg-gray-600 class-merge-use-saved"
+				onclick="useSavedVersion()"
+			>Use saved version</button>
+			<button
+				type="submit"
+				form="editor-form"
+				class="px-3 py-1 text-sm bg-blue-100 text-blue-800 dark:bg-blue-800 dark:text-blue-100 rounded hover:bg-blue-200 dark:hover:bg-blue-700 class-merge-keep-mine"
+			>Save my version</button>
+		</div>
+	</div>`)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"html"
 	"net/http"
 	"sort"
 
@@ -9,7 +10,6 @@ import (
 
 	"vovere/internal/app/models"
 	"vovere/internal/app/services"
-	md "vovere/internal/markdown"
 )
 
 // DashboardHandler handles dashboard-related functionality
@@ -27,7 +27,7 @@ func NewDashboardHandler(repo *services.Repository) *DashboardHandler {
 // RecentItem represents an item displayed on the dashboard
 type RecentItem struct {
 	*models.Item
-	Label string
+	Definition services.TypeConfig
 }
 
 // Routes returns the router for dashboard endpoints
@@ -41,25 +41,13 @@ func (h *DashboardHandler) Routes() chi.Router {
 
 // getRecentItems returns the most recent items across all types
 func (h *DashboardHandler) getRecentItems(w http.ResponseWriter, r *http.Request) {
-	// Get items of each type
-	notes, _ := h.repo.ListItems(models.TypeNote)
-	bookmarks, _ := h.repo.ListItems(models.TypeBookmark)
-	tasks, _ := h.repo.ListItems(models.TypeTask)
-	workstreams, _ := h.repo.ListItems(models.TypeWorkstream)
-
-	// Create combined list with labels
+	// Get items of every type of the repository
 	var allItems []RecentItem
-	for _, item := range notes {
-		allItems = append(allItems, RecentItem{Item: item, Label: "Note"})
-	}
-	for _, item := range bookmarks {
-		allItems = append(allItems, RecentItem{Item: item, Label: "Bookmark"})
-	}
-	for _, item := range tasks {
-		allItems = append(allItems, RecentItem{Item: item, Label: "Task"})
-	}
-	for _, item := range workstreams {
-		allItems = append(allItems, RecentItem{Item: item, Label: "Workstream"})
+	for _, def := range h.repo.Types() {
+		items, _ := h.repo.ListItems(def.Name)
+		for _, item := range items {
+			allItems = append(allItems, RecentItem{Item: item, Definition: def})
+		}
 	}
 
 	// Sort by modified time, most recent first
@@ -96,7 +84,7 @@ func (h *DashboardHandler) getRecentItems(w http.ResponseWriter, r *http.Request
 		fmt.Fprint(w, `
 			<tr>
 				<td colspan="4" class="px-6 py-4 text-center text-sm text-gray-500 dark:text-gray-400">
-					No items found. Create your first note, bookmark, task, or workstream to get started.
+					No items found. Create your first item to get started.
 				</td>
 			</tr>
 		`)
@@ -108,12 +96,7 @@ func (h *DashboardHandler) getRecentItems(w http.ResponseWriter, r *http.Request
 		if title == "" {
 			_, content, err := h.repo.LoadItem(item.ID, item.Type)
 			if err == nil {
-				title = md.ExtractTitleFromContent(content, string(item.Type))
-				// Save the extracted title
-				if title != "" {
-					item.Title = title
-					h.repo.SaveItem(item.Item, "")
-				}
+				title = services.ContentTitle(content, item.Type)
 			}
 		}
 
@@ -122,23 +105,10 @@ func (h *DashboardHandler) getRecentItems(w http.ResponseWriter, r *http.Request
 			title = item.ID
 		}
 
-		// Type-specific styling
-		typeClass := "bg-indigo-100 text-indigo-800 dark:bg-indigo-900 dark:text-indigo-200"
-		switch item.Type {
-		case models.TypeNote:
-			typeClass = "bg-blue-100 text-blue-800 dark:bg-blue-900 dark:text-blue-200"
-		case models.TypeBookmark:
-			typeClass = "bg-purple-100 text-purple-800 dark:bg-purple-900 dark:text-purple-200"
-		case models.TypeTask:
-			typeClass = "bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200"
-		case models.TypeWorkstream:
-			typeClass = "bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200"
-		}
-
 		fmt.Fprintf(w, `
 		<tr class="hover:bg-gray-50 dark:hover:bg-gray-700">
 			<td class="px-6 py-4 whitespace-nowrap">
-				<span class="inline-block px-2 py-1 text-xs rounded %s">%s</span>
+				<span class="inline-block px-2 py-1 text-xs rounded %s">%s %s</span>
 			</td>
 			<td class="px-6 py-4 whitespace-nowrap">
 				<a 
@@ -168,13 +138,13 @@ func (h *DashboardHandler) getRecentItems(w http.ResponseWriter, r *http.Request
 					hx-delete="/api/items/%s/%s"
 					hx-target="#content"
 					hx-swap="innerHTML"
-					hx-confirm="Are you sure you want to delete this item?"
+					hx-confirm="Move this item to the trash?"
 				>
 					Delete
 				</button>
 			</td>
 		</tr>`,
-			typeClass, item.Label,
+			typeBadgeClass(item.Definition), html.EscapeString(item.Definition.Icon), html.EscapeString(item.Definition.Label),
 			item.Type, item.ID,
 			item.Type, item.ID,
 			item.Type, item.ID,
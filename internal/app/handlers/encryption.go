@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/services"
+)
+
+// EncryptionHandler handles HTTP requests for the repository passphrase
+type EncryptionHandler struct {
+	encryption *services.EncryptionService
+}
+
+// NewEncryptionHandler creates a new encryption handler
+func NewEncryptionHandler(repo *services.Repository) *EncryptionHandler {
+	return &EncryptionHandler{
+		encryption: services.NewEncryptionService(repo),
+	}
+}
+
+// Routes returns the router for encryption endpoints
+func (h *EncryptionHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.status)
+	r.Post("/setup", h.setup)
+	r.Post("/unlock", h.unlock)
+	r.Post("/lock", h.lock)
+
+	return r
+}
+
+// status reports whether the repository has a passphrase and is unlocked as JSON
+func (h *EncryptionHandler) status(w http.ResponseWriter, r *http.Request) {
+	status, err := h.encryption.Status()
+	if err != nil {
+		http.Error(w, "Failed to read encryption status: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// setup sets the repository passphrase from the passphrase form value and reports the new status
+func (h *EncryptionHandler) setup(w http.ResponseWriter, r *http.Request) {
+	passphrase := r.FormValue("passphrase")
+	if passphrase == "" {
+		http.Error(w, "Passphrase is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.encryption.Setup(passphrase); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrEncryptionSetUp) {
+			status = http.StatusConflict
+		}
+		http.Error(w, "Failed to set up encryption: "+err.Error(), status)
+		return
+	}
+	h.status(w, r)
+}
+
+// unlock keeps the key of encrypted items in memory until the unlock period ends
+func (h *EncryptionHandler) unlock(w http.ResponseWriter, r *http.Request) {
+	if err := h.encryption.Unlock(r.FormValue("passphrase")); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrWrongPassphrase):
+			status = http.StatusForbidden
+		case errors.Is(err, services.ErrEncryptionNotSetUp):
+			status = http.StatusConflict
+		}
+		http.Error(w, "Failed to unlock: "+err.Error(), status)
+		return
+	}
+	h.status(w, r)
+}
+
+// lock forgets the key of encrypted items
+func (h *EncryptionHandler) lock(w http.ResponseWriter, r *http.Request) {
+	h.encryption.Lock()
+	h.status(w, r)
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/models"
+	"vovere/internal/app/services"
+)
+
+// multipartOverhead is allowed on top of the upload size limit for the rest of the form
+const multipartOverhead = 1 << 20
+
+// inlineTypes are the media types served for display in the browser, anything else is
+// downloaded so stored HTML or SVG can't run scripts in the application's origin
+var inlineTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/avif":      true,
+	"image/bmp":       true,
+	"application/pdf": true,
+}
+
+// FileHandler handles HTTP requests for the stored files of file items
+type FileHandler struct {
+	repo  *services.Repository
+	files *services.FileService
+}
+
+// NewFileHandler creates a new file handler
+func NewFileHandler(repo *services.Repository) *FileHandler {
+	return &FileHandler{
+		repo:  repo,
+		files: services.NewFileService(repo),
+	}
+}
+
+// Routes returns the router for file endpoints
+func (h *FileHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/", h.upload)
+	r.Get("/{id}", h.download)
+	r.Get("/{id}/preview", h.preview)
+
+	return r
+}
+
+// upload creates a file item from the "file" field of a multipart form, with the
+// "description" field as its content. Identical files are stored once.
+func (h *FileHandler) upload(w http.ResponseWriter, r *http.Request) {
+	config, err := h.repo.Config()
+	if err != nil {
+		http.Error(w, "Failed to upload file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, config.Files.MaxUploadSize()+multipartOverhead)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Failed to upload file: "+services.ErrFileTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Missing file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	item, duplicate, err := h.files.Upload(header.Filename, file, r.FormValue("description"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrFileTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, "Failed to upload file: "+err.Error(), status)
+		return
+	}
+
+	if wantsJSON(r) {
+		status := http.StatusCreated
+		if duplicate {
+			status = http.StatusOK
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"item":      item,
+			"duplicate": duplicate,
+		})
+		return
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/items/%s/%s", item.Type, item.ID))
+	w.WriteHeader(http.StatusOK)
+}
+
+// download sends the stored file of a file item as an attachment
+func (h *FileHandler) download(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, false)
+}
+
+// preview sends the stored file of a file item for display, when its type is safe to show
+func (h *FileHandler) preview(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, true)
+}
+
+// serve sends the stored file of the file item named by the id URL parameter, with range support
+func (h *FileHandler) serve(w http.ResponseWriter, r *http.Request, inline bool) {
+	id := chi.URLParam(r, "id")
+	if err := services.ValidateItemID(id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	item, _, err := h.repo.LoadItem(id, models.TypeFile)
+	if err != nil || item.Filename == "" {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	f, err := os.Open(h.files.Path(item))
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "Failed to read file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := h.files.ContentType(item)
+	disposition := "attachment"
+	if inline {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		switch {
+		case inlineTypes[mediaType]:
+			disposition = "inline"
+		case h.files.Preview(item) == services.PreviewText:
+			// Text of any kind is shown as plain text, never rendered
+			contentType = "text/plain; charset=utf-8"
+			disposition = "inline"
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": item.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if item.Hash != "" {
+		w.Header().Set("ETag", `"`+item.Hash+`"`)
+	}
+	http.ServeContent(w, r, item.Filename, info.ModTime(), f)
+}
+
+// maxTextPreview is how much of a text file is shown in its preview
+const maxTextPreview = 64 << 10
+
+// renderFilePreview returns the panel showing a file item's stored file above its description
+func renderFilePreview(repo *services.Repository, item *models.Item) string {
+	files := services.NewFileService(repo)
+	name := html.EscapeString(item.Filename)
+	src := fmt.Sprintf("/api/files/%s/preview", item.ID)
+
+	info, err := os.Stat(files.Path(item))
+	if item.Filename == "" || err != nil {
+		return `
+				<div class="bg-white dark:bg-gray-800 p-4 rounded-lg border border-gray-200 dark:border-gray-700 shadow-sm text-sm text-gray-500 dark:text-gray-400 class-file-preview">
+					The stored file is missing.
+				</div>`
+	}
+
+	var preview string
+	switch files.Preview(item) {
+	case services.PreviewImage:
+		preview = fmt.Sprintf(`<img src="%s" alt="%s" class="max-w-full max-h-[32rem] mx-auto class-file-image">`, src, name)
+	case services.PreviewPDF:
+		preview = fmt.Sprintf(`<iframe src="%s" title="%s" class="w-full h-[40rem] rounded border border-gray-200 dark:border-gray-700 class-file-pdf"></iframe>`, src, name)
+	case services.PreviewText:
+		text, truncated, err := files.Text(item, maxTextPreview)
+		if err != nil {
+			preview = `<p class="text-sm text-gray-500 dark:text-gray-400">No preview, the file isn't plain text.</p>`
+			break
+		}
+		preview = fmt.Sprintf(`<pre class="text-sm overflow-x-auto p-3 bg-gray-50 dark:bg-gray-900 rounded border border-gray-200 dark:border-gray-700 class-file-text">%s</pre>`, html.EscapeString(text))
+		if truncated {
+			preview += fmt.Sprintf(`<p class="mt-2 text-xs text-gray-500 dark:text-gray-400">Showing the first %s.</p>`, formatSize(maxTextPreview))
+		}
+	default:
+		preview = `<p class="text-sm text-gray-500 dark:text-gray-400">No preview for this type of file.</p>`
+	}
+
+	return fmt.Sprintf(`
+				<div class="bg-white dark:bg-gray-800 p-4 rounded-lg border border-gray-200 dark:border-gray-700 shadow-sm class-file-preview">
+					<div class="flex justify-between items-center mb-3">
+						<div>
+							<span class="font-mono text-sm dark:text-gray-200 class-file-name">%s</span>
+							<span class="ml-2 text-sm text-gray-500 dark:text-gray-400 class-file-size">%s</span>
+						</div>
+						<a href="/api/files/%s" class="px-3 py-1 bg-indigo-100 text-indigo-800 dark:bg-indigo-800 dark:text-indigo-100 rounded hover:bg-indigo-200 dark:hover:bg-indigo-700 class-file-download">Download</a>
+					</div>
+					%s
+				</div>`,
+		name, formatSize(info.Size()), item.ID, preview)
+}
+
+// renderUploadForm returns the form uploading a new file item
+func renderUploadForm() string {
+	return `
+		<form
+			hx-post="/api/files"
+			hx-encoding="multipart/form-data"
+			class="flex flex-wrap gap-2 items-center class-file-upload"
+		>
+			<input type="file" name="file" required class="text-sm dark:text-gray-200" aria-label="File">
+			<input type="text" name="description" placeholder="Description" class="px-2 py-1 border rounded text-sm bg-white dark:bg-gray-700 dark:text-white dark:border-gray-600" aria-label="Description">
+			<button type="submit" class="px-3 py-1 bg-indigo-600 text-white rounded hover:bg-indigo-700 dark:bg-indigo-700 dark:hover:bg-indigo-800">Upload</button>
+		</form>`
+}
+
+// formatSize returns a size in bytes for display, like "1.5 MB"
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[exp])
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/models"
+	"vovere/internal/app/services"
+)
+
+// defaultGraphDepth is the number of hops around a focus item when no depth is given
+const defaultGraphDepth = 2
+
+// GraphHandler handles HTTP requests for the item graph
+type GraphHandler struct {
+	repo         *services.Repository
+	graphService *services.GraphService
+}
+
+// NewGraphHandler creates a new graph handler
+func NewGraphHandler(repo *services.Repository) *GraphHandler {
+	return &GraphHandler{
+		repo:         repo,
+		graphService: services.NewGraphService(repo),
+	}
+}
+
+// Routes returns the router for graph endpoints
+func (h *GraphHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.getGraph)
+
+	return r
+}
+
+// getGraph returns the nodes and edges matching the type, tag and focus filters as JSON
+func (h *GraphHandler) getGraph(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := services.GraphFilter{
+		Focus: strings.TrimSpace(query.Get("focus")),
+		Depth: defaultGraphDepth,
+	}
+
+	for _, value := range splitValues(query["type"]) {
+		itemType := models.ItemType(strings.TrimSuffix(value, "s"))
+		if err := h.repo.ValidateItemType(itemType); err != nil {
+			http.Error(w, "Invalid type: "+value, http.StatusBadRequest)
+			return
+		}
+		filter.Types = append(filter.Types, itemType)
+	}
+
+	for _, tag := range splitValues(query["tag"]) {
+		filter.Tags = append(filter.Tags, strings.TrimPrefix(tag, "#"))
+	}
+
+	if value := query.Get("depth"); value != "" {
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			http.Error(w, "Invalid depth", http.StatusBadRequest)
+			return
+		}
+		filter.Depth = depth
+	}
+
+	graph, err := h.graphService.Build(filter)
+	if err != nil {
+		if errors.Is(err, services.ErrFocusNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to build graph: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(graph); err != nil {
+		http.Error(w, "Failed to encode graph: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// splitValues flattens repeated and comma separated query values
+func splitValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
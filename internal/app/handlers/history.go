@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/models"
+	"vovere/internal/app/services"
+)
+
+// listRevisions returns the revisions of an item as an HTMX fragment or JSON
+func (h *ItemHandler) listRevisions(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.loadItemFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := services.NewHistoryService(h.repo).Revisions(item)
+	if err != nil {
+		http.Error(w, "Failed to load revisions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(revisions)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+
+	fmt.Fprintf(w, `
+	<div class="bg-gray-50 dark:bg-gray-800 p-4 rounded-lg border border-gray-200 dark:border-gray-700 mb-4 class-item-history">
+		<h3 class="text-lg font-semibold mb-3 dark:text-gray-200">History</h3>
+		<p class="text-xs text-gray-500 dark:text-gray-400 mb-2">%d revision%s</p>
+		<ul class="divide-y divide-gray-200 dark:divide-gray-700 text-sm">`, len(revisions), plural(len(revisions)))
+
+	for i, revision := range revisions {
+		// The newest revision matches the current content, so it can't be restored or diffed
+		actions := `<span class="text-xs text-gray-500 dark:text-gray-400">Current</span>`
+		if i > 0 {
+			actions = fmt.Sprintf(`
+				<button
+					class="px-2 py-1 text-xs bg-gray-100 text-gray-800 dark:bg-gray-700 dark:text-gray-200 rounded hover:bg-gray-200 dark:hover:bg-gray-600 class-history-diff"
+					hx-get="/api/items/%s/%s/history/diff?from=%s&to=current"
+					hx-target="#history-diff"
+				>Diff</button>
+				<button
+					class="px-2 py-1 text-xs bg-blue-100 text-blue-800 dark:bg-blue-800 dark:text-blue-100 rounded hover:bg-blue-200 dark:hover:bg-blue-700 class-history-restore"
+					hx-post="/api/items/%s/%s/history/%s/restore"
+					hx-confirm="Restore this revision? The current content will be kept in the history."
+				>Restore</button>`,
+				item.Type, item.ID, revision.ID,
+				item.Type, item.ID, revision.ID)
+		}
+
+		fmt.Fprintf(w, `
+			<li class="py-2 flex items-center justify-between gap-2 class-history-revision">
+				<div>
+					<div class="dark:text-gray-200">%s</div>
+					<div class="text-xs text-gray-500 dark:text-gray-400">%d bytes</div>
+				</div>
+				<div class="flex gap-1 flex-shrink-0">%s</div>
+			</li>`,
+			revision.Created.Local().Format("Jan 2, 2006 3:04:05 PM"),
+			revision.Size,
+			actions)
+	}
+
+	fmt.Fprint(w, `
+		</ul>
+		<div id="history-diff" class="mt-3"></div>
+	</div>`)
+}
+
+// diffRevisions returns a unified diff between two revisions, "current" meaning the content on disk
+func (h *ItemHandler) diffRevisions(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.loadItemFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = "current"
+	}
+	if from == "" {
+		http.Error(w, "Missing from revision", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := services.NewHistoryService(h.repo).Diff(item, from, to)
+	if err != nil {
+		h.revisionError(w, err)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"from": from,
+			"to":   to,
+			"diff": diff,
+		})
+		return
+	}
+
+	if r.URL.Query().Get("format") == "diff" {
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		fmt.Fprint(w, diff)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+
+	if diff == "" {
+		fmt.Fprint(w, `<p class="text-sm text-gray-500 dark:text-gray-400">No differences.</p>`)
+		return
+	}
+
+	renderDiff(w, diff)
+}
+
+// getRevision returns the content of a revision as plain text or JSON
+func (h *ItemHandler) getRevision(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.loadItemFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	revision, content, err := services.NewHistoryService(h.repo).Revision(item, chi.URLParam(r, "revision"))
+	if err != nil {
+		h.revisionError(w, err)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			services.Revision
+			Content string `json:"content"`
+		}{*revision, content})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	fmt.Fprint(w, content)
+}
+
+// restoreRevision replaces the content of an item with a revision
+func (h *ItemHandler) restoreRevision(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.loadItemFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := services.NewHistoryService(h.repo).Restore(item, chi.URLParam(r, "revision")); err != nil {
+		h.revisionError(w, err)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(item)
+		return
+	}
+
+	// Reload the item page so the editor and sidebar pick up the restored content
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/items/%s/%s", item.Type, item.ID))
+	w.WriteHeader(http.StatusOK)
+}
+
+// renderDiff writes a unified diff as colored HTML lines
+func renderDiff(w io.Writer, diff string) {
+	var lines strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		class := "text-gray-700 dark:text-gray-300"
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			class = "text-indigo-600 dark:text-indigo-400"
+		case strings.HasPrefix(line, "+"):
+			class = "bg-green-50 text-green-800 dark:bg-green-900 dark:text-green-200"
+		case strings.HasPrefix(line, "-"):
+			class = "bg-red-50 text-red-800 dark:bg-red-900 dark:text-red-200"
+		}
+		fmt.Fprintf(&lines, `<div class="%s">%s</div>`, class, html.EscapeString(line))
+	}
+
+	fmt.Fprintf(w, `<pre class="text-xs font-mono overflow-x-auto p-2 bg-white dark:bg-gray-900 rounded border border-gray-200 dark:border-gray-700 class-history-diff-output">%s</pre>`, lines.String())
+}
+
+// loadItemFromURL loads the item named by the type and id URL parameters, writing a 400 for invalid parameters and a 404 if it doesn't exist
+func (h *ItemHandler) loadItemFromURL(w http.ResponseWriter, r *http.Request) (*models.Item, bool) {
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return nil, false
+	}
+
+	item, _, err := h.repo.LoadItem(id, itemType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return item, true
+}
+
+// revisionError writes a 404 for unknown revisions and a 500 for anything else
+func (h *ItemHandler) revisionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrRevisionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
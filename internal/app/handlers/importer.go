@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/services"
+)
+
+// maxImportSize caps the size of an uploaded archive
+const maxImportSize = 512 << 20
+
+// maxImportUnpackedSize caps the size of an uploaded archive once unpacked
+const maxImportUnpackedSize = 2 << 30
+
+// ImportHandler handles HTTP requests for importing markdown folders
+type ImportHandler struct {
+	importer *services.ImportService
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(repo *services.Repository) *ImportHandler {
+	return &ImportHandler{
+		importer: services.NewImportService(repo),
+	}
+}
+
+// Routes returns the router for import endpoints
+func (h *ImportHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/", h.importArchive)
+
+	return r
+}
+
+// importArchive imports a zip of an Obsidian vault or markdown folder, uploaded as the
+// "archive" form field, and reports the result as JSON
+func (h *ImportHandler) importArchive(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		http.Error(w, "Missing archive: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	dir, err := os.MkdirTemp("", "vovere-import-*")
+	if err != nil {
+		http.Error(w, "Failed to import archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		http.Error(w, "Invalid archive: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := services.ExtractZip(archive, dir, maxImportUnpackedSize); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrArchiveTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, "Invalid archive: "+err.Error(), status)
+		return
+	}
+
+	report, err := h.importer.ImportDirectory(dir)
+	if err != nil {
+		http.Error(w, "Failed to import archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/models"
+	"vovere/internal/app/services"
+)
+
+// InboxHandler handles HTTP requests for the inbox of unprocessed items
+type InboxHandler struct {
+	repo  *services.Repository
+	inbox *services.InboxService
+}
+
+// NewInboxHandler creates a new inbox handler
+func NewInboxHandler(repo *services.Repository) *InboxHandler {
+	return &InboxHandler{
+		repo:  repo,
+		inbox: services.NewInboxService(repo),
+	}
+}
+
+// Routes returns the router for inbox endpoints
+func (h *InboxHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.listInbox)
+	r.Get("/count", h.getCount)
+	r.Post("/{type}/{id}/tags", h.addTags)
+	r.Post("/{type}/{id}/title", h.setTitle)
+	r.Post("/{type}/{id}/convert", h.convertType)
+	r.Post("/{type}/{id}/workstream", h.moveToWorkstream)
+	r.Post("/{type}/{id}/archive", h.archive)
+
+	return r
+}
+
+// getCount returns the number of unprocessed items as a sidebar badge or JSON
+func (h *InboxHandler) getCount(w http.ResponseWriter, r *http.Request) {
+	count, err := h.inbox.Count()
+	if err != nil {
+		http.Error(w, "Failed to count inbox items: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"count": count})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if count == 0 {
+		return
+	}
+	fmt.Fprintf(w, `<span class="inline-block min-w-[1.5rem] px-2 py-0.5 text-xs text-center rounded-full bg-indigo-100 text-indigo-800 dark:bg-indigo-900 dark:text-indigo-200 class-inbox-badge" aria-label="%d unprocessed items">%d</span>`, count, count)
+}
+
+// addTags adds tags to an inbox item
+func (h *InboxHandler) addTags(w http.ResponseWriter, r *http.Request) {
+	h.triage(w, r, func(item *models.Item) error {
+		tags := strings.FieldsFunc(r.FormValue("tags"), func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+		return h.inbox.AddTags(item, tags)
+	})
+}
+
+// setTitle sets the title of an inbox item
+func (h *InboxHandler) setTitle(w http.ResponseWriter, r *http.Request) {
+	h.triage(w, r, func(item *models.Item) error {
+		return h.inbox.SetTitle(item, r.FormValue("title"))
+	})
+}
+
+// convertType converts an inbox item into another type
+func (h *InboxHandler) convertType(w http.ResponseWriter, r *http.Request) {
+	h.triage(w, r, func(item *models.Item) error {
+		return h.inbox.ConvertType(item, models.ItemType(r.FormValue("type")))
+	})
+}
+
+// moveToWorkstream adds an inbox item to a workstream
+func (h *InboxHandler) moveToWorkstream(w http.ResponseWriter, r *http.Request) {
+	h.triage(w, r, func(item *models.Item) error {
+		return h.inbox.MoveToWorkstream(item, r.FormValue("workstream"))
+	})
+}
+
+// archive archives an inbox item
+func (h *InboxHandler) archive(w http.ResponseWriter, r *http.Request) {
+	h.triage(w, r, func(item *models.Item) error {
+		return h.inbox.Archive(item)
+	})
+}
+
+// triage loads the item from the URL, applies an action and renders the updated inbox
+func (h *InboxHandler) triage(w http.ResponseWriter, r *http.Request, action func(item *models.Item) error) {
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	item, _, err := h.repo.LoadItem(id, itemType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := action(item); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Let the sidebar badge refresh itself
+	w.Header().Set("HX-Trigger", "inbox-updated")
+	h.listInbox(w, r)
+}
+
+// listInbox returns the list of unprocessed items with triage actions
+func (h *InboxHandler) listInbox(w http.ResponseWriter, r *http.Request) {
+	items, err := h.inbox.Items()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	workstreams, err := h.repo.ListItems(models.TypeWorkstream)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Breadcrumb for inbox view
+	breadcrumb := `
+		<a href="/" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300 flex-shrink-0 inline-flex items-center" hx-boost="true">
+            <svg class="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M3 12l2-2m0 0l7-7 7 7M5 10v10a1 1 0 001 1h3m10-11l2 2m-2-2v10a1 1 0 01-1 1h-3m-6 0a1 1 0 001-1v-4a1 1 0 011-1h2a1 1 0 011 1v4a1 1 0 001 1m-6 0h6"></path>
+            </svg>
+        </a>
+		<span class="text-gray-500 dark:text-gray-400 flex-shrink-0">/</span>
+		<span class="text-gray-600 dark:text-gray-300">Inbox</span>
+	`
+
+	w.Header().Set("Content-Type", "text/html")
+
+	// Update breadcrumb via HTMX
+	fmt.Fprintf(w, `<div hx-swap-oob="innerHTML:#breadcrumb" class="flex items-center gap-2">%s</div>`, breadcrumb)
+
+	fmt.Fprintf(w, `
+	<div class="flex justify-between items-center mb-6">
+		<h1 class="text-2xl font-bold class-page-title">Inbox</h1>
+		<span class="text-sm text-gray-500 dark:text-gray-400">%d unprocessed item%s</span>
+	</div>
+	<div class="bg-white dark:bg-gray-800 rounded-lg shadow-sm border border-gray-200 dark:border-gray-700 divide-y divide-gray-200 dark:divide-gray-700 class-inbox-list">
+	`, len(items), plural(len(items)))
+
+	if len(items) == 0 {
+		fmt.Fprint(w, `
+		<div class="px-6 py-4 text-sm text-center text-gray-500 dark:text-gray-400">
+			Inbox zero. Every item has a title and tags.
+		</div>
+		`)
+	}
+
+	// Workstream options are shared by every row
+	var workstreamOptions strings.Builder
+	for _, workstream := range workstreams {
+		title := workstream.Title
+		if title == "" {
+			title = workstream.ID
+		}
+		fmt.Fprintf(&workstreamOptions, `<option value="%s">%s</option>`, workstream.ID, html.EscapeString(title))
+	}
+
+	inputClass := "flex-1 min-w-0 px-2 py-1 border rounded text-sm bg-white dark:bg-gray-700 dark:text-white dark:border-gray-600"
+	buttonClass := "px-2 py-1 text-sm bg-indigo-100 text-indigo-800 dark:bg-indigo-800 dark:text-indigo-100 rounded hover:bg-indigo-200 dark:hover:bg-indigo-700"
+
+	for _, item := range items {
+		title := item.Title
+		if title == "" {
+			title = item.ID
+		}
+
+		// Explain why the item is still in the inbox
+		var missing []string
+		if item.Title == "" || item.Title == item.ID {
+			missing = append(missing, "title")
+		}
+		if len(item.Tags) == 0 {
+			missing = append(missing, "tags")
+		}
+
+		// Conversion targets exclude the current type, and files which need a stored file.
+		// File items keep their type since their stored file belongs to it.
+		convertForm := ""
+		if item.Type != models.TypeFile {
+			var typeOptions strings.Builder
+			for _, def := range h.repo.Types() {
+				if def.Name != item.Type && def.Name != models.TypeFile {
+					fmt.Fprintf(&typeOptions, `<option value="%s">%s</option>`, def.Name, html.EscapeString(def.Label))
+				}
+			}
+			convertForm = fmt.Sprintf(`
+				<form hx-post="/api/inbox/%s/%s/convert" hx-target="#content" class="flex gap-2 class-inbox-convert">
+					<select name="type" class="%s" aria-label="Convert to">%s</select>
+					<button type="submit" class="%s">Convert</button>
+				</form>`,
+				item.Type, item.ID, inputClass, typeOptions.String(), buttonClass)
+		}
+
+		workstreamForm := ""
+		if workstreamOptions.Len() > 0 {
+			workstreamForm = fmt.Sprintf(`
+				<form hx-post="/api/inbox/%s/%s/workstream" hx-target="#content" class="flex gap-2 class-inbox-workstream">
+					<select name="workstream" class="%s" aria-label="Workstream">%s</select>
+					<button type="submit" class="%s">Move</button>
+				</form>`,
+				item.Type, item.ID, inputClass, workstreamOptions.String(), buttonClass)
+		}
+
+		fmt.Fprintf(w, `
+		<div class="px-6 py-4 class-inbox-item">
+			<div class="flex items-center justify-between mb-3">
+				<div>
+					<a
+						href="/items/%s/%s"
+						class="text-blue-600 dark:text-blue-400 hover:text-blue-800 dark:hover:text-blue-300 font-medium class-item-title"
+						hx-get="/api/items/%s/%s"
+						hx-target="#content"
+						hx-swap="innerHTML"
+						hx-push-url="/items/%s/%s"
+					>%s</a>
+					<span class="ml-2 text-xs text-gray-500 dark:text-gray-400 class-item-type">%s</span>
+					<span class="ml-2 text-xs text-yellow-700 dark:text-yellow-300">missing %s</span>
+				</div>
+				<button
+					class="px-2 py-1 text-sm bg-gray-100 text-gray-800 dark:bg-gray-700 dark:text-gray-200 rounded hover:bg-gray-200 dark:hover:bg-gray-600 class-inbox-archive"
+					hx-post="/api/inbox/%s/%s/archive"
+					hx-target="#content"
+				>
+					Archive
+				</button>
+			</div>
+			<div class="grid gap-2 md:grid-cols-2 class-inbox-actions">
+				<form hx-post="/api/inbox/%s/%s/title" hx-target="#content" class="flex gap-2 class-inbox-title">
+					<input type="text" name="title" value="%s" placeholder="Title" class="%s" aria-label="Title">
+					<button type="submit" class="%s">Set title</button>
+				</form>
+				<form hx-post="/api/inbox/%s/%s/tags" hx-target="#content" class="flex gap-2 class-inbox-tags">
+					<input type="text" name="tags" placeholder="tag1, tag2" class="%s" aria-label="Tags">
+					<button type="submit" class="%s">Add tags</button>
+				</form>
+				%s
+				%s
+			</div>
+		</div>`,
+			item.Type, item.ID,
+			item.Type, item.ID,
+			item.Type, item.ID,
+			html.EscapeString(title),
+			typeLabel(h.repo, item.Type),
+			strings.Join(missing, " and "),
+			item.Type, item.ID,
+			item.Type, item.ID, html.EscapeString(item.Title), inputClass, buttonClass,
+			item.Type, item.ID, inputClass, buttonClass,
+			convertForm,
+			workstreamForm,
+		)
+	}
+
+	// Close list container
+	fmt.Fprint(w, `
+	</div>
+	`)
+}
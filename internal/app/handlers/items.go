@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"log"
 	"net/http"
 	"sort"
@@ -44,6 +46,12 @@ func (h *ItemHandler) Routes() chi.Router {
 	r.Get("/{type}/{id}/edit", h.editItem)
 	r.Put("/{type}/{id}/content", h.updateContent)
 	r.Delete("/{type}/{id}", h.deleteItem)
+	r.Post("/{type}/{id}/encrypt", h.encryptItem)
+	r.Post("/{type}/{id}/decrypt", h.decryptItem)
+	r.Get("/{type}/{id}/history", h.listRevisions)
+	r.Get("/{type}/{id}/history/diff", h.diffRevisions)
+	r.Get("/{type}/{id}/history/{revision}", h.getRevision)
+	r.Post("/{type}/{id}/history/{revision}/restore", h.restoreRevision)
 	r.Get("/tags/{tag}", h.listItemsByTag)
 
 	return r
@@ -51,8 +59,10 @@ func (h *ItemHandler) Routes() chi.Router {
 
 // deleteItem handles deletion of an item
 func (h *ItemHandler) deleteItem(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	itemType := models.ItemType(chi.URLParam(r, "type"))
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return
+	}
 
 	item, _, err := h.repo.LoadItem(id, itemType)
 	if err != nil {
@@ -60,33 +70,78 @@ func (h *ItemHandler) deleteItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Store the previous tags before deletion
-	previousTags := make([]string, len(item.Tags))
-	copy(previousTags, item.Tags)
-
-	// Delete the item
-	if err := h.repo.DeleteItem(item); err != nil {
+	// Move the item to the trash, it can be restored until the trash is purged
+	if err := services.NewTrashService(h.repo).Trash(item); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Update tag associations - since the item is deleted, pass empty tags list
-	// This will effectively remove the item from all its previous tags
-	item.Tags = []string{} // Clear tags since item is deleted
-	if err := h.tagService.UpdateItemTags(item, previousTags); err != nil {
-		// Just log the error, don't fail the deletion
-		log.Printf("Error updating tags after deletion: %v", err)
-	}
-
 	// Redirect back to list view
 	w.Header().Set("HX-Redirect", "/")
 	w.WriteHeader(http.StatusOK)
 }
 
+// encryptItem encrypts an item's content, and its title and tags when sealMetadata is "true"
+func (h *ItemHandler) encryptItem(w http.ResponseWriter, r *http.Request) {
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	item, _, err := h.repo.LoadItem(id, itemType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	sealMetadata := r.FormValue("sealMetadata") == "true"
+	if err := services.NewEncryptionService(h.repo).EncryptItem(item, sealMetadata); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrLocked) {
+			status = http.StatusLocked
+		}
+		http.Error(w, "Failed to encrypt item: "+err.Error(), status)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/items/%s/%s", itemType, id))
+	w.WriteHeader(http.StatusOK)
+}
+
+// decryptItem stores an encrypted item in plain text again
+func (h *ItemHandler) decryptItem(w http.ResponseWriter, r *http.Request) {
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	item, _, err := h.repo.LoadItem(id, itemType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := services.NewEncryptionService(h.repo).DecryptItem(item); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrLocked) {
+			status = http.StatusLocked
+		}
+		http.Error(w, "Failed to decrypt item: "+err.Error(), status)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/items/%s/%s", itemType, id))
+	w.WriteHeader(http.StatusOK)
+}
+
 // viewItem returns the view interface for an item
 func (h *ItemHandler) viewItem(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	itemType := models.ItemType(chi.URLParam(r, "type"))
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	def, _ := h.repo.Type(itemType)
 
 	item, content, err := h.repo.LoadItem(id, itemType)
 	if err != nil {
@@ -94,16 +149,16 @@ func (h *ItemHandler) viewItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If the item doesn't have a title, extract it
+	// If the item doesn't have a title, extract it for display. Viewing never writes,
+	// so an editor open on the item keeps a valid ETag.
 	if item.Title == "" {
-		item.Title = md.ExtractTitleFromContent(content, string(itemType))
+		item.Title = services.ContentTitle(content, itemType)
 		if item.Title == "" {
 			item.Title = item.ID
 		}
-		// Save the updated metadata
-		h.repo.SaveItem(item, "")
 	}
 
+	w.Header().Set("ETag", services.ItemETag(item, content))
 	w.Header().Set("Content-Type", "text/html")
 
 	// Breadcrumb data
@@ -114,13 +169,16 @@ func (h *ItemHandler) viewItem(w http.ResponseWriter, r *http.Request) {
             </svg>
         </a>
 		<span class="text-gray-500 dark:text-gray-400 flex-shrink-0">/</span>
-		<a href="/%ss" hx-boost="true" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300 flex-shrink-0 inline-flex items-center">%ss</a>
+		<a href="/%ss" hx-boost="true" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300 flex-shrink-0 inline-flex items-center">%s</a>
 		<span class="text-gray-500 dark:text-gray-400 flex-shrink-0">/</span>
 		<span class="text-gray-600 dark:text-gray-300 truncate">%s</span>
-	`, itemType, strings.Title(string(itemType)), item.Title)
+	`, itemType, html.EscapeString(def.Plural), item.Title)
 
-	// Generate HTML
-	contentHTML := md.Render(content)
+	// Generate HTML, resolving wiki links against the repository
+	contentHTML := md.RenderWithResolver(content, services.NewItemResolver(h.repo))
+	if services.IsSealed(content) {
+		contentHTML = `<p class="text-gray-500 dark:text-gray-400 italic">This item is encrypted. Unlock the repository to read it.</p>`
+	}
 
 	// Format tags
 	tags := "None"
@@ -139,7 +197,7 @@ func (h *ItemHandler) viewItem(w http.ResponseWriter, r *http.Request) {
 			</tr>
 			<tr>
 				<th class="dark:text-gray-300">Type</th>
-				<td class="dark:text-gray-200">%s</td>
+				<td class="dark:text-gray-200">%s %s</td>
 			</tr>
 			<tr>
 				<th class="dark:text-gray-300">Created</th>
@@ -154,43 +212,13 @@ func (h *ItemHandler) viewItem(w http.ResponseWriter, r *http.Request) {
 				<td class="dark:text-gray-200">%s</td>
 			</tr>`,
 		item.ID,
-		strings.Title(string(itemType)),
+		html.EscapeString(def.Icon), html.EscapeString(def.Label),
 		item.Created.Format("Jan 2, 2006 3:04 PM"),
 		item.Modified.Format("Jan 2, 2006 3:04 PM"),
 		tags)
 
-	// Add type-specific fields to metadata table
-	switch itemType {
-	case models.TypeBookmark:
-		metadataTable += fmt.Sprintf(`
-		<tr>
-			<th class="dark:text-gray-300">URL</th>
-			<td class="dark:text-gray-200"><a href="%s" target="_blank" class="text-blue-600 dark:text-blue-400 hover:underline">%s</a></td>
-		</tr>`,
-			item.URL, item.URL)
-	case models.TypeTask:
-		statusClass := "bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200"
-		statusText := "Todo"
-		if item.Status == models.TaskStatusDone {
-			statusClass = "bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200"
-			statusText = "Done"
-		}
-		metadataTable += fmt.Sprintf(`
-		<tr>
-			<th class="dark:text-gray-300">Status</th>
-			<td class="dark:text-gray-200">
-				<span class="inline-block px-2 py-1 text-xs rounded %s">%s</span>
-			</td>
-		</tr>`,
-			statusClass, statusText)
-	case models.TypeFile:
-		metadataTable += fmt.Sprintf(`
-		<tr>
-			<th class="dark:text-gray-300">Filename</th>
-			<td class="dark:text-gray-200">%s</td>
-		</tr>`,
-			item.Filename)
-	}
+	// Add the fields of the item's type to metadata table
+	metadataTable += renderFieldRows(item, def)
 
 	// Close the table and container
 	metadataTable += `
@@ -219,22 +247,42 @@ func (h *ItemHandler) viewItem(w http.ResponseWriter, r *http.Request) {
 				hx-delete="/api/items/%s/%s"
 				hx-target="#content"
 				hx-swap="innerHTML"
-				hx-confirm="Are you sure you want to delete this item?"
+				hx-confirm="Move this item to the trash?"
 			>
 				Delete
 			</button>
 		</div>
+		<button
+			class="w-full mt-2 px-3 py-2 bg-gray-100 text-gray-800 dark:bg-gray-700 dark:text-gray-200 rounded hover:bg-gray-200 dark:hover:bg-gray-600 class-item-history-button"
+			hx-get="/api/items/%s/%s/history"
+			hx-target="#item-history"
+			hx-swap="innerHTML"
+		>
+			History
+		</button>
 	</div>
+	<div id="item-history"></div>
 	`,
-		itemType, item.ID, itemType, item.ID, itemType, item.ID)
+		itemType, item.ID, itemType, item.ID, itemType, item.ID, itemType, item.ID)
+
+	// Linked references from other items
+	linkedReferences := h.renderLinkedReferences(item)
+
+	// File items show their stored file above the description
+	filePreview := ""
+	if itemType == models.TypeFile {
+		filePreview = renderFilePreview(h.repo, item)
+	}
 
 	tmpl := `
 	<div id="content-with-sidebar" class="flex flex-col lg:flex-row lg:space-x-6 min-h-full flex-1">
 		<div class="w-full lg:w-2/3 flex flex-col flex-shrink min-h-0">
 			<div class="space-y-6 class-item-detail flex-grow flex flex-col">
+				%s
 				<div class="prose max-w-none bg-white dark:bg-gray-800 p-6 rounded-lg border border-gray-200 dark:border-gray-700 shadow-sm class-item-content flex-grow">
 					%s
 				</div>
+				%s
 			</div>
 		</div>
 		
@@ -248,14 +296,79 @@ func (h *ItemHandler) viewItem(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `<div hx-swap-oob="innerHTML:#breadcrumb" class="flex items-center gap-2">%s</div>`, breadcrumb)
 
 	fmt.Fprintf(w, tmpl,
+		filePreview,
 		contentHTML,
+		linkedReferences,
 		actionsSidebar,
 		metadataTable)
 }
 
+// renderLinkedReferences returns the panel listing the items that link to an item
+func (h *ItemHandler) renderLinkedReferences(item *models.Item) string {
+	backlinks, err := services.NewLinkService(h.repo).Backlinks(item)
+	if err != nil {
+		log.Printf("Error loading backlinks: %v", err)
+		return ""
+	}
+
+	var references strings.Builder
+	for _, backlink := range backlinks {
+		id, sourceType, ok := strings.Cut(backlink.Source, ":")
+		if !ok {
+			continue
+		}
+
+		source, _, err := h.repo.LoadItem(id, models.ItemType(sourceType))
+		if err != nil {
+			continue
+		}
+		title := source.Title
+		if title == "" {
+			title = source.ID
+		}
+
+		fmt.Fprintf(&references, `
+			<li class="py-3 class-linked-reference">
+				<a
+					href="/items/%s/%s"
+					class="text-blue-600 dark:text-blue-400 hover:text-blue-800 dark:hover:text-blue-300 font-medium"
+					hx-get="/api/items/%s/%s"
+					hx-target="#content"
+					hx-swap="innerHTML"
+					hx-push-url="/items/%s/%s"
+				>%s</a>
+				<span class="ml-2 text-xs text-gray-500 dark:text-gray-400">%s</span>
+				<p class="mt-1 text-sm text-gray-600 dark:text-gray-300 class-linked-reference-context">%s</p>
+			</li>`,
+			source.Type, source.ID,
+			source.Type, source.ID,
+			source.Type, source.ID,
+			html.EscapeString(title),
+			typeLabel(h.repo, source.Type),
+			html.EscapeString(backlink.Context),
+		)
+	}
+
+	if references.Len() == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(`
+				<div class="bg-white dark:bg-gray-800 p-6 rounded-lg border border-gray-200 dark:border-gray-700 shadow-sm class-linked-references">
+					<h3 class="text-lg font-semibold mb-2 dark:text-gray-200">Linked references</h3>
+					<ul class="divide-y divide-gray-200 dark:divide-gray-700">%s
+					</ul>
+				</div>`, references.String())
+}
+
 // listItems returns a list of items of a given type
 func (h *ItemHandler) listItems(w http.ResponseWriter, r *http.Request) {
-	itemType := models.ItemType(chi.URLParam(r, "type"))
+	itemType, ok := typeParam(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	def, _ := h.repo.Type(itemType)
 
 	items, err := h.repo.ListItems(itemType)
 	if err != nil {
@@ -271,25 +384,32 @@ func (h *ItemHandler) listItems(w http.ResponseWriter, r *http.Request) {
             </svg>
         </a>
 		<span class="text-gray-500 dark:text-gray-400 flex-shrink-0">/</span>
-		<span class="text-gray-600 dark:text-gray-300">%ss</span>
-	`, strings.Title(string(itemType)))
+		<span class="text-gray-600 dark:text-gray-300">%s</span>
+	`, html.EscapeString(def.Plural))
 
 	w.Header().Set("Content-Type", "text/html")
 
 	// Update breadcrumb via HTMX
 	fmt.Fprintf(w, `<div hx-swap-oob="innerHTML:#breadcrumb" class="flex items-center gap-2">%s</div>`, breadcrumb)
 
-	// Table header that matches the design with title and create button
-	fmt.Fprintf(w, `
-	<div class="flex justify-between items-center mb-6">
-		<h1 class="text-2xl font-bold class-page-title">%ss</h1>
+	// Files are created by uploading them, other items start from the type's template
+	createControl := fmt.Sprintf(`
 		<button 
 			class="px-3 py-1 bg-indigo-600 text-white rounded hover:bg-indigo-700 dark:bg-indigo-700 dark:hover:bg-indigo-800 class-create-item"
 			hx-post="/api/items/%s"
 			hx-target="#content"
 		>
 			Create %s
-		</button>
+		</button>`, itemType, html.EscapeString(def.Label))
+	if itemType == models.TypeFile {
+		createControl = renderUploadForm()
+	}
+
+	// Table header that matches the design with title and create control
+	fmt.Fprintf(w, `
+	<div class="flex justify-between items-center mb-6">
+		<h1 class="text-2xl font-bold class-page-title">%s</h1>
+		%s
 	</div>
 	<div class="bg-white dark:bg-gray-800 rounded-lg shadow-sm border border-gray-200 dark:border-gray-700 overflow-hidden class-items-list">
 		<table class="min-w-full divide-y divide-gray-200 dark:divide-gray-700">
@@ -301,7 +421,7 @@ func (h *ItemHandler) listItems(w http.ResponseWriter, r *http.Request) {
 				</tr>
 			</thead>
 			<tbody class="bg-white dark:bg-gray-800 divide-y divide-gray-200 dark:divide-gray-700 class-items-rows">
-	`, strings.Title(string(itemType)), itemType, strings.Title(string(itemType)))
+	`, html.EscapeString(def.Plural), createControl)
 
 	if len(items) == 0 {
 		fmt.Fprintf(w, `
@@ -310,7 +430,7 @@ func (h *ItemHandler) listItems(w http.ResponseWriter, r *http.Request) {
 				No items found. Create your first %s to get started.
 			</td>
 		</tr>
-		`, itemType)
+		`, html.EscapeString(strings.ToLower(def.Label)))
 	}
 
 	for _, item := range items {
@@ -320,12 +440,7 @@ func (h *ItemHandler) listItems(w http.ResponseWriter, r *http.Request) {
 		if title == "" {
 			_, rawContent, err := h.repo.LoadItem(item.ID, itemType)
 			if err == nil {
-				title = md.ExtractTitleFromContent(rawContent, string(itemType))
-				// Update metadata if title was extracted
-				if title != "" {
-					item.Title = title
-					h.repo.SaveItem(item, "")
-				}
+				title = services.ContentTitle(rawContent, itemType)
 			}
 		}
 
@@ -364,7 +479,7 @@ func (h *ItemHandler) listItems(w http.ResponseWriter, r *http.Request) {
 					hx-delete="/api/items/%s/%s"
 					hx-target="#content"
 					hx-swap="innerHTML"
-					hx-confirm="Are you sure you want to delete this item?"
+					hx-confirm="Move this item to the trash?"
 				>
 					Delete
 				</button>
@@ -391,28 +506,36 @@ func (h *ItemHandler) listItems(w http.ResponseWriter, r *http.Request) {
 
 // createItem handles creation of new items
 func (h *ItemHandler) createItem(w http.ResponseWriter, r *http.Request) {
-	itemType := models.ItemType(chi.URLParam(r, "type"))
+	itemType, ok := typeParam(w, r, h.repo)
+	if !ok {
+		return
+	}
 
-	// Generate ID based on timestamp
-	id := time.Now().UTC().Format("20060102150405")
+	if itemType == models.TypeFile {
+		http.Error(w, "Files are created by uploading them to /api/files", http.StatusBadRequest)
+		return
+	}
 
-	item := models.NewItem(itemType, id)
+	def, _ := h.repo.Type(itemType)
+	item := models.NewItem(itemType, "")
 
-	// Save the new item with empty content
-	if err := h.repo.SaveItem(item, ""); err != nil {
+	// Save the new item with the type's template under a unique ID
+	if err := h.repo.CreateItem(item, def.NewContent(time.Now())); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Redirect to edit view for the new item
-	w.Header().Set("HX-Redirect", fmt.Sprintf("/items/%s/%s/edit", itemType, id))
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/items/%s/%s/edit", itemType, item.ID))
 	w.WriteHeader(http.StatusOK)
 }
 
 // updateContent handles updating the content of an item
 func (h *ItemHandler) updateContent(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	itemType := models.ItemType(chi.URLParam(r, "type"))
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return
+	}
 
 	// Get item
 	item, _, err := h.repo.LoadItem(id, itemType)
@@ -445,38 +568,41 @@ func (h *ItemHandler) updateContent(w http.ResponseWriter, r *http.Request) {
 		}
 		content = r.FormValue("content")
 		shouldRedirect = r.FormValue("redirect") == "true"
-	}
-
-	// Extract hashtags from content
-	previousTags := item.Tags
-	extractedTags := h.tagService.ExtractTags(content)
-
-	// Update item tags
-	if len(extractedTags) > 0 || len(previousTags) > 0 {
-		item.Tags = extractedTags
 
-		// Update tag indices
-		if err := h.tagService.UpdateItemTags(item, previousTags); err != nil {
-			http.Error(w, "Failed to update tag indices: "+err.Error(), http.StatusInternalServerError)
+		// The editor sends the fields of the item's type along with the content
+		if err := h.repo.SetFields(item, fieldValues(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
 
+	previousTags := item.Tags
+
 	// Auto-update title from content if needed
-	newTitle := md.ExtractTitleFromContent(content, string(itemType))
+	newTitle := services.ContentTitle(content, itemType)
 	if newTitle != "" && (item.Title == "" || item.Title == item.ID) {
 		item.Title = newTitle
-		h.repo.SaveItem(item, "")
 	}
 
-	// Update modification time
-	item.Modified = time.Now().UTC()
-
-	// Save item with new content
-	if err := h.repo.SaveItem(item, content); err != nil {
+	// Save the content and tags, rejecting the update if the item changed since the client loaded it
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		ifMatch = "*"
+	}
+	etag, err := h.repo.UpdateContentIfMatch(item, content, ifMatch)
+	if errors.Is(err, services.ErrConflict) {
+		h.writeConflict(w, r, content)
+		return
+	}
+	if errors.Is(err, services.ErrLocked) {
+		http.Error(w, "Failed to save encrypted item: "+err.Error(), http.StatusLocked)
+		return
+	}
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("ETag", etag)
 
 	// Respond based on request type
 	if shouldRedirect {
@@ -523,8 +649,10 @@ func stringSlicesEqual(a, b []string) bool {
 
 // editItem shows the editor for an item
 func (h *ItemHandler) editItem(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	itemType := models.ItemType(chi.URLParam(r, "type"))
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return
+	}
 
 	item, content, err := h.repo.LoadItem(id, itemType)
 	if err != nil {
@@ -532,6 +660,14 @@ func (h *ItemHandler) editItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Saving the sealed text would fail, it can only be edited while unlocked
+	if services.IsSealed(content) {
+		http.Error(w, "Failed to edit item: "+services.ErrLocked.Error(), http.StatusLocked)
+		return
+	}
+
+	def, _ := h.repo.Type(itemType)
+
 	// Breadcrumb data
 	breadcrumb := fmt.Sprintf(`
 		<a href="/" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300 flex-shrink-0 inline-flex items-center" hx-boost="true">
@@ -540,11 +676,16 @@ func (h *ItemHandler) editItem(w http.ResponseWriter, r *http.Request) {
             </svg>
         </a>
 		<span class="text-gray-500 dark:text-gray-400 flex-shrink-0">/</span>
-		<a href="/%ss" hx-get="/api/items/%s" hx-target="#content" hx-push-url="/%ss" hx-boost="true" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300 flex-shrink-0 inline-flex items-center">%ss</a>
+		<a href="/%ss" hx-get="/api/items/%s" hx-target="#content" hx-push-url="/%ss" hx-boost="true" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300 flex-shrink-0 inline-flex items-center">%s</a>
 		<span class="text-gray-500 dark:text-gray-400 flex-shrink-0">/</span>
 		<span class="text-gray-600 dark:text-gray-300 truncate">%s</span>
-	`, itemType, itemType, itemType, strings.Title(string(itemType)), item.Title)
+	`, itemType, itemType, itemType, html.EscapeString(def.Plural), item.Title)
 
+	// The editor sends the ETag back so saves over someone else's changes are detected
+	etag := services.ItemETag(item, content)
+	etagHeader, _ := json.Marshal(map[string]string{"If-Match": etag})
+
+	w.Header().Set("ETag", etag)
 	w.Header().Set("Content-Type", "text/html")
 
 	// Update breadcrumb via HTMX
@@ -576,11 +717,12 @@ func (h *ItemHandler) editItem(w http.ResponseWriter, r *http.Request) {
 		<form 
 			id="editor-form"
 			hx-put="/api/items/%s/%s/content"
+			hx-headers='%s'
 			hx-trigger="submit"
 			hx-indicator="#saving-indicator"
 			hx-on::before-request="disableSaveButton()"
 			class="bg-white dark:bg-gray-800 p-4 rounded-lg border border-gray-200 dark:border-gray-700 shadow-sm class-editor-form flex-1 flex flex-col"
-		>
+		>%s
 			<div class="flex-1 flex flex-col class-editor-preview">
 				<label class="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-2" for="content">Content</label>
 				<textarea 
@@ -592,6 +734,9 @@ func (h *ItemHandler) editItem(w http.ResponseWriter, r *http.Request) {
 			<input type="hidden" name="redirect" value="true">
 		</form>
 		
+		<!-- Merge view shown when the item changed while editing -->
+		<div id="merge-view" class="class-merge-container"></div>
+
 		<!-- Saving indicator -->
 		<div id="saving-indicator" class="fixed bottom-4 left-4 bg-blue-500 text-white px-4 py-2 rounded shadow class-save-indicator htmx-indicator">
 			Saving...
@@ -605,12 +750,38 @@ func (h *ItemHandler) editItem(w http.ResponseWriter, r *http.Request) {
 			saveButton.classList.add('opacity-50');
 		}
 		
+		function enableSaveButton() {
+			const saveButton = document.getElementById('save-button');
+			if (saveButton) {
+				saveButton.disabled = false;
+				saveButton.classList.remove('opacity-50');
+			}
+		}
+		
+		function useSavedVersion() {
+			document.getElementById('content').value = document.getElementById('merge-saved').textContent;
+			document.getElementById('merge-view').innerHTML = '';
+		}
+		
 		document.addEventListener('htmx:beforeSwap', function(evt) {
 			// Check if this is a response from our content save endpoint
 			if (evt.detail.requestConfig && 
 				evt.detail.requestConfig.path && 
 				evt.detail.requestConfig.path.includes('/content')) {
 				
+				// The item changed since the editor was opened, show both versions
+				if (evt.detail.xhr.status === 409) {
+					evt.detail.shouldSwap = true;
+					evt.detail.isError = false;
+					evt.detail.target = document.getElementById('merge-view');
+					
+					// The next save is based on the version now on disk
+					const etag = evt.detail.xhr.getResponseHeader('ETag');
+					document.getElementById('editor-form').setAttribute('hx-headers', JSON.stringify({'If-Match': etag}));
+					enableSaveButton();
+					return;
+				}
+				
 				// Prevent the default content swap
 				evt.detail.shouldSwap = false;
 				
@@ -622,11 +793,7 @@ func (h *ItemHandler) editItem(w http.ResponseWriter, r *http.Request) {
 				setTimeout(() => savedIndicator.remove(), 2000);
 				
 				// Enable save button
-				const saveButton = document.getElementById('save-button');
-				if (saveButton) {
-					saveButton.disabled = false;
-					saveButton.classList.remove('opacity-50');
-				}
+				enableSaveButton();
 				
 				// Return to the previous page
 				window.history.back();
@@ -636,17 +803,18 @@ func (h *ItemHandler) editItem(w http.ResponseWriter, r *http.Request) {
 	`
 
 	fmt.Fprintf(w, tmpl,
-		strings.Title(string(itemType)),
+		html.EscapeString(def.Label),
 		itemType, item.ID,
+		html.EscapeString(string(etagHeader)),
+		renderFieldInputs(item, def),
 		content,
 	)
 }
 
 // listItemsByTag returns a list of items with a specific tag
 func (h *ItemHandler) listItemsByTag(w http.ResponseWriter, r *http.Request) {
-	tag := chi.URLParam(r, "tag")
-	if tag == "" {
-		http.Error(w, "Tag is required", http.StatusBadRequest)
+	tag, ok := tagParam(w, r)
+	if !ok {
 		return
 	}
 
@@ -736,7 +904,7 @@ func (h *ItemHandler) listItemsByTag(w http.ResponseWriter, r *http.Request) {
 			item.Type, item.ID,
 			item.Type, item.ID,
 			title,
-			strings.Title(string(item.Type)),
+			typeLabel(h.repo, item.Type),
 			item.Modified.Format("Jan 2, 2006 3:04 PM"),
 		)
 	}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -202,6 +205,60 @@ func TestViewItem(t *testing.T) {
 	}
 }
 
+func TestViewItemLinkedReferences(t *testing.T) {
+	repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	target := models.NewItem(models.TypeNote, "target")
+	target.Title = "Target Note"
+	if err := repo.SaveItem(target, "# Target Note"); err != nil {
+		t.Fatalf("Failed to save target item: %v", err)
+	}
+
+	source := models.NewItem(models.TypeTask, "source")
+	source.Title = "Source Task"
+	if err := repo.SaveItem(source, "Intro. Read [[target]] before Monday. Outro."); err != nil {
+		t.Fatalf("Failed to save source item: %v", err)
+	}
+
+	handler := NewItemHandler(repo)
+
+	r := httptest.NewRequest("GET", "/note/target", nil)
+	w := httptest.NewRecorder()
+	r = addChiURLParams(r, map[string]string{
+		"type": "note",
+		"id":   "target",
+	})
+	handler.Routes().ServeHTTP(w, r)
+
+	response := w.Body.String()
+	if !strings.Contains(response, "Linked references") {
+		t.Errorf("Response doesn't contain the linked references panel")
+	}
+	if !strings.Contains(response, `href="/items/task/source"`) {
+		t.Errorf("Response doesn't link to the referencing item")
+	}
+	if !strings.Contains(response, "Read Target Note before Monday.") {
+		t.Errorf("Response doesn't contain the reference context")
+	}
+
+	// The source renders the link to the target
+	r = httptest.NewRequest("GET", "/task/source", nil)
+	w = httptest.NewRecorder()
+	r = addChiURLParams(r, map[string]string{
+		"type": "task",
+		"id":   "source",
+	})
+	handler.Routes().ServeHTTP(w, r)
+
+	if !strings.Contains(w.Body.String(), `<a href="/items/note/target" class="wiki-link">Target Note</a>`) {
+		t.Errorf("Response doesn't contain the resolved wiki link")
+	}
+	if strings.Contains(w.Body.String(), "Linked references") {
+		t.Errorf("Items without backlinks shouldn't show the panel")
+	}
+}
+
 func TestListItems(t *testing.T) {
 	repo, cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -369,3 +426,464 @@ func TestDeleteItemWithTags(t *testing.T) {
 		t.Errorf("Expected 0 items with tag2 after deletion, got %d", len(items2))
 	}
 }
+
+func TestRestoreRevision(t *testing.T) {
+	repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	item := models.NewItem(models.TypeNote, "test-history")
+	if err := repo.SaveItem(item, "Good content #good"); err != nil {
+		t.Fatalf("Failed to save test item: %v", err)
+	}
+	if err := repo.UpdateContent(item, "Bad content #bad"); err != nil {
+		t.Fatalf("Failed to update test item: %v", err)
+	}
+
+	revisions, err := services.NewHistoryService(repo).Revisions(item)
+	if err != nil || len(revisions) != 2 {
+		t.Fatalf("Expected 2 revisions, got %d (%v)", len(revisions), err)
+	}
+	oldest := revisions[1].ID
+
+	handler := NewItemHandler(repo)
+
+	// List revisions
+	r := httptest.NewRequest("GET", "/note/test-history/history", nil)
+	w := httptest.NewRecorder()
+	handler.Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "2 revisions") {
+		t.Errorf("Expected revision list, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Diff against the current content
+	r = httptest.NewRequest("GET", "/note/test-history/history/diff?from="+oldest+"&format=diff", nil)
+	w = httptest.NewRecorder()
+	handler.Routes().ServeHTTP(w, r)
+	if !strings.Contains(w.Body.String(), "-Good content #good\n+Bad content #bad") {
+		t.Errorf("Unexpected diff: %s", w.Body.String())
+	}
+
+	// Restore the first revision
+	r = httptest.NewRequest("POST", "/note/test-history/history/"+oldest+"/restore", nil)
+	w = httptest.NewRecorder()
+	handler.Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("HX-Redirect") != "/items/note/test-history" {
+		t.Errorf("Expected redirect to the item, got %q", w.Header().Get("HX-Redirect"))
+	}
+
+	_, content, _ := repo.LoadItem("test-history", models.TypeNote)
+	if content != "Good content #good" {
+		t.Errorf("Expected restored content, got %q", content)
+	}
+
+	// Unknown revisions are not found
+	r = httptest.NewRequest("POST", "/note/test-history/history/missing/restore", nil)
+	w = httptest.NewRecorder()
+	handler.Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestUpdateContentConflict(t *testing.T) {
+	repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	item := models.NewItem(models.TypeNote, "test-conflict")
+	item.Title = "Test Conflict"
+	if err := repo.SaveItem(item, "Original content"); err != nil {
+		t.Fatalf("Failed to save test item: %v", err)
+	}
+
+	handler := NewItemHandler(repo)
+
+	// The editor hands out the ETag of the version being edited
+	r := httptest.NewRequest("GET", "/note/test-conflict/edit", nil)
+	w := httptest.NewRecorder()
+	handler.Routes().ServeHTTP(w, r)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag on the editor response")
+	}
+
+	put := func(content, ifMatch string, htmx bool) *httptest.ResponseRecorder {
+		form := url.Values{}
+		form.Add("content", content)
+		r := httptest.NewRequest("PUT", "/note/test-conflict/content", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("If-Match", ifMatch)
+		if htmx {
+			r.Header.Set("HX-Request", "true")
+		}
+		w := httptest.NewRecorder()
+		handler.Routes().ServeHTTP(w, r)
+		return w
+	}
+
+	// The first tab saves with the current ETag
+	w = put("First tab", etag, false)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	newETag := w.Header().Get("ETag")
+	if newETag == "" || newETag == etag {
+		t.Errorf("Expected a new ETag after saving, got %q", newETag)
+	}
+
+	// The second tab still has the old ETag and gets both versions back
+	w = put("Second tab", etag, false)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != newETag {
+		t.Errorf("Expected the current ETag %q, got %q", newETag, w.Header().Get("ETag"))
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"content":"First tab"`) || !strings.Contains(body, `"content":"Second tab"`) {
+		t.Errorf("Expected both versions in the conflict response: %s", body)
+	}
+	if strings.Contains(body, "0001-01-01") {
+		t.Errorf("Expected no modification time for the submitted version: %s", body)
+	}
+
+	_, content, _ := repo.LoadItem("test-conflict", models.TypeNote)
+	if content != "First tab" {
+		t.Errorf("Expected the first save to be kept, got %q", content)
+	}
+
+	// HTMX requests get the merge view
+	w = put("Second tab", etag, true)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "class-merge-view") {
+		t.Errorf("Expected merge view, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Saving again with the current ETag goes through
+	w = put("Merged", newETag, false)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateContentETagWithFrontMatter(t *testing.T) {
+	repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	config, err := repo.Config()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	config.FrontMatter.Write = true
+	if err := repo.SaveConfig(config); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	item := models.NewItem(models.TypeNote, "test-front-matter")
+	item.Title = "Front Matter"
+	if err := repo.SaveItem(item, "Original content"); err != nil {
+		t.Fatalf("Failed to save test item: %v", err)
+	}
+
+	handler := NewItemHandler(repo)
+	put := func(content, ifMatch string) *httptest.ResponseRecorder {
+		form := url.Values{}
+		form.Add("content", content)
+		r := httptest.NewRequest("PUT", "/note/test-front-matter/content", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+		handler.Routes().ServeHTTP(w, r)
+		return w
+	}
+
+	// Viewing the item doesn't change its ETag
+	r := httptest.NewRequest("GET", "/note/test-front-matter/edit", nil)
+	w := httptest.NewRecorder()
+	handler.Routes().ServeHTTP(w, r)
+	etag := w.Header().Get("ETag")
+	r = httptest.NewRequest("GET", "/note/test-front-matter", nil)
+	handler.Routes().ServeHTTP(httptest.NewRecorder(), r)
+
+	// The ETag of a save is the one of the stored content, front matter included
+	w = put("First save", etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = put("Second save", w.Header().Get("ETag"))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 saving with the returned ETag, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestInvalidItemParams(t *testing.T) {
+	repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	handler := NewItemHandler(repo)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{"GET", "/foo", http.StatusBadRequest},
+		{"POST", "/foo", http.StatusBadRequest},
+		{"GET", "/foo/20240102150405", http.StatusBadRequest},
+		{"GET", "/note/..%2F..%2Fconfig", http.StatusBadRequest},
+		{"GET", "/note/..", http.StatusBadRequest},
+		{"GET", "/note/a.b/edit", http.StatusBadRequest},
+		{"DELETE", "/note/%2Fetc%2Fpasswd", http.StatusBadRequest},
+		{"GET", "/tags/%20", http.StatusBadRequest},
+		// Valid parameters for a missing item still get a 404
+		{"GET", "/note/20240102150405", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+		handler.Routes().ServeHTTP(w, r)
+
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.want, w.Code)
+		}
+	}
+
+	// Unknown types never get a directory
+	if _, err := os.Stat(filepath.Join(repo.BasePath(), ".meta", "foos")); !os.IsNotExist(err) {
+		t.Errorf("Expected no .meta/foos directory, got %v", err)
+	}
+}
+
+func TestDeclaredTypeItems(t *testing.T) {
+	repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	config, err := repo.Config()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.Types = []services.TypeConfig{{
+		Name:     "meeting",
+		Icon:     "🗓",
+		Template: "# Meeting {{date}}",
+		Fields: []services.FieldConfig{
+			{Name: "attendees", Label: "Attendees"},
+			{Name: "decided", Type: services.FieldBool},
+		},
+	}}
+	if err := repo.SaveConfig(config); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	handler := NewItemHandler(repo)
+	serve := func(method, path string, form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		if form != nil {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		w := httptest.NewRecorder()
+		handler.Routes().ServeHTTP(w, r)
+		return w
+	}
+
+	// New items start from the type's template
+	w := serve("POST", "/meeting", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	items, err := repo.ListItems("meeting")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Expected 1 meeting, got %d (%v)", len(items), err)
+	}
+	id := items[0].ID
+	_, content, _ := repo.LoadItem(id, "meeting")
+	if !strings.HasPrefix(content, "# Meeting 20") {
+		t.Errorf("Expected the template as content, got %q", content)
+	}
+
+	// The editor shows and saves the fields of the type
+	w = serve("GET", "/meeting/"+id+"/edit", nil)
+	if !strings.Contains(w.Body.String(), `name="field.attendees"`) {
+		t.Errorf("Expected field inputs in the editor: %s", w.Body.String())
+	}
+
+	form := url.Values{}
+	form.Add("content", content)
+	form.Add("field.attendees", "Ana, <Joan>")
+	form.Add("field.decided", "false")
+	form.Add("field.decided", "true")
+	if w = serve("PUT", "/meeting/"+id+"/content", form); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve("GET", "/meeting/"+id, nil)
+	body := w.Body.String()
+	for _, want := range []string{"🗓 Meeting", "Attendees", "Ana, &lt;Joan&gt;", "Decided", "Yes", `href="/meetings"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the item view", want)
+		}
+	}
+
+	// Values that don't fit the field are refused
+	form.Set("field.decided", "perhaps")
+	if w = serve("PUT", "/meeting/"+id+"/content", form); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid field, got %d", w.Code)
+	}
+
+	w = serve("GET", "/meeting", nil)
+	if !strings.Contains(w.Body.String(), "Meetings") || !strings.Contains(w.Body.String(), "Create Meeting") {
+		t.Errorf("Expected the type's labels in the list: %s", w.Body.String())
+	}
+}
+
+func TestFileItems(t *testing.T) {
+	repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	upload := func(filename, data string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		part.Write([]byte(data))
+		form.WriteField("description", "Quarterly numbers")
+		form.Close()
+
+		r := httptest.NewRequest("POST", "/", &body)
+		r.Header.Set("Content-Type", form.FormDataContentType())
+		r.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		NewFileHandler(repo).Routes().ServeHTTP(w, r)
+		return w
+	}
+
+	w := upload("report.txt", "line one\nline two\n")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Item      models.Item `json:"item"`
+		Duplicate bool        `json:"duplicate"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Item.Filename != "report.txt" || created.Duplicate {
+		t.Errorf("Unexpected upload result: %+v", created)
+	}
+
+	// Uploading the same data again returns the existing item
+	w = upload("copy.txt", "line one\nline two\n")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), created.Item.ID) {
+		t.Errorf("Expected the existing item for a duplicate upload, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Downloads support ranges
+	r := httptest.NewRequest("GET", "/"+created.Item.ID, nil)
+	r.Header.Set("Range", "bytes=0-3")
+	w = httptest.NewRecorder()
+	NewFileHandler(repo).Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "line" {
+		t.Errorf("Expected the first 4 bytes, got %d: %q", w.Code, w.Body.String())
+	}
+	if disposition := w.Header().Get("Content-Disposition"); disposition != `attachment; filename=report.txt` {
+		t.Errorf("Unexpected Content-Disposition %q", disposition)
+	}
+
+	// Markup is never rendered inline
+	w = upload("page.html", "<script>alert(1)</script>")
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	r = httptest.NewRequest("GET", "/"+created.Item.ID+"/preview", nil)
+	w = httptest.NewRecorder()
+	NewFileHandler(repo).Routes().ServeHTTP(w, r)
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+		t.Errorf("Expected HTML to be previewed as plain text, got %q", contentType)
+	}
+
+	// The item page shows the text with the description
+	r = httptest.NewRequest("GET", "/file/"+created.Item.ID, nil)
+	r = addChiURLParams(r, map[string]string{"type": "file", "id": created.Item.ID})
+	w = httptest.NewRecorder()
+	NewItemHandler(repo).Routes().ServeHTTP(w, r)
+	response := w.Body.String()
+	if !strings.Contains(response, "&lt;script&gt;alert(1)&lt;/script&gt;") || !strings.Contains(response, "Quarterly numbers") {
+		t.Errorf("Expected the escaped text preview and description, got %s", response)
+	}
+
+	// Untagged files wait in the inbox without a way to convert them
+	r = httptest.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	NewInboxHandler(repo).Routes().ServeHTTP(w, r)
+	response = w.Body.String()
+	if !strings.Contains(response, "class-inbox-item") || strings.Contains(response, "class-inbox-convert") {
+		t.Errorf("Expected the file in the inbox without a convert form, got %s", response)
+	}
+
+	r = httptest.NewRequest("POST", "/file/"+created.Item.ID+"/convert", strings.NewReader("type=note"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	NewInboxHandler(repo).Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 converting a file item, got %d", w.Code)
+	}
+	r = httptest.NewRequest("GET", "/"+created.Item.ID, nil)
+	w = httptest.NewRecorder()
+	NewFileHandler(repo).Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the file to survive a refused conversion, got %d", w.Code)
+	}
+
+	// File items can't be created without a file
+	r = httptest.NewRequest("POST", "/file", nil)
+	r = addChiURLParams(r, map[string]string{"type": "file"})
+	w = httptest.NewRecorder()
+	NewItemHandler(repo).Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 creating a file item, got %d", w.Code)
+	}
+}
+
+func TestRepositorySelectionSameOrigin(t *testing.T) {
+	dir := t.TempDir()
+	store := services.NewRepositoryStore(filepath.Join(t.TempDir(), "repositories.json"), []string{dir})
+	handler := NewRepositoryHandler(nil, store).Routes()
+
+	selectPath := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/select", strings.NewReader(url.Values{"path": {dir}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// Pages on other sites can't make the server set up a repository
+	for _, headers := range []map[string]string{
+		{"Sec-Fetch-Site": "cross-site"},
+		{"Origin": "http://attacker.test"},
+	} {
+		if w := selectPath(headers); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for %v, got %d", headers, w.Code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".meta")); !os.IsNotExist(err) {
+		t.Errorf("Expected no repository to be set up, got %v", err)
+	}
+
+	// The selection screen itself can
+	w := selectPath(map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/" {
+		t.Errorf("Expected a redirect to the dashboard, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".meta")); err != nil {
+		t.Errorf("Expected the repository to be set up: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/models"
+	"vovere/internal/app/services"
+)
+
+// itemParams returns the {id} and {type} URL parameters. When they don't name a valid
+// item of the repository it writes 400 Bad Request and reports false.
+func itemParams(w http.ResponseWriter, r *http.Request, repo *services.Repository) (string, models.ItemType, bool) {
+	id := chi.URLParam(r, "id")
+	itemType := models.ItemType(chi.URLParam(r, "type"))
+	if err := repo.ValidateItemRef(id, itemType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", "", false
+	}
+	return id, itemType, true
+}
+
+// typeParam returns the {type} URL parameter, writing 400 Bad Request for types the repository doesn't have
+func typeParam(w http.ResponseWriter, r *http.Request, repo *services.Repository) (models.ItemType, bool) {
+	itemType := models.ItemType(chi.URLParam(r, "type"))
+	if err := repo.ValidateItemType(itemType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return itemType, true
+}
+
+// tagParam returns the {tag} URL parameter, writing 400 Bad Request for invalid tags
+func tagParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tag := chi.URLParam(r, "tag")
+	if err := services.ValidateTag(tag); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return tag, true
+}
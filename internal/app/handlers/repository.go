@@ -2,32 +2,31 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-)
 
-// RepositoryConfig represents configuration for a repository
-type RepositoryConfig struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Tags        []string `json:"tags"`
-}
+	"vovere/internal/app/services"
+)
 
 // RepositoryHandler handles repository selection and management
 type RepositoryHandler struct {
-	tmpl *template.Template
+	tmpl  *template.Template
+	store *services.RepositoryStore
 }
 
 // NewRepositoryHandler creates a new repository handler
-func NewRepositoryHandler(tmpl *template.Template) *RepositoryHandler {
+func NewRepositoryHandler(tmpl *template.Template, store *services.RepositoryStore) *RepositoryHandler {
 	return &RepositoryHandler{
-		tmpl: tmpl,
+		tmpl:  tmpl,
+		store: store,
 	}
 }
 
@@ -36,46 +35,96 @@ func (h *RepositoryHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
 	r.Get("/", h.showSelection)
-	r.Post("/select", h.selectRepository)
-	r.Get("/select", h.selectRepository) // For recent repos
+	r.Get("/select", h.openKnownRepository) // For recent repos
+	r.Get("/repositories", h.listRepositories)
+	r.Group(func(r chi.Router) {
+		// These need no selected repository, so no cookie protects them from other sites
+		r.Use(sameOrigin)
+		r.Post("/select", h.selectRepository)
+		r.Post("/repositories", h.addRepository)
+		r.Post("/repositories/{id}/rename", h.renameRepository)
+		r.Delete("/repositories/{id}", h.forgetRepository)
+	})
 	r.Get("/config", h.getConfig)
+	r.Get("/backup", h.downloadBackup)
 	r.Get("/close", h.closeRepository) // Add endpoint for closing repository
 
 	return r
 }
 
+// sameOrigin refuses requests a browser sent from another site. Browsers name the site in
+// Sec-Fetch-Site or Origin, requests without either don't come from a web page.
+func sameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+			if site != "same-origin" && site != "none" {
+				http.Error(w, "Cross-origin requests are not allowed", http.StatusForbidden)
+				return
+			}
+		} else if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				http.Error(w, "Cross-origin requests are not allowed", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // getConfig returns repository configuration
 func (h *RepositoryHandler) getConfig(w http.ResponseWriter, r *http.Request) {
-	// Get repository from cookie
-	cookie, err := r.Cookie("repository")
+	known, err := h.selected(r)
 	if err != nil {
-		http.Error(w, "Repository not selected", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	repoPath := cookie.Value
-	if repoPath == "" {
-		http.Error(w, "Repository path is empty", http.StatusBadRequest)
+	// Load config, falling back to defaults
+	config, err := services.NewRepository(known.Path).Config()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Default config uses the last directory name
-	config := RepositoryConfig{
-		Name: filepath.Base(repoPath),
+	// Return config as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
+// downloadBackup sends a zip archive of the selected repository
+func (h *RepositoryHandler) downloadBackup(w http.ResponseWriter, r *http.Request) {
+	known, err := h.selected(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	repo := services.NewRepository(known.Path)
 
-	// Try to load config file
-	configPath := filepath.Join(repoPath, "config.json")
-	if configFile, err := os.Open(configPath); err == nil {
-		defer configFile.Close()
-		if err := json.NewDecoder(configFile).Decode(&config); err != nil {
-			// Use default on error
-		}
+	config, err := repo.Config()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	// Return config as JSON
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(config)
+	// Write the archive to disk first, saves shouldn't wait for a slow download
+	tmp, err := os.CreateTemp("", "vovere-backup-*.zip")
+	if err != nil {
+		http.Error(w, "Failed to create backup: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := services.NewBackupService(repo).Write(tmp); err != nil {
+		http.Error(w, "Failed to create backup: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	filename := fmt.Sprintf("%s-%s.zip", config.Name, now.UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	http.ServeContent(w, r, filename, now, tmp)
 }
 
 // showSelection shows the repository selection screen
@@ -94,7 +143,7 @@ func (h *RepositoryHandler) showSelection(w http.ResponseWriter, r *http.Request
 	}
 }
 
-// selectRepository handles repository selection
+// selectRepository adds the repository at the path form value, setting it up if needed, and opens it
 func (h *RepositoryHandler) selectRepository(w http.ResponseWriter, r *http.Request) {
 	path := r.FormValue("path")
 	if path == "" {
@@ -103,22 +152,131 @@ func (h *RepositoryHandler) selectRepository(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Ensure path exists and is a directory
-	info, err := os.Stat(path)
+	// Only directories that exist and are within the allowed roots are added
+	known, err := h.store.Add(path, "")
+	if err != nil {
+		http.Redirect(w, r, "/api/repository?error="+url.QueryEscape("Cannot open repository: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	if err := setupRepository(known.Path); err != nil {
+		http.Redirect(w, r, "/api/repository?error="+url.QueryEscape("Cannot open repository: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	h.open(w, r, known)
+}
+
+// openKnownRepository opens a known repository by the id query parameter
+func (h *RepositoryHandler) openKnownRepository(w http.ResponseWriter, r *http.Request) {
+	known, err := h.store.Get(r.URL.Query().Get("id"))
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Directory doesn't exist, return error instead of creating it
-			http.Redirect(w, r, "/api/repository?error="+url.QueryEscape("Repository directory does not exist"), http.StatusSeeOther)
-			return
-		} else {
-			http.Redirect(w, r, "/api/repository?error="+url.QueryEscape("Invalid repository path"), http.StatusSeeOther)
-			return
+		http.Redirect(w, r, "/api/repository?error="+url.QueryEscape("Cannot open repository: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	if err := services.NewRepository(known.Path).CheckSchema(); err != nil {
+		http.Redirect(w, r, "/api/repository?error="+url.QueryEscape("Cannot open repository: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	h.open(w, r, known)
+}
+
+// open sets the repository cookie to a known repository's ID and goes to the dashboard
+func (h *RepositoryHandler) open(w http.ResponseWriter, r *http.Request, known *services.KnownRepository) {
+	if _, err := h.store.Touch(known.ID); err != nil {
+		http.Redirect(w, r, "/api/repository?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	// The cookie names the repository by ID, never by path
+	http.SetCookie(w, &http.Cookie{
+		Name:     "repository",
+		Value:    known.ID,
+		Path:     "/",
+		MaxAge:   86400 * 30, // 30 days
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// listRepositories returns the known repositories as JSON, most recently opened first
+func (h *RepositoryHandler) listRepositories(w http.ResponseWriter, r *http.Request) {
+	repos, err := h.store.List()
+	if err != nil {
+		http.Error(w, "Failed to list repositories: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(repos)
+}
+
+// addRepository adds the directory at the path form value to the known repositories without opening it
+func (h *RepositoryHandler) addRepository(w http.ResponseWriter, r *http.Request) {
+	known, err := h.store.Add(r.FormValue("path"), r.FormValue("name"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrPathNotAllowed) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, "Failed to add repository: "+err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(known)
+}
+
+// renameRepository changes the name a known repository is listed with
+func (h *RepositoryHandler) renameRepository(w http.ResponseWriter, r *http.Request) {
+	known, err := h.store.Rename(chi.URLParam(r, "id"), r.FormValue("name"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrUnknownRepository) {
+			status = http.StatusNotFound
 		}
-	} else if !info.IsDir() {
-		http.Redirect(w, r, "/api/repository?error="+url.QueryEscape("Path must be a directory"), http.StatusSeeOther)
+		http.Error(w, "Failed to rename repository: "+err.Error(), status)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(known)
+}
+
+// forgetRepository removes a repository from the known repositories, leaving its files alone
+func (h *RepositoryHandler) forgetRepository(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Forget(chi.URLParam(r, "id")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrUnknownRepository) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, "Failed to forget repository: "+err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// selected returns the known repository named by the repository cookie
+func (h *RepositoryHandler) selected(r *http.Request) (*services.KnownRepository, error) {
+	cookie, err := r.Cookie("repository")
+	if err != nil || cookie.Value == "" {
+		return nil, fmt.Errorf("repository not selected")
+	}
+	return h.store.Get(cookie.Value)
+}
+
+// setupRepository creates the directories and default config of a new repository.
+// Repositories this version can't read are refused before anything is touched.
+func setupRepository(path string) error {
+	if err := services.NewRepository(path).CheckSchema(); err != nil {
+		return err
+	}
+
 	// Create required subdirectories
 	dirs := []string{
 		filepath.Join(path, ".meta", "notes"),
@@ -133,8 +291,7 @@ func (h *RepositoryHandler) selectRepository(w http.ResponseWriter, r *http.Requ
 
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			http.Redirect(w, r, "/api/repository?error="+url.QueryEscape("Failed to create repository structure"), http.StatusSeeOther)
-			return
+			return fmt.Errorf("failed to create repository structure: %w", err)
 		}
 	}
 
@@ -142,58 +299,17 @@ func (h *RepositoryHandler) selectRepository(w http.ResponseWriter, r *http.Requ
 	// TODO: review this, it isn't really needed by default.
 	configPath := filepath.Join(path, "config.json")
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		config := RepositoryConfig{
-			Name:        filepath.Base(path),
-			Description: "Vovere knowledge repository",
-			Tags:        []string{},
+		config := services.RepositoryConfig{
+			SchemaVersion: services.SchemaVersion,
+			Name:          filepath.Base(path),
+			Description:   "Vovere knowledge repository",
+			Tags:          []string{},
 		}
 
-		configFile, err := os.Create(configPath)
-		if err == nil {
-			defer configFile.Close()
-			json.NewEncoder(configFile).Encode(config)
-		}
+		services.NewRepository(path).SaveConfig(&config)
 	}
 
-	// Set repository cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "repository",
-		Value:    path,
-		Path:     "/",
-		MaxAge:   86400 * 30, // 30 days
-		HttpOnly: true,
-		SameSite: http.SameSiteStrictMode,
-	})
-
-	// Save to localStorage via script (for recent repositories)
-	w.Header().Set("Content-Type", "text/html")
-	script := `
-	<script>
-		// Get existing repos from localStorage or initialize empty array
-		const recentRepos = JSON.parse(localStorage.getItem('recentRepos') || '[]');
-		
-		// Create new entry
-		const newRepo = {
-			path: "%s",
-			lastAccessed: new Date().toISOString()
-		};
-		
-		// Remove existing entry with same path
-		const filteredRepos = recentRepos.filter(repo => repo.path !== newRepo.path);
-		
-		// Add to front of array and limit to 5 entries
-		filteredRepos.unshift(newRepo);
-		if (filteredRepos.length > 5) filteredRepos.pop();
-		
-		// Save back to localStorage
-		localStorage.setItem('recentRepos', JSON.stringify(filteredRepos));
-		localStorage.setItem('currentRepo', "%s");
-		
-		// Redirect to main page with theme preservation
-		window.location.href = '/';
-	</script>
-	`
-	fmt.Fprintf(w, script, path, path)
+	return nil
 }
 
 // closeRepository handles closing the current repository
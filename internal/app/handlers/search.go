@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/services"
+)
+
+// defaultSearchLimit is the number of results returned when no limit is given
+const defaultSearchLimit = 50
+
+// SearchHandler handles HTTP requests for full-text search
+type SearchHandler struct {
+	repo          *services.Repository
+	searchService *services.SearchService
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(repo *services.Repository) *SearchHandler {
+	return &SearchHandler{
+		repo:          repo,
+		searchService: services.NewSearchService(repo),
+	}
+}
+
+// Routes returns the router for search endpoints
+func (h *SearchHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.search)
+
+	return r
+}
+
+// search runs a query and returns the results as an HTMX fragment or JSON
+func (h *SearchHandler) search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	limit := defaultSearchLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	parsed, err := services.ParseQuery(query)
+	if err != nil {
+		if wantsJSON(r) {
+			http.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Keep HTMX swapping while the query is still being typed
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `
+	<div class="class-search-results">
+		<h1 class="text-2xl font-bold mb-6 class-page-title">Search</h1>
+		<p class="text-sm text-red-600 dark:text-red-400 class-search-error">Invalid query: %s</p>
+	</div>
+	`, html.EscapeString(err.Error()))
+		return
+	}
+
+	results, err := h.searchService.Query(parsed, limit)
+	if err != nil {
+		http.Error(w, "Failed to search: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		response := struct {
+			Query   string                  `json:"query"`
+			Results []services.SearchResult `json:"results"`
+		}{
+			Query:   query,
+			Results: results,
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode response: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Breadcrumb for search view
+	breadcrumb := `
+		<a href="/" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300 flex-shrink-0 inline-flex items-center" hx-boost="true">
+            <svg class="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M3 12l2-2m0 0l7-7 7 7M5 10v10a1 1 0 001 1h3m10-11l2 2m-2-2v10a1 1 0 01-1 1h-3m-6 0a1 1 0 001-1v-4a1 1 0 011-1h2a1 1 0 011 1v4a1 1 0 001 1m-6 0h6"></path>
+            </svg>
+        </a>
+		<span class="text-gray-500 dark:text-gray-400 flex-shrink-0">/</span>
+		<span class="text-gray-600 dark:text-gray-300">Search</span>
+	`
+
+	w.Header().Set("Content-Type", "text/html")
+
+	// Update breadcrumb via HTMX
+	fmt.Fprintf(w, `<div hx-swap-oob="innerHTML:#breadcrumb" class="flex items-center gap-2">%s</div>`, breadcrumb)
+
+	if query == "" {
+		fmt.Fprint(w, `
+	<div class="class-search-results">
+		<h1 class="text-2xl font-bold mb-6 class-page-title">Search</h1>
+		<p class="text-sm text-gray-500 dark:text-gray-400">Type something to search titles, content, tags and types.</p>
+		<p class="mt-2 text-sm text-gray-500 dark:text-gray-400">
+			Narrow results with filters such as <code>type:task</code>, <code>status:todo</code>, <code>tag:project:*</code>,
+			<code>created:&gt;2025-01-01</code> or <code>"exact phrase"</code>. Prefix any of them with <code>-</code> to exclude matches.
+		</p>
+	</div>
+	`)
+		return
+	}
+
+	fmt.Fprintf(w, `
+	<div class="class-search-results">
+		<div class="flex justify-between items-center mb-6">
+			<h1 class="text-2xl font-bold class-page-title">Search results for "%s"</h1>
+			<span class="text-sm text-gray-500 dark:text-gray-400">%d result%s</span>
+		</div>
+		<div class="bg-white dark:bg-gray-800 rounded-lg shadow-sm border border-gray-200 dark:border-gray-700 divide-y divide-gray-200 dark:divide-gray-700 class-items-list">
+	`, html.EscapeString(query), len(results), plural(len(results)))
+
+	if len(results) == 0 {
+		fmt.Fprint(w, `
+			<div class="px-6 py-4 text-sm text-center text-gray-500 dark:text-gray-400">
+				No items match your search.
+			</div>
+		`)
+	}
+
+	for _, result := range results {
+		title := result.Title
+		if title == "" {
+			title = result.ID
+		}
+
+		tags := ""
+		for _, tag := range result.Tags {
+			tags += fmt.Sprintf(`<a href="%s" class="tag-link mr-2">#%s</a>`, tagHref(tag), html.EscapeString(tag))
+		}
+
+		fmt.Fprintf(w, `
+			<div class="px-6 py-4 hover:bg-gray-50 dark:hover:bg-gray-700 class-search-result">
+				<div class="flex items-center justify-between">
+					<a
+						href="/items/%s/%s"
+						class="text-blue-600 dark:text-blue-400 hover:text-blue-800 dark:hover:text-blue-300 font-medium class-item-title"
+						hx-get="/api/items/%s/%s"
+						hx-target="#content"
+						hx-swap="innerHTML"
+						hx-push-url="/items/%s/%s"
+					>%s</a>
+					<span class="text-xs text-gray-500 dark:text-gray-400 class-item-type">%s</span>
+				</div>
+				<p class="mt-1 text-sm text-gray-600 dark:text-gray-300 class-search-snippet">%s</p>
+				<div class="mt-1 text-xs text-indigo-600 dark:text-indigo-400">%s</div>
+			</div>`,
+			result.Type, result.ID,
+			result.Type, result.ID,
+			result.Type, result.ID,
+			html.EscapeString(title),
+			typeLabel(h.repo, result.Type),
+			result.Snippet,
+			tags,
+		)
+	}
+
+	// Close results container
+	fmt.Fprint(w, `
+		</div>
+	</div>
+	`)
+}
+
+// wantsJSON reports whether the client asked for a JSON response
+func wantsJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "json" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 
@@ -136,7 +138,7 @@ func (h *TagHandler) RenderTagList() (string, error) {
 		fmt.Fprintf(&buf, `
 			<tr class="hover:bg-gray-50 dark:hover:bg-gray-700">
 				<td class="px-6 py-4 whitespace-nowrap">
-					<a href="/tags/%s" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300">
+					<a href="%s" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300">
 						#%s
 					</a>
 				</td>
@@ -144,7 +146,7 @@ func (h *TagHandler) RenderTagList() (string, error) {
 					%d item%s
 				</td>
 			</tr>
-		`, tag.Name, tag.Name, tag.Count, plural(tag.Count))
+		`, tagHref(tag.Name), html.EscapeString(tag.Name), tag.Count, plural(tag.Count))
 	}
 
 	// Close tags table
@@ -156,3 +158,8 @@ func (h *TagHandler) RenderTagList() (string, error) {
 
 	return buf.String(), nil
 }
+
+// tagHref returns the link to a tag's page, escaped for an href attribute
+func tagHref(tag string) string {
+	return html.EscapeString("/tags/" + url.PathEscape(tag))
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"vovere/internal/app/services"
+)
+
+// TrashHandler handles HTTP requests for deleted items
+type TrashHandler struct {
+	repo  *services.Repository
+	trash *services.TrashService
+}
+
+// NewTrashHandler creates a new trash handler
+func NewTrashHandler(repo *services.Repository) *TrashHandler {
+	return &TrashHandler{
+		repo:  repo,
+		trash: services.NewTrashService(repo),
+	}
+}
+
+// Routes returns the router for trash endpoints
+func (h *TrashHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.listTrash)
+	r.Delete("/", h.emptyTrash)
+	r.Post("/{type}/{id}/restore", h.restoreItem)
+	r.Delete("/{type}/{id}", h.purgeItem)
+
+	return r
+}
+
+// restoreItem puts a trashed item back in the repository
+func (h *TrashHandler) restoreItem(w http.ResponseWriter, r *http.Request) {
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	item, err := h.trash.Restore(itemType, id)
+	if err != nil {
+		h.trashError(w, err)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(item)
+		return
+	}
+
+	h.listTrash(w, r)
+}
+
+// purgeItem permanently deletes a trashed item
+func (h *TrashHandler) purgeItem(w http.ResponseWriter, r *http.Request) {
+	id, itemType, ok := itemParams(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	if err := h.trash.Purge(itemType, id); err != nil {
+		h.trashError(w, err)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.listTrash(w, r)
+}
+
+// emptyTrash permanently deletes every trashed item
+func (h *TrashHandler) emptyTrash(w http.ResponseWriter, r *http.Request) {
+	if err := h.trash.Empty(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.listTrash(w, r)
+}
+
+// listTrash returns the trashed items with restore and purge actions
+func (h *TrashHandler) listTrash(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.trash.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJSON(r) {
+		if entries == nil {
+			entries = []*services.TrashEntry{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+		return
+	}
+
+	// Breadcrumb for trash view
+	breadcrumb := `
+		<a href="/" class="text-indigo-600 dark:text-indigo-400 hover:text-indigo-800 dark:hover:text-indigo-300 flex-shrink-0 inline-flex items-center" hx-boost="true">
+            <svg class="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M3 12l2-2m0 0l7-7 7 7M5 10v10a1 1 0 001 1h3m10-11l2 2m-2-2v10a1 1 0 01-1 1h-3m-6 0a1 1 0 001-1v-4a1 1 0 011-1h2a1 1 0 011 1v4a1 1 0 001 1m-6 0h6"></path>
+            </svg>
+        </a>
+		<span class="text-gray-500 dark:text-gray-400 flex-shrink-0">/</span>
+		<span class="text-gray-600 dark:text-gray-300">Trash</span>
+	`
+
+	w.Header().Set("Content-Type", "text/html")
+
+	// Update breadcrumb via HTMX
+	fmt.Fprintf(w, `<div hx-swap-oob="innerHTML:#breadcrumb" class="flex items-center gap-2">%s</div>`, breadcrumb)
+
+	emptyButton := ""
+	if len(entries) > 0 {
+		emptyButton = `
+			<button
+				class="px-3 py-1 text-sm bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-100 rounded hover:bg-red-200 dark:hover:bg-red-800 class-trash-empty"
+				hx-delete="/api/trash"
+				hx-target="#content"
+				hx-confirm="Permanently delete every item in the trash?"
+			>Empty trash</button>`
+	}
+
+	fmt.Fprintf(w, `
+	<div class="flex justify-between items-center mb-6">
+		<div>
+			<h1 class="text-2xl font-bold class-page-title">Trash</h1>
+			<span class="text-sm text-gray-500 dark:text-gray-400">%d deleted item%s</span>
+		</div>
+		%s
+	</div>
+	<div class="bg-white dark:bg-gray-800 rounded-lg shadow-sm border border-gray-200 dark:border-gray-700 divide-y divide-gray-200 dark:divide-gray-700 class-trash-list">
+	`, len(entries), plural(len(entries)), emptyButton)
+
+	if len(entries) == 0 {
+		fmt.Fprint(w, `
+		<div class="px-6 py-4 text-sm text-center text-gray-500 dark:text-gray-400">
+			The trash is empty.
+		</div>
+		`)
+	}
+
+	for _, entry := range entries {
+		item := entry.Item
+		title := item.Title
+		if title == "" {
+			title = item.ID
+		}
+
+		fmt.Fprintf(w, `
+		<div class="px-6 py-4 flex items-center justify-between gap-2 class-trash-item">
+			<div>
+				<span class="font-medium dark:text-gray-200 class-item-title">%s</span>
+				<span class="ml-2 text-xs text-gray-500 dark:text-gray-400 class-item-type">%s</span>
+				<div class="text-xs text-gray-500 dark:text-gray-400">Deleted %s</div>
+			</div>
+			<div class="flex gap-1 flex-shrink-0">
+				<button
+					class="px-2 py-1 text-sm bg-blue-100 text-blue-800 dark:bg-blue-800 dark:text-blue-100 rounded hover:bg-blue-200 dark:hover:bg-blue-700 class-trash-restore"
+					hx-post="/api/trash/%s/%s/restore"
+					hx-target="#content"
+				>Restore</button>
+				<button
+					class="px-2 py-1 text-sm bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-100 rounded hover:bg-red-200 dark:hover:bg-red-800 class-trash-purge"
+					hx-delete="/api/trash/%s/%s"
+					hx-target="#content"
+					hx-confirm="Permanently delete this item?"
+				>Delete forever</button>
+			</div>
+		</div>`,
+			html.EscapeString(title),
+			item.Type,
+			entry.Deleted.Local().Format("Jan 2, 2006 3:04 PM"),
+			item.Type, item.ID,
+			item.Type, item.ID)
+	}
+
+	fmt.Fprint(w, `
+	</div>`)
+}
+
+// trashError writes a 404 for items not in the trash and a 409 when restoring would overwrite an item
+func (h *TrashHandler) trashError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotInTrash):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrItemExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
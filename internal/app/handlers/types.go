@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"vovere/internal/app/models"
+	"vovere/internal/app/services"
+)
+
+// fieldPrefix starts the names of field inputs in the editor form
+const fieldPrefix = "field."
+
+// typeBadgeClass returns the classes of a badge in a type's color
+func typeBadgeClass(def services.TypeConfig) string {
+	return fmt.Sprintf("bg-%[1]s-100 text-%[1]s-800 dark:bg-%[1]s-900 dark:text-%[1]s-200", def.Color)
+}
+
+// typeLabel returns the display name of an item type, the type itself when the repository doesn't have it
+func typeLabel(repo *services.Repository, itemType models.ItemType) string {
+	def, err := repo.Type(itemType)
+	if err != nil {
+		return string(itemType)
+	}
+	return def.Label
+}
+
+// renderFieldRows returns the metadata table rows of an item's fields
+func renderFieldRows(item *models.Item, def services.TypeConfig) string {
+	var rows strings.Builder
+	for _, field := range def.Fields {
+		value := services.FieldValue(item, field)
+
+		cell := `<span class="text-gray-400 dark:text-gray-500">None</span>`
+		switch {
+		case value == "":
+		case field.Type == services.FieldURL && isWebURL(value):
+			cell = fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener" class="text-blue-600 dark:text-blue-400 hover:underline">%s</a>`,
+				html.EscapeString(value), html.EscapeString(value))
+		case field.Type == services.FieldBool:
+			cell = "No"
+			if value == "true" {
+				cell = "Yes"
+			}
+		case field.Type == services.FieldSelect:
+			cell = fmt.Sprintf(`<span class="inline-block px-2 py-1 text-xs rounded %s">%s</span>`,
+				typeBadgeClass(def), html.EscapeString(capitalize(value)))
+		default:
+			cell = html.EscapeString(value)
+		}
+
+		fmt.Fprintf(&rows, `
+		<tr class="class-item-field">
+			<th class="dark:text-gray-300">%s</th>
+			<td class="dark:text-gray-200">%s</td>
+		</tr>`,
+			html.EscapeString(field.Label), cell)
+	}
+	return rows.String()
+}
+
+// renderFieldInputs returns the editor inputs of an item's fields, empty for types without fields
+func renderFieldInputs(item *models.Item, def services.TypeConfig) string {
+	inputClass := "w-full px-2 py-1 border rounded text-sm bg-white dark:bg-gray-700 dark:text-white dark:border-gray-600"
+
+	var inputs strings.Builder
+	for _, field := range def.Fields {
+		if field.ReadOnly {
+			continue
+		}
+		name := html.EscapeString(fieldPrefix + field.Name)
+		value := services.FieldValue(item, field)
+
+		var input string
+		switch field.Type {
+		case services.FieldBool:
+			// The hidden input sends false for an unchecked box, the checkbox overrides it
+			checked := ""
+			if value == "true" {
+				checked = " checked"
+			}
+			input = fmt.Sprintf(`<input type="hidden" name="%s" value="false"><input type="checkbox" id="%s" name="%s" value="true"%s>`,
+				name, name, name, checked)
+		case services.FieldSelect:
+			var options strings.Builder
+			options.WriteString(`<option value=""></option>`)
+			for _, option := range field.Options {
+				selected := ""
+				if option == value {
+					selected = " selected"
+				}
+				fmt.Fprintf(&options, `<option value="%s"%s>%s</option>`,
+					html.EscapeString(option), selected, html.EscapeString(capitalize(option)))
+			}
+			input = fmt.Sprintf(`<select id="%s" name="%s" class="%s">%s</select>`, name, name, inputClass, options.String())
+		default:
+			inputType, attrs := "text", ""
+			switch field.Type {
+			case services.FieldNumber:
+				inputType, attrs = "number", ` step="any"`
+			case services.FieldDate, services.FieldURL:
+				inputType = field.Type
+			}
+			input = fmt.Sprintf(`<input type="%s" id="%s" name="%s" value="%s" class="%s"%s>`,
+				inputType, name, name, html.EscapeString(value), inputClass, attrs)
+		}
+
+		fmt.Fprintf(&inputs, `
+				<div class="class-editor-field">
+					<label class="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-1" for="%s">%s</label>
+					%s
+				</div>`,
+			name, html.EscapeString(field.Label), input)
+	}
+
+	if inputs.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`
+			<div class="grid gap-4 md:grid-cols-2 mb-4 class-editor-fields">%s
+			</div>`, inputs.String())
+}
+
+// fieldValues returns the field values of a parsed editor form, keyed by field name.
+// The last value of a field wins, so a checked box overrides its hidden input.
+func fieldValues(r *http.Request) map[string]string {
+	values := make(map[string]string)
+	for key, submitted := range r.Form {
+		if name, ok := strings.CutPrefix(key, fieldPrefix); ok && len(submitted) > 0 {
+			values[name] = submitted[len(submitted)-1]
+		}
+	}
+	return values
+}
+
+// isWebURL reports whether a URL is safe to link to
+func isWebURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "mailto")
+}
+
+// capitalize returns a value with its first letter in upper case
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
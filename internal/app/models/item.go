@@ -13,6 +13,15 @@ const (
 	TypeFile       ItemType = "file"
 )
 
+// ItemTypes lists the built-in item types, repositories may declare more in their config
+var ItemTypes = []ItemType{
+	TypeNote,
+	TypeBookmark,
+	TypeTask,
+	TypeWorkstream,
+	TypeFile,
+}
+
 // TaskStatus represents the status of a task
 type TaskStatus string
 
@@ -35,7 +44,24 @@ type Item struct {
 	Status      TaskStatus `json:"status,omitempty"`   // for tasks
 	Items       []string   `json:"items,omitempty"`    // for workstreams
 	Filename    string     `json:"filename,omitempty"` // for files
+	Hash        string     `json:"hash,omitempty"`     // for files, SHA-256 of the stored file
+	Size        int64      `json:"size,omitempty"`     // for files, in bytes
 	Description string     `json:"description,omitempty"`
+
+	// Properties holds custom front matter keys of the content file
+	Properties map[string]interface{} `json:"properties,omitempty"`
+
+	// Archived items are kept but no longer need triage
+	Archived bool `json:"archived,omitempty"`
+
+	// Encrypted items store their content sealed with the repository passphrase
+	Encrypted bool `json:"encrypted,omitempty"`
+	// SealMetadata keeps the title and tags of an encrypted item in its sealed content
+	SealMetadata bool `json:"sealMetadata,omitempty"`
+
+	// SchemaVersion is the metadata format the item was written with, zero for files
+	// written before metadata was versioned
+	SchemaVersion int `json:"schemaVersion,omitempty"`
 }
 
 // NewItem creates a new item with the given type and ID
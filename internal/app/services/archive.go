@@ -0,0 +1,99 @@
+package services
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrArchiveTooLarge is returned for an archive that unpacks to more than its size limit
+var ErrArchiveTooLarge = errors.New("archive is too large once unpacked")
+
+// ExtractZip unpacks a zip archive into dir, keeping modification times. Entries must stay
+// inside dir and all of them together may unpack to at most limit bytes. Links and other
+// special files are skipped. Nothing more is written after an error, the caller removes dir.
+func ExtractZip(archive *zip.Reader, dir string, limit int64) error {
+	// The reader fails entries longer than they claim, so the declared sizes bound the data
+	var total uint64
+	for _, entry := range archive.File {
+		if !safeArchivePath(entry.Name) {
+			return fmt.Errorf("entry %q is outside the archive", entry.Name)
+		}
+		total += entry.UncompressedSize64
+		if total > uint64(limit) {
+			return fmt.Errorf("%w: more than %d bytes", ErrArchiveTooLarge, limit)
+		}
+	}
+
+	var dirs []*zip.File
+	remaining := limit
+	for _, entry := range archive.File {
+		target := filepath.Join(dir, filepath.FromSlash(entry.Name))
+		if entry.FileInfo().IsDir() {
+			dirs = append(dirs, entry)
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", entry.Name, err)
+			}
+			continue
+		}
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+
+		written, err := extractFile(entry, target, remaining)
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", entry.Name, err)
+		}
+		remaining -= written
+	}
+
+	// Directory times are set last, creating their files changed them
+	for _, entry := range dirs {
+		os.Chtimes(filepath.Join(dir, filepath.FromSlash(entry.Name)), entry.Modified, entry.Modified)
+	}
+	return nil
+}
+
+// safeArchivePath reports whether an entry name is a relative slash-separated path that
+// stays inside the directory it's unpacked into
+func safeArchivePath(entryName string) bool {
+	name := strings.TrimSuffix(entryName, "/")
+	return name != "" && !strings.Contains(entryName, `\`) && !path.IsAbs(name) && path.Clean(name) == name &&
+		name != ".." && !strings.HasPrefix(name, "../")
+}
+
+// extractFile writes one archive entry of at most limit bytes, keeping its modification time.
+// It returns the number of bytes written.
+func extractFile(entry *zip.File, target string, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, err
+	}
+
+	src, err := entry.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(dst, io.LimitReader(src, limit+1))
+	if err == nil && written > limit {
+		err = ErrArchiveTooLarge
+	}
+	if err != nil {
+		dst.Close()
+		return written, err
+	}
+	if err := dst.Close(); err != nil {
+		return written, err
+	}
+	return written, os.Chtimes(target, entry.Modified, entry.Modified)
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractZip(t *testing.T) {
+	dir := t.TempDir()
+
+	open := func(entries map[string]string) *zip.Reader {
+		path := filepath.Join(t.TempDir(), "archive.zip")
+		writeZip(t, path, entries)
+		archive, err := zip.OpenReader(path)
+		require.NoError(t, err)
+		t.Cleanup(func() { archive.Close() })
+		return &archive.Reader
+	}
+
+	target := filepath.Join(dir, "vault")
+	require.NoError(t, ExtractZip(open(map[string]string{"vault/": "", "vault/notes/a.md": "# A"}), dir, 1024))
+	data, err := os.ReadFile(filepath.Join(target, "notes", "a.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# A", string(data))
+
+	err = ExtractZip(open(map[string]string{"../escape.md": "x"}), t.TempDir(), 1024)
+	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "escape.md"))
+
+	// Archives that unpack to more than the limit are refused before anything is written
+	bomb := t.TempDir()
+	err = ExtractZip(open(map[string]string{"small.md": "x", "large.md": strings.Repeat("0", 4096)}), bomb, 1024)
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+	entries, err := os.ReadDir(bomb)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
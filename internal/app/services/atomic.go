@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// repoLocks serializes item saves per repository path.
+// Repository values aren't always shared through a Registry, so the locks can't live on them.
+var repoLocks sync.Map // map[string]*sync.Mutex
+
+// lock acquires the save lock of the repository and returns the function releasing it
+func (r *Repository) lock() func() {
+	value, _ := repoLocks.LoadOrStore(filepath.Clean(r.basePath), &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory, syncs it and
+// renames it over path, so readers see either the old or the new file and never a partial one
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicFrom(path, bytes.NewReader(data), perm)
+}
+
+// writeFileAtomicFrom is writeFileAtomic for data that doesn't fit in memory
+func writeFileAtomicFrom(path string, data io.Reader, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temporary file unless it was renamed into place
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	committed = true
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes a directory so a rename survives a crash.
+// Errors are ignored because some platforms can't sync directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// fileBatch groups the file writes of one operation so they can be undone together
+type fileBatch struct {
+	originals []fileOriginal
+	seen      map[string]bool
+}
+
+// fileOriginal is the state of a file before the batch first touched it
+type fileOriginal struct {
+	path    string
+	data    []byte
+	existed bool
+	perm    os.FileMode
+}
+
+// newFileBatch creates an empty batch
+func newFileBatch() *fileBatch {
+	return &fileBatch{
+		seen: make(map[string]bool),
+	}
+}
+
+// write atomically replaces a file, remembering its previous content
+func (b *fileBatch) write(path string, data []byte, perm os.FileMode) error {
+	if err := b.remember(path); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, perm)
+}
+
+// remove deletes a file, remembering its previous content
+func (b *fileBatch) remove(path string) error {
+	if err := b.remember(path); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// remember records the state of a file the first time the batch touches it
+func (b *fileBatch) remember(path string) error {
+	if b.seen[path] {
+		return nil
+	}
+
+	original := fileOriginal{path: path, perm: 0644}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		original.data = data
+		original.existed = true
+		if info, err := os.Stat(path); err == nil {
+			original.perm = info.Mode().Perm()
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to read %s before writing: %w", path, err)
+	}
+
+	b.seen[path] = true
+	b.originals = append(b.originals, original)
+	return nil
+}
+
+// rollback puts every file touched by the batch back as it was, newest change first
+func (b *fileBatch) rollback() error {
+	var errs []error
+	for i := len(b.originals) - 1; i >= 0; i-- {
+		original := b.originals[i]
+		if original.existed {
+			if err := writeFileAtomic(original.path, original.data, original.perm); err != nil {
+				errs = append(errs, err)
+			}
+		} else if err := os.Remove(original.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	b.originals = nil
+	b.seen = make(map[string]bool)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.json")
+
+	require.NoError(t, writeFileAtomic(path, []byte("first"), 0644))
+	require.NoError(t, writeFileAtomic(path, []byte("second"), 0644))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// A failed write leaves the existing file untouched
+	assert.Error(t, writeFileAtomic(filepath.Join(dir, "missing", "file.json"), []byte("x"), 0644))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+}
+
+func TestFileBatchRollback(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.json")
+	created := filepath.Join(dir, "created.json")
+	removed := filepath.Join(dir, "removed.json")
+	require.NoError(t, os.WriteFile(existing, []byte("original"), 0644))
+	require.NoError(t, os.WriteFile(removed, []byte("keep me"), 0644))
+
+	batch := newFileBatch()
+	require.NoError(t, batch.write(existing, []byte("changed"), 0644))
+	require.NoError(t, batch.write(existing, []byte("changed twice"), 0644))
+	require.NoError(t, batch.write(created, []byte("new"), 0644))
+	require.NoError(t, batch.remove(removed))
+
+	require.NoError(t, batch.rollback())
+
+	data, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(data))
+	assert.NoFileExists(t, created)
+	data, err = os.ReadFile(removed)
+	require.NoError(t, err)
+	assert.Equal(t, "keep me", string(data))
+}
+
+func TestSaveItemRollsBackOnTagFailure(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "Original #first"))
+	modified := item.Modified
+
+	// Make the tag index unwritable by putting a file where its directory should be
+	tagsDir := filepath.Join(tempDir, ".meta", "tags")
+	require.NoError(t, os.RemoveAll(tagsDir))
+	require.NoError(t, os.WriteFile(tagsDir, []byte("not a directory"), 0644))
+
+	err := repo.UpdateContent(item, "Changed #second")
+	require.Error(t, err)
+
+	// Content, metadata and the in-memory item still agree with the last good save
+	loaded, content, err := repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Original #first", content)
+	assert.Equal(t, []string{"first"}, loaded.Tags)
+	assert.Equal(t, []string{"first"}, item.Tags)
+	assert.True(t, modified.Equal(item.Modified))
+
+	// A new item is removed entirely
+	fresh := models.NewItem(models.TypeNote, "note2")
+	require.Error(t, repo.SaveItem(fresh, "Fresh #tag"))
+	_, _, err = repo.LoadItem("note2", models.TypeNote)
+	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(tempDir, "notes", "note2.md"))
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat is the timestamp in the names of scheduled backups
+const backupTimeFormat = "20060102-150405"
+
+// backupCheckInterval is how often the scheduler checks whether a backup is due
+const backupCheckInterval = 10 * time.Minute
+
+// tempFilePattern matches the temporary files of atomic writes, which never go in a backup
+var tempFilePattern = regexp.MustCompile(`^\..+\.[0-9]+\.tmp$`)
+
+// maxRestoreSize caps the data a restored backup may unpack to
+const maxRestoreSize = 64 << 30
+
+// ErrInvalidBackup is returned when restoring an archive that isn't a usable Vovere backup
+var ErrInvalidBackup = errors.New("invalid backup archive")
+
+// BackupService writes zip archives of a repository
+type BackupService struct {
+	repo *Repository
+}
+
+// NewBackupService creates a new backup service
+func NewBackupService(repo *Repository) *BackupService {
+	return &BackupService{
+		repo: repo,
+	}
+}
+
+// Write writes a zip archive of the repository to w. Saves wait until it's done,
+// so the archive never holds a half-written item.
+func (s *BackupService) Write(w io.Writer) error {
+	unlock := s.repo.lock()
+	defer unlock()
+
+	config, err := s.repo.Config()
+	if err != nil {
+		return err
+	}
+	skip, err := s.backupDir(config)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	archive.SetComment(fmt.Sprintf("Vovere backup of %s, schema version %d", config.Name, config.schemaVersion()))
+
+	base := s.repo.BasePath()
+	err = filepath.WalkDir(base, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == base {
+			return nil
+		}
+		// Scheduled backups kept inside the repository aren't backed up again
+		if entry.IsDir() && skip != "" {
+			if abs, err := filepath.Abs(p); err == nil && abs == skip {
+				return filepath.SkipDir
+			}
+		}
+		if !entry.IsDir() && (tempFilePattern.MatchString(entry.Name()) || !entry.Type().IsRegular()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return addToArchive(archive, p, filepath.ToSlash(rel), info)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to back up repository: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to back up repository: %w", err)
+	}
+	return nil
+}
+
+// WriteFile writes a backup to a file, which only appears once the archive is complete
+func (s *BackupService) WriteFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := s.Write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	return nil
+}
+
+// RunIfDue writes a backup to the configured directory when the newest one there is older
+// than the configured interval, then deletes the oldest backups beyond the number to keep.
+// It returns the path of the new backup, or "" when none was due or backups aren't configured.
+func (s *BackupService) RunIfDue(now time.Time) (string, error) {
+	config, err := s.repo.Config()
+	if err != nil {
+		return "", err
+	}
+	dir, err := s.backupDir(config)
+	if err != nil || dir == "" {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := backupName(config)
+	backups, err := s.scheduledBackups(dir, name)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) > 0 {
+		if last, ok := backupTime(backups[len(backups)-1], name); ok && now.Sub(last) < config.Backup.interval() {
+			return "", nil
+		}
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.zip", name, now.UTC().Format(backupTimeFormat)))
+	if err := s.WriteFile(path); err != nil {
+		return "", err
+	}
+	backups = append(backups, filepath.Base(path))
+
+	// Rotate: the oldest backups go first
+	if keep := config.Backup.keep(); keep > 0 && len(backups) > keep {
+		for _, name := range backups[:len(backups)-keep] {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+				return path, fmt.Errorf("failed to delete old backup: %w", err)
+			}
+		}
+	}
+	return path, nil
+}
+
+// backupDir returns the configured backup directory, relative paths being relative to the repository
+func (s *BackupService) backupDir(config *RepositoryConfig) (string, error) {
+	if config.Backup.Dir == "" {
+		return "", nil
+	}
+	dir := config.Backup.Dir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.repo.BasePath(), dir)
+	}
+	return filepath.Abs(dir)
+}
+
+// scheduledBackups returns the names of the scheduled backups of a repository in dir, oldest first
+func (s *BackupService) scheduledBackups(dir, name string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if _, ok := backupTime(entry.Name(), name); ok && !entry.IsDir() {
+			backups = append(backups, entry.Name())
+		}
+	}
+	// The timestamp format sorts by time
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// backupName returns the repository name as used in backup file names
+func backupName(config *RepositoryConfig) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '-'
+		}
+		return r
+	}, config.Name)
+}
+
+// backupTime returns the time in the name of a scheduled backup
+func backupTime(filename, name string) (time.Time, bool) {
+	stamp, ok := strings.CutPrefix(filename, name+"-")
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, ok = strings.CutSuffix(stamp, ".zip")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(backupTimeFormat, stamp)
+	return t, err == nil
+}
+
+// addToArchive adds a file or directory to a zip archive, keeping its modification time
+func addToArchive(archive *zip.Writer, p, name string, info fs.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+		_, err := archive.CreateHeader(header)
+		return err
+	}
+	header.Method = zip.Deflate
+
+	w, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// RestoreBackup unpacks a backup archive into dir, which must not exist or be empty.
+// The whole archive is checked first: entries must stay inside dir, their checksums must
+// match and the repository must be one this version can read. Nothing is written otherwise.
+func RestoreBackup(archivePath, dir string) error {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s is not empty", dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read restore directory: %w", err)
+	}
+
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer archive.Close()
+
+	if err := validateBackup(&archive.Reader); err != nil {
+		return err
+	}
+
+	// Unpack next to the target and move it into place, so a failure leaves nothing behind
+	parent := filepath.Dir(filepath.Clean(dir))
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(parent, "."+filepath.Base(dir)+".restore-*")
+	if err != nil {
+		return fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := ExtractZip(&archive.Reader, tmp, maxRestoreSize); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	// An empty target directory is replaced
+	if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	return nil
+}
+
+// validateBackup checks every entry of a backup archive without writing anything
+func validateBackup(archive *zip.Reader) error {
+	var hasMeta bool
+	for _, entry := range archive.File {
+		name := strings.TrimSuffix(entry.Name, "/")
+		if !safeArchivePath(entry.Name) {
+			return fmt.Errorf("%w: unsafe path %q", ErrInvalidBackup, entry.Name)
+		}
+		if mode := entry.Mode(); !mode.IsRegular() && !mode.IsDir() {
+			return fmt.Errorf("%w: %s is not a regular file", ErrInvalidBackup, entry.Name)
+		}
+		if name == ".meta" || strings.HasPrefix(name, ".meta/") {
+			hasMeta = true
+		}
+		if strings.HasSuffix(entry.Name, "/") {
+			continue
+		}
+
+		// Reading an entry to the end verifies its checksum
+		r, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBackup, entry.Name, err)
+		}
+		var data []byte
+		if name == "config.json" {
+			data, err = io.ReadAll(r)
+		} else {
+			_, err = io.Copy(io.Discard, r)
+		}
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBackup, entry.Name, err)
+		}
+
+		if name == "config.json" {
+			var config RepositoryConfig
+			if err := json.Unmarshal(data, &config); err != nil {
+				return fmt.Errorf("%w: config.json: %v", ErrInvalidBackup, err)
+			}
+			if version := config.schemaVersion(); version > SchemaVersion {
+				return fmt.Errorf("%w: schema version %d, this version supports %d", ErrSchemaTooNew, version, SchemaVersion)
+			}
+		}
+	}
+
+	if !hasMeta {
+		return fmt.Errorf("%w: no .meta directory, not a Vovere repository", ErrInvalidBackup)
+	}
+	return nil
+}
+
+// BackupScheduler writes scheduled backups of a repository in the background
+type BackupScheduler struct {
+	backups *BackupService
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBackupScheduler creates a scheduler for the backups configured in a repository's config.json
+func NewBackupScheduler(repo *Repository) *BackupScheduler {
+	return &BackupScheduler{
+		backups: NewBackupService(repo),
+	}
+}
+
+// Start checks for due backups in the background until Stop is called
+func (s *BackupScheduler) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(backupCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			if path, err := s.backups.RunIfDue(time.Now()); err != nil {
+				log.Printf("Error backing up repository %s: %v", s.backups.repo.BasePath(), err)
+			} else if path != "" {
+				log.Printf("Backed up repository %s to %s", s.backups.repo.BasePath(), path)
+			}
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler and waits for a running backup to finish
+func (s *BackupScheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
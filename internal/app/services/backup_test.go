@@ -0,0 +1,125 @@
+package services
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+// writeZip writes an archive with the given entries
+func writeZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	archive := zip.NewWriter(file)
+	for name, content := range entries {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	require.NoError(t, file.Close())
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	note := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(note, "Backed up #safe"))
+	// Leftovers of interrupted writes aren't backed up
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".meta", "notes", ".note1.json.123.tmp"), []byte("{"), 0644))
+
+	archive := filepath.Join(t.TempDir(), "backup.zip")
+	require.NoError(t, NewBackupService(repo).WriteFile(archive))
+
+	restored := filepath.Join(t.TempDir(), "restored")
+	require.NoError(t, RestoreBackup(archive, restored))
+	assert.NoFileExists(t, filepath.Join(restored, ".meta", "notes", ".note1.json.123.tmp"))
+	assert.DirExists(t, filepath.Join(restored, "tasks"))
+
+	item, content, err := NewRepository(restored).LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Backed up #safe", content)
+	assert.Equal(t, []string{"safe"}, item.Tags)
+
+	// Restores never write over existing files
+	assert.Error(t, RestoreBackup(archive, restored))
+}
+
+func TestRestoreValidatesArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	unsafe := filepath.Join(dir, "unsafe.zip")
+	writeZip(t, unsafe, map[string]string{".meta/notes/a.json": "{}", "../escape.md": "x"})
+	err := RestoreBackup(unsafe, filepath.Join(dir, "unsafe"))
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+	assert.NoDirExists(t, filepath.Join(dir, "unsafe"))
+	assert.NoFileExists(t, filepath.Join(dir, "escape.md"))
+
+	foreign := filepath.Join(dir, "foreign.zip")
+	writeZip(t, foreign, map[string]string{"notes.txt": "not a repository"})
+	assert.ErrorIs(t, RestoreBackup(foreign, filepath.Join(dir, "foreign")), ErrInvalidBackup)
+
+	newer := filepath.Join(dir, "newer.zip")
+	writeZip(t, newer, map[string]string{".meta/notes/a.json": "{}", "config.json": `{"schemaVersion": 99}`})
+	assert.ErrorIs(t, RestoreBackup(newer, filepath.Join(dir, "newer")), ErrSchemaTooNew)
+}
+
+func TestScheduledBackupsRotate(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	backupDir := t.TempDir()
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{
+		Name:   "test repo",
+		Backup: BackupConfig{Dir: backupDir, IntervalHours: 1, Keep: 2},
+	}))
+	require.NoError(t, repo.SaveItem(models.NewItem(models.TypeNote, "note1"), "Note"))
+
+	backups := NewBackupService(repo)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	first, err := backups.RunIfDue(start)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(backupDir, "test-repo-20240101-100000.zip"), first)
+
+	// Not due yet
+	path, err := backups.RunIfDue(start.Add(30 * time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, path)
+
+	_, err = backups.RunIfDue(start.Add(time.Hour))
+	require.NoError(t, err)
+	last, err := backups.RunIfDue(start.Add(2 * time.Hour))
+	require.NoError(t, err)
+
+	// Only the newest two are kept
+	entries, err := os.ReadDir(backupDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.NoFileExists(t, first)
+	assert.FileExists(t, last)
+
+	// Backups kept inside the repository aren't backed up again
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{Name: "test repo", Backup: BackupConfig{Dir: "backups"}}))
+	inside, err := backups.RunIfDue(start)
+	require.NoError(t, err)
+	inside2, err := backups.RunIfDue(start.Add(48 * time.Hour))
+	require.NoError(t, err)
+	archive, err := zip.OpenReader(inside2)
+	require.NoError(t, err)
+	defer archive.Close()
+	for _, entry := range archive.File {
+		assert.NotContains(t, entry.Name, "backups")
+	}
+	assert.Equal(t, filepath.Join(tempDir, "backups"), filepath.Dir(inside))
+}
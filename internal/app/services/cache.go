@@ -0,0 +1,294 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"vovere/internal/app/models"
+)
+
+// itemCache keeps the decoded metadata of every item in memory, loaded per type on first use.
+// Saves and deletes through the repository update it; changes made outside Vovere reach it
+// through the watcher, which refreshes the items it reconciles.
+type itemCache struct {
+	mu    sync.RWMutex
+	types map[models.ItemType]*typeCache
+}
+
+// typeCache holds the items of one type and their order for listing
+type typeCache struct {
+	items map[string]*models.Item
+	// sorted is the newest-first listing, rebuilt after a change
+	sorted []*models.Item
+}
+
+// newItemCache creates an empty item cache
+func newItemCache() *itemCache {
+	return &itemCache{
+		types: make(map[models.ItemType]*typeCache),
+	}
+}
+
+// list returns copies of the cached items of a type, newest first, loading them if needed
+func (c *itemCache) list(r *Repository, itemType models.ItemType) ([]*models.Item, error) {
+	c.mu.RLock()
+	cached, ok := c.types[itemType]
+	if ok && cached.sorted != nil {
+		items := cloneItems(cached.sorted)
+		c.mu.RUnlock()
+		return items, nil
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, err := c.load(r, itemType)
+	if err != nil {
+		return nil, err
+	}
+	if cached.sorted == nil {
+		cached.sorted = make([]*models.Item, 0, len(cached.items))
+		for _, item := range cached.items {
+			cached.sorted = append(cached.sorted, item)
+		}
+		sort.Slice(cached.sorted, func(i, j int) bool {
+			return cached.sorted[i].Modified.After(cached.sorted[j].Modified)
+		})
+	}
+	return cloneItems(cached.sorted), nil
+}
+
+// get returns a copy of a cached item, loading its type if needed
+func (c *itemCache) get(r *Repository, id string, itemType models.ItemType) (*models.Item, bool, error) {
+	c.mu.RLock()
+	cached, ok := c.types[itemType]
+	if ok {
+		item, found := cached.items[id]
+		c.mu.RUnlock()
+		if !found {
+			return nil, false, nil
+		}
+		return cloneItem(item), true, nil
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, err := c.load(r, itemType)
+	if err != nil {
+		return nil, false, err
+	}
+	item, found := cached.items[id]
+	if !found {
+		return nil, false, nil
+	}
+	return cloneItem(item), true, nil
+}
+
+// load reads the metadata of every item of a type unless it's cached, the caller holds the write lock
+func (c *itemCache) load(r *Repository, itemType models.ItemType) (*typeCache, error) {
+	if cached, ok := c.types[itemType]; ok {
+		return cached, nil
+	}
+
+	metaDir := filepath.Join(r.basePath, ".meta", string(itemType)+"s")
+	entries, err := os.ReadDir(metaDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read metadata directory: %w", err)
+	}
+
+	cached := &typeCache{items: make(map[string]*models.Item, len(entries))}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		id := strings.TrimSuffix(name, ".json")
+		item, err := r.loadMetadata(id, itemType)
+		if err != nil {
+			continue // Skip items that can't be loaded
+		}
+		cached.items[id] = item
+	}
+
+	c.types[itemType] = cached
+	return cached, nil
+}
+
+// put stores a copy of an item's saved metadata, if its type is cached
+func (c *itemCache) put(item *models.Item) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.types[item.Type]; ok {
+		cached.items[item.ID] = cloneItem(item)
+		cached.sorted = nil
+	}
+}
+
+// remove drops an item from the cache
+func (c *itemCache) remove(itemType models.ItemType, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.types[itemType]; ok {
+		delete(cached.items, id)
+		cached.sorted = nil
+	}
+}
+
+// reset drops every cached item, they are loaded again on next use
+func (c *itemCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.types = make(map[models.ItemType]*typeCache)
+}
+
+// refreshItem reloads an item's metadata from disk into the cache, or drops it when it's gone
+func (r *Repository) refreshItem(itemType models.ItemType, id string) {
+	item, err := r.loadMetadata(id, itemType)
+	if err != nil {
+		r.items.remove(itemType, id)
+		return
+	}
+	r.items.put(item)
+}
+
+// loadMetadata reads and decodes an item's metadata file
+func (r *Repository) loadMetadata(id string, itemType models.ItemType) (*models.Item, error) {
+	if err := r.ValidateItemRef(id, itemType); err != nil {
+		return nil, err
+	}
+	item := &models.Item{
+		ID:   id,
+		Type: itemType,
+	}
+
+	metaFile, err := os.Open(r.getMetaPath(item))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metadata file: %w", err)
+	}
+	defer metaFile.Close()
+
+	if err := json.NewDecoder(metaFile).Decode(item); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	return item, nil
+}
+
+// tagCache keeps the parsed tag index files of a repository. Entries remember the
+// file they were read from, so tag files changed by another process are read again.
+type tagCache struct {
+	mu      sync.RWMutex
+	entries map[string]tagCacheEntry
+}
+
+// tagCacheEntry is the item list of a tag and the state of its file when it was read
+type tagCacheEntry struct {
+	itemIDs []string
+	modTime time.Time
+	size    int64
+}
+
+// newTagCache creates an empty tag cache
+func newTagCache() *tagCache {
+	return &tagCache{
+		entries: make(map[string]tagCacheEntry),
+	}
+}
+
+// get returns a copy of a tag's item list if the cached entry matches the file
+func (c *tagCache) get(tag string, info os.FileInfo) ([]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[tag]
+	if !ok || !entry.modTime.Equal(info.ModTime()) || entry.size != info.Size() {
+		return nil, false
+	}
+	return append([]string{}, entry.itemIDs...), true
+}
+
+// set stores a copy of a tag's item list along with the state of its file
+func (c *tagCache) set(tag string, itemIDs []string, info os.FileInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[tag] = tagCacheEntry{
+		itemIDs: append([]string{}, itemIDs...),
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+}
+
+// remove drops a tag from the cache
+func (c *tagCache) remove(tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, tag)
+}
+
+// reset drops every cached tag
+func (c *tagCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]tagCacheEntry)
+}
+
+// cloneItems returns deep copies of items, so callers can change them without touching the cache
+func cloneItems(items []*models.Item) []*models.Item {
+	clones := make([]*models.Item, len(items))
+	for i, item := range items {
+		clones[i] = cloneItem(item)
+	}
+	return clones
+}
+
+// cloneItem returns a deep copy of an item
+func cloneItem(item *models.Item) *models.Item {
+	clone := *item
+	if item.Tags != nil {
+		clone.Tags = append([]string{}, item.Tags...)
+	}
+	if item.Items != nil {
+		clone.Items = append([]string{}, item.Items...)
+	}
+	if item.Properties != nil {
+		clone.Properties = cloneValue(item.Properties).(map[string]interface{})
+	}
+	return &clone
+}
+
+// cloneValue returns a deep copy of a property value, which may hold maps and lists from front matter
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		clone := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			clone[key] = cloneValue(nested)
+		}
+		return clone
+	case []interface{}:
+		clone := make([]interface{}, len(v))
+		for i, nested := range v {
+			clone[i] = cloneValue(nested)
+		}
+		return clone
+	case []string:
+		return append([]string{}, v...)
+	default:
+		return v
+	}
+}
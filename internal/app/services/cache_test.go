@@ -0,0 +1,159 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestListItemsUsesCache(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	first := models.NewItem(models.TypeNote, "first")
+	require.NoError(t, repo.SaveItem(first, "First note"))
+
+	items, err := repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	// Listing doesn't read the metadata again once it's cached
+	metaPath := filepath.Join(tempDir, ".meta", "notes", "first.json")
+	require.NoError(t, os.WriteFile(metaPath, []byte(`{"id": "first", "type": "note", "title": "Changed on disk"}`), 0644))
+	items, err = repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.NotEqual(t, "Changed on disk", items[0].Title)
+
+	// Saves and deletes update the cache
+	second := models.NewItem(models.TypeNote, "second")
+	require.NoError(t, repo.SaveItem(second, "Second note"))
+	items, err = repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "second", items[0].ID, "newest first")
+
+	require.NoError(t, repo.DeleteItem(second))
+	items, err = repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	// Changing a listed item doesn't change the cache
+	items[0].Tags = append(items[0].Tags, "mutated")
+	items, err = repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	assert.Empty(t, items[0].Tags)
+
+	// Refreshing reads the metadata from disk
+	repo.refreshItem(models.TypeNote, "first")
+	items, err = repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Changed on disk", items[0].Title)
+}
+
+func TestCachedItemsDontShareProperties(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	_, err := repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+
+	item := models.NewItem(models.TypeNote, "note1")
+	item.Properties = map[string]interface{}{
+		"status": "draft",
+		"people": []interface{}{"Ann"},
+		"venue":  map[string]interface{}{"city": "Oslo"},
+	}
+	require.NoError(t, repo.SaveItem(item, "Note"))
+
+	change := func(item *models.Item) {
+		item.Properties["status"] = "final"
+		item.Properties["people"].([]interface{})[0] = "Bob"
+		item.Properties["venue"].(map[string]interface{})["city"] = "Bergen"
+	}
+
+	// Neither the saved item nor a listed one share their properties with the cache
+	change(item)
+	items, err := repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	change(items[0])
+
+	items, err = repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "draft", items[0].Properties["status"])
+	assert.Equal(t, []interface{}{"Ann"}, items[0].Properties["people"])
+	assert.Equal(t, map[string]interface{}{"city": "Oslo"}, items[0].Properties["venue"])
+}
+
+func TestWatcherRefreshesCache(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	watcher := NewWatcherService(repo, 0)
+	_, err := watcher.Scan()
+	require.NoError(t, err)
+
+	items, err := repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "synced.md"), []byte("# Synced\n\nFrom elsewhere"), 0644))
+	_, err = watcher.Scan()
+	require.NoError(t, err)
+
+	items, err = repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Synced", items[0].Title)
+}
+
+func TestTagCacheFollowsTagFiles(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "Tagged #shared"))
+
+	// Tag services of the same repository share the cache
+	items, err := NewTagService(repo).GetItemsByTag("shared")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	other := models.NewItem(models.TypeNote, "note2")
+	require.NoError(t, repo.SaveItem(other, "Also #shared"))
+	items, err = NewTagService(repo).GetItemsByTag("shared")
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	// A tag file rewritten by another process is read again
+	writeJSON(t, filepath.Join(tempDir, ".meta", "tags", "shared.json"), []string{"note1:note"})
+	items, err = NewTagService(repo).GetItemsByTag("shared")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "note1", items[0].ID)
+}
+
+func TestRegistryReusesRepositories(t *testing.T) {
+	tempDir, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	registry := NewRegistry(0)
+	defer registry.Close()
+
+	repo, err := registry.Open(tempDir)
+	require.NoError(t, err)
+	again, err := registry.Open(tempDir + string(filepath.Separator))
+	require.NoError(t, err)
+	assert.Same(t, repo, again)
+
+	registry.Close()
+	again, err = registry.Open(tempDir)
+	require.NoError(t, err)
+	assert.NotSame(t, repo, again)
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vovere/internal/app/models"
+)
+
+// defaultMaxRevisions is the number of revisions kept per item when not configured
+const defaultMaxRevisions = 50
+
+// defaultBackupIntervalHours is the time between scheduled backups when not configured
+const defaultBackupIntervalHours = 24
+
+// defaultBackupKeep is the number of scheduled backups kept when not configured
+const defaultBackupKeep = 7
+
+// defaultUnlockMinutes is how long the key of encrypted items is kept when not configured
+const defaultUnlockMinutes = 15
+
+// defaultMaxUploadMB is the size limit of uploaded files when not configured
+const defaultMaxUploadMB = 100
+
+// defaultTrashRetentionDays is the number of days trashed items are kept when not configured
+const defaultTrashRetentionDays = 30
+
+// RepositoryConfig represents configuration for a repository
+type RepositoryConfig struct {
+	// SchemaVersion is the metadata format of the repository, see migrations.go
+	SchemaVersion int               `json:"schemaVersion,omitempty"`
+	Name          string            `json:"name"`
+	Description   string            `json:"description"`
+	Tags          []string          `json:"tags"`
+	History       HistoryConfig     `json:"history"`
+	Trash         TrashConfig       `json:"trash"`
+	FrontMatter   FrontMatterConfig `json:"frontMatter"`
+	Backup        BackupConfig      `json:"backup"`
+	Git           GitConfig         `json:"git"`
+	Files         FilesConfig       `json:"files"`
+	// Encryption is set once a passphrase is set up, see encryption.go
+	Encryption *EncryptionConfig `json:"encryption,omitempty"`
+	// Types declares item types besides the built-in ones, see types.go
+	Types []TypeConfig `json:"types,omitempty"`
+}
+
+// HistoryConfig controls how many content revisions are kept
+type HistoryConfig struct {
+	// MaxRevisions is the number of revisions kept per item.
+	// Zero uses the default and a negative value keeps every revision.
+	MaxRevisions int `json:"maxRevisions,omitempty"`
+	// MaxAgeDays drops revisions older than this many days, zero keeps them regardless of age.
+	// The latest revision of an item is always kept.
+	MaxAgeDays int `json:"maxAgeDays,omitempty"`
+}
+
+// TrashConfig controls how long deleted items stay in the trash
+type TrashConfig struct {
+	// RetentionDays is the number of days before trashed items are purged.
+	// Zero uses the default and a negative value keeps them until purged by hand.
+	RetentionDays int `json:"retentionDays,omitempty"`
+}
+
+// FrontMatterConfig controls the YAML front matter of content files.
+// Front matter is always read: it is stripped when rendering, its tags count like hashtags
+// and it fills metadata fields that are empty.
+type FrontMatterConfig struct {
+	// Write keeps the front matter of saved content in line with the item's title, tags,
+	// URL, status, description and properties
+	Write bool `json:"write,omitempty"`
+	// Precedence decides which source wins when metadata and front matter disagree,
+	// PrecedenceMetadata (the default) or PrecedenceContent
+	Precedence string `json:"precedence,omitempty"`
+}
+
+// BackupConfig controls scheduled backups, which the server writes while the repository is open
+type BackupConfig struct {
+	// Dir is where scheduled backups are written, relative to the repository unless absolute.
+	// Empty disables scheduled backups.
+	Dir string `json:"dir,omitempty"`
+	// IntervalHours is the time between backups, zero uses the default
+	IntervalHours int `json:"intervalHours,omitempty"`
+	// Keep is the number of backups kept, older ones are deleted.
+	// Zero uses the default and a negative value keeps every backup.
+	Keep int `json:"keep,omitempty"`
+}
+
+// GitConfig controls automatic git commits of the repository
+type GitConfig struct {
+	// Enabled commits saves with the local git binary, initializing the repository if needed
+	Enabled bool `json:"enabled,omitempty"`
+	// QuietSeconds is how long saves must stop before they are committed together, zero uses the default
+	QuietSeconds int `json:"quietSeconds,omitempty"`
+	// Push pushes every commit to Remote. Nothing is ever pushed without it.
+	Push bool `json:"push,omitempty"`
+	// Remote is the remote pushed to, "origin" when empty
+	Remote string `json:"remote,omitempty"`
+}
+
+// FilesConfig controls the files of file items
+type FilesConfig struct {
+	// MaxUploadMB is the size limit of uploaded files in megabytes, zero uses the default
+	MaxUploadMB int `json:"maxUploadMB,omitempty"`
+}
+
+// EncryptionConfig holds what is needed to derive the key of encrypted items from
+// the repository passphrase. The passphrase and the key are never stored.
+type EncryptionConfig struct {
+	// KDF names the key derivation, "argon2id"
+	KDF  string `json:"kdf"`
+	Salt string `json:"salt"`
+	// Iterations, MemoryKiB and Threads are the cost parameters of the key derivation
+	Iterations int `json:"iterations"`
+	MemoryKiB  int `json:"memoryKiB"`
+	Threads    int `json:"threads"`
+	// Check is a known value sealed with the key, used to reject a wrong passphrase
+	Check string `json:"check"`
+	// UnlockMinutes is how long the key is kept after unlocking, zero uses the default
+	UnlockMinutes int `json:"unlockMinutes,omitempty"`
+}
+
+// TypeConfig declares an item type. Its items are stored under {name}s/ and listed at /{name}s.
+type TypeConfig struct {
+	Name models.ItemType `json:"name"`
+	// Label is the display name of one item, the name capitalized when empty
+	Label string `json:"label,omitempty"`
+	// Plural is the display name of a list, the label followed by "s" when empty
+	Plural string `json:"plural,omitempty"`
+	// Icon is a short text, usually an emoji, shown next to the label
+	Icon string `json:"icon,omitempty"`
+	// Color is the Tailwind color of the type's badges, such as "blue" or "emerald"
+	Color string `json:"color,omitempty"`
+	// Fields are the typed metadata fields of the type's items
+	Fields []FieldConfig `json:"fields,omitempty"`
+	// Template is the content of new items, {{date}} and {{time}} are replaced when they are created
+	Template string `json:"template,omitempty"`
+	// Hidden leaves the type out of the sidebar, its items are still listed everywhere else
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// FieldConfig declares a metadata field of an item type. Values are kept in the item's
+// properties, except for the url, status, filename and description fields of the metadata.
+type FieldConfig struct {
+	Name string `json:"name"`
+	// Label is the display name of the field, the name capitalized when empty
+	Label string `json:"label,omitempty"`
+	// Type is one of FieldText (the default), FieldNumber, FieldDate, FieldURL, FieldBool or FieldSelect
+	Type string `json:"type,omitempty"`
+	// Options are the values a FieldSelect field can take
+	Options []string `json:"options,omitempty"`
+	// ReadOnly fields are shown but not edited
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// contentWins reports whether front matter fields override the metadata
+func (c FrontMatterConfig) contentWins() bool {
+	return c.Precedence == PrecedenceContent
+}
+
+// Config loads the repository configuration from config.json, using defaults for missing values
+func (r *Repository) Config() (*RepositoryConfig, error) {
+	config := &RepositoryConfig{
+		Name: filepath.Base(r.basePath),
+	}
+
+	data, err := os.ReadFile(r.configPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if config.Name == "" {
+		config.Name = filepath.Base(r.basePath)
+	}
+
+	return config, nil
+}
+
+// SaveConfig writes the repository configuration to config.json
+func (r *Repository) SaveConfig(config *RepositoryConfig) error {
+	defer r.types.reset()
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := writeFileAtomic(r.configPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// configPath returns the path of the repository configuration file
+func (r *Repository) configPath() string {
+	return filepath.Join(r.basePath, "config.json")
+}
+
+// revisionLimit returns the configured number of revisions to keep, or 0 for no limit
+func (c HistoryConfig) revisionLimit() int {
+	switch {
+	case c.MaxRevisions < 0:
+		return 0
+	case c.MaxRevisions == 0:
+		return defaultMaxRevisions
+	default:
+		return c.MaxRevisions
+	}
+}
+
+// retention returns how long trashed items are kept, or 0 to keep them forever
+func (c TrashConfig) retention() time.Duration {
+	switch {
+	case c.RetentionDays < 0:
+		return 0
+	case c.RetentionDays == 0:
+		return defaultTrashRetentionDays * 24 * time.Hour
+	default:
+		return time.Duration(c.RetentionDays) * 24 * time.Hour
+	}
+}
+
+// interval returns the time between scheduled backups
+func (c BackupConfig) interval() time.Duration {
+	if c.IntervalHours <= 0 {
+		return defaultBackupIntervalHours * time.Hour
+	}
+	return time.Duration(c.IntervalHours) * time.Hour
+}
+
+// keep returns the number of scheduled backups to keep, or 0 to keep them all
+func (c BackupConfig) keep() int {
+	switch {
+	case c.Keep < 0:
+		return 0
+	case c.Keep == 0:
+		return defaultBackupKeep
+	default:
+		return c.Keep
+	}
+}
+
+// quietPeriod returns how long saves must stop before they are committed
+func (c GitConfig) quietPeriod() time.Duration {
+	if c.QuietSeconds <= 0 {
+		return defaultGitQuietSeconds * time.Second
+	}
+	return time.Duration(c.QuietSeconds) * time.Second
+}
+
+// remote returns the remote commits are pushed to
+func (c GitConfig) remote() string {
+	if c.Remote == "" {
+		return defaultGitRemote
+	}
+	return c.Remote
+}
+
+// MaxUploadSize returns the size limit of uploaded files in bytes
+func (c FilesConfig) MaxUploadSize() int64 {
+	if c.MaxUploadMB <= 0 {
+		return defaultMaxUploadMB << 20
+	}
+	return int64(c.MaxUploadMB) << 20
+}
+
+// unlockPeriod returns how long an unlock lasts
+func (c EncryptionConfig) unlockPeriod() time.Duration {
+	if c.UnlockMinutes <= 0 {
+		return defaultUnlockMinutes * time.Minute
+	}
+	return time.Duration(c.UnlockMinutes) * time.Minute
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	assert.Empty(t, UnifiedDiff("a", "b", "same\n", "same\n"))
+
+	from := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	to := "one\ntwo\n3\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+
+	expected := `--- old
++++ new
+@@ -1,6 +1,6 @@
+ one
+ two
+-three
++3
+ four
+ five
+ six
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+`
+	assert.Equal(t, expected, UnifiedDiff("old", "new", from, to))
+
+	// Close changes share a hunk
+	diff := UnifiedDiff("old", "new", "a\nb\nc\nd\n", "A\nb\nc\nD\n")
+	assert.Equal(t, 1, strings.Count(diff, "@@ "))
+	assert.Contains(t, diff, "@@ -1,4 +1,4 @@")
+
+	// Additions to empty content
+	assert.Equal(t, "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+first\n+second\n", UnifiedDiff("old", "new", "", "first\nsecond"))
+}
+
+func TestDiffLinesLimit(t *testing.T) {
+	var a, b []string
+	for i := 0; i < maxDiffEdits; i++ {
+		a = append(a, "old "+strconv.Itoa(i))
+		b = append(b, "new "+strconv.Itoa(i))
+	}
+	a = append(a, "shared")
+	b = append(b, "shared")
+
+	// Revisions too far apart remove every old line and add every new one
+	ops := diffLines(a, b)
+	require.Len(t, ops, len(a)+len(b))
+	assert.Equal(t, diffOp{'-', "old 0"}, ops[0])
+	assert.Equal(t, diffOp{'-', "shared"}, ops[len(a)-1])
+	assert.Equal(t, diffOp{'+', "shared"}, ops[len(ops)-1])
+
+	// Within the limit the shared line is kept
+	ops = diffLines(a[maxDiffEdits/2:], b[maxDiffEdits/2:])
+	assert.Equal(t, diffOp{' ', "shared"}, ops[len(ops)-1])
+}
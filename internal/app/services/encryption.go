@@ -0,0 +1,420 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"vovere/internal/app/models"
+)
+
+const (
+	// kdfName identifies the key derivation in config.json
+	kdfName = "argon2id"
+	// kdfIterations, kdfMemoryKiB and kdfThreads are the Argon2id parameters recommended by RFC 9106
+	// for memory-constrained environments
+	kdfIterations = 3
+	kdfMemoryKiB  = 64 * 1024
+	kdfThreads    = 4
+	// sealedHeader and sealedFooter wrap the encrypted content of an item
+	sealedHeader = "-----BEGIN VOVERE ENCRYPTED ITEM-----\n"
+	sealedFooter = "-----END VOVERE ENCRYPTED ITEM-----\n"
+
+	// lockedTitle replaces the title of items whose title is encrypted
+	lockedTitle = "Encrypted item"
+
+	// passphraseCheck is sealed with the key so a wrong passphrase is detected
+	passphraseCheck = "vovere passphrase check"
+)
+
+var (
+	// ErrLocked is returned when encrypted content is needed and the repository isn't unlocked
+	ErrLocked = errors.New("repository is locked")
+	// ErrWrongPassphrase is returned when unlocking with a passphrase that doesn't match
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrEncryptionNotSetUp is returned when unlocking a repository without a passphrase
+	ErrEncryptionNotSetUp = errors.New("encryption is not set up")
+	// ErrEncryptionSetUp is returned when setting up a passphrase twice
+	ErrEncryptionSetUp = errors.New("encryption is already set up")
+)
+
+// EncryptionStatus reports whether a repository has a passphrase and is unlocked
+type EncryptionStatus struct {
+	Configured bool       `json:"configured"`
+	Unlocked   bool       `json:"unlocked"`
+	Expires    *time.Time `json:"expires,omitempty"`
+}
+
+// sealedPayload is what an encrypted content file holds
+type sealedPayload struct {
+	Content string   `json:"content"`
+	Title   string   `json:"title,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// keySession holds the key of an unlocked repository until it expires
+type keySession struct {
+	mu      sync.Mutex
+	key     []byte
+	expires time.Time
+}
+
+// get returns the key unless the session expired
+func (k *keySession) get() ([]byte, time.Time, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.key == nil || time.Now().After(k.expires) {
+		k.key = nil
+		return nil, time.Time{}, false
+	}
+	return k.key, k.expires, true
+}
+
+// set stores a key for a while
+func (k *keySession) set(key []byte, ttl time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.key = key
+	k.expires = time.Now().Add(ttl)
+}
+
+// clear forgets the key
+func (k *keySession) clear() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.key = nil
+}
+
+// EncryptionService seals item content with a key derived from the repository passphrase.
+// The passphrase is never stored; unlocking keeps the derived key in memory for a while.
+type EncryptionService struct {
+	repo *Repository
+}
+
+// NewEncryptionService creates a new encryption service
+func NewEncryptionService(repo *Repository) *EncryptionService {
+	return &EncryptionService{
+		repo: repo,
+	}
+}
+
+// Setup sets the repository passphrase and unlocks the repository
+func (s *EncryptionService) Setup(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase is required")
+	}
+
+	config, err := s.repo.Config()
+	if err != nil {
+		return err
+	}
+	if config.Encryption != nil {
+		return ErrEncryptionSetUp
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	encryption := &EncryptionConfig{
+		KDF:        kdfName,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Iterations: kdfIterations,
+		MemoryKiB:  kdfMemoryKiB,
+		Threads:    kdfThreads,
+	}
+	key, err := encryption.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	check, err := seal(key, []byte(passphraseCheck), []byte(passphraseCheck))
+	if err != nil {
+		return err
+	}
+	encryption.Check = base64.StdEncoding.EncodeToString(check)
+
+	config.Encryption = encryption
+	if err := s.repo.SaveConfig(config); err != nil {
+		return err
+	}
+	s.repo.keys.set(key, encryption.unlockPeriod())
+	return nil
+}
+
+// Unlock derives the key from the passphrase and keeps it until the unlock period ends
+func (s *EncryptionService) Unlock(passphrase string) error {
+	config, err := s.repo.Config()
+	if err != nil {
+		return err
+	}
+	if config.Encryption == nil {
+		return ErrEncryptionNotSetUp
+	}
+
+	key, err := config.Encryption.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	check, err := base64.StdEncoding.DecodeString(config.Encryption.Check)
+	if err != nil {
+		return fmt.Errorf("invalid passphrase check in config: %w", err)
+	}
+	if _, err := open(key, []byte(passphraseCheck), check); err != nil {
+		return ErrWrongPassphrase
+	}
+
+	s.repo.keys.set(key, config.Encryption.unlockPeriod())
+	return nil
+}
+
+// Lock forgets the key, encrypted items can't be read until the next unlock
+func (s *EncryptionService) Lock() {
+	s.repo.keys.clear()
+}
+
+// Status reports whether the repository has a passphrase and until when it is unlocked
+func (s *EncryptionService) Status() (*EncryptionStatus, error) {
+	config, err := s.repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &EncryptionStatus{Configured: config.Encryption != nil}
+	if _, expires, ok := s.repo.keys.get(); ok {
+		status.Unlocked = true
+		status.Expires = &expires
+	}
+	return status, nil
+}
+
+// EncryptItem encrypts an item's content, and with sealMetadata its title and tags as well.
+// The item leaves the tag, link and search indexes and its plain text revisions are deleted.
+func (s *EncryptionService) EncryptItem(item *models.Item, sealMetadata bool) error {
+	if _, _, ok := s.repo.keys.get(); !ok {
+		return ErrLocked
+	}
+
+	unlock := s.repo.lock()
+	defer unlock()
+
+	loaded, content, err := s.repo.LoadItem(item.ID, item.Type)
+	if err != nil {
+		return err
+	}
+	if loaded.Encrypted && IsSealed(content) {
+		return ErrLocked
+	}
+
+	previousTags := loaded.Tags
+	loaded.Encrypted = true
+	loaded.SealMetadata = sealMetadata
+	if err := s.repo.saveItem(loaded, content, true, previousTags); err != nil {
+		return err
+	}
+	if err := NewHistoryService(s.repo).RemoveItem(loaded); err != nil {
+		return err
+	}
+
+	*item = *loaded
+	return nil
+}
+
+// DecryptItem stores an encrypted item in plain text again and puts it back in the indexes
+func (s *EncryptionService) DecryptItem(item *models.Item) error {
+	unlock := s.repo.lock()
+	defer unlock()
+
+	loaded, content, err := s.repo.LoadItem(item.ID, item.Type)
+	if err != nil {
+		return err
+	}
+	if !loaded.Encrypted {
+		*item = *loaded
+		return nil
+	}
+	if IsSealed(content) {
+		return ErrLocked
+	}
+
+	loaded.Encrypted = false
+	loaded.SealMetadata = false
+	if err := s.repo.saveItem(loaded, content, true, nil); err != nil {
+		return err
+	}
+
+	*item = *loaded
+	return nil
+}
+
+// IsSealed reports whether content is encrypted content that wasn't opened
+func IsSealed(content string) bool {
+	return strings.HasPrefix(content, sealedHeader)
+}
+
+// sealContent encrypts an item's content, and its title and tags when they are sealed too
+func (r *Repository) sealContent(item *models.Item, content string) (string, error) {
+	key, _, ok := r.keys.get()
+	if !ok {
+		return "", ErrLocked
+	}
+
+	payload := sealedPayload{Content: content}
+	if item.SealMetadata {
+		payload.Title = item.Title
+		payload.Tags = item.Tags
+	}
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode encrypted content: %w", err)
+	}
+	sealed, err := seal(key, []byte(item.ID), plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	// Armored like PEM so the file is recognizable and diffs stay line based
+	encoded := base64.StdEncoding.EncodeToString(sealed)
+	var out strings.Builder
+	out.WriteString(sealedHeader)
+	for len(encoded) > 64 {
+		out.WriteString(encoded[:64] + "\n")
+		encoded = encoded[64:]
+	}
+	out.WriteString(encoded + "\n")
+	out.WriteString(sealedFooter)
+	return out.String(), nil
+}
+
+// openContent decrypts the content of an encrypted item, filling in a sealed title and tags.
+// It reports false when the repository is locked or the content can't be decrypted.
+func (r *Repository) openContent(item *models.Item, content string) (string, bool) {
+	key, _, ok := r.keys.get()
+	if !ok || !IsSealed(content) {
+		return "", false
+	}
+
+	body := strings.TrimSuffix(strings.TrimPrefix(content, sealedHeader), sealedFooter)
+	sealed, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\n", ""))
+	if err != nil {
+		return "", false
+	}
+	plaintext, err := open(key, []byte(item.ID), sealed)
+	if err != nil {
+		return "", false
+	}
+	var payload sealedPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return "", false
+	}
+
+	if item.SealMetadata {
+		item.Title = payload.Title
+		item.Tags = payload.Tags
+		if item.Tags == nil {
+			item.Tags = []string{}
+		}
+	}
+	return payload.Content, true
+}
+
+// storedMetadata returns the metadata written to disk: items with sealed metadata
+// get a placeholder title and no tags
+func storedMetadata(item *models.Item) *models.Item {
+	if !item.Encrypted || !item.SealMetadata {
+		return item
+	}
+	stored := cloneItem(item)
+	stored.Title = lockedTitle
+	stored.Tags = []string{}
+	return stored
+}
+
+// unlockedItems returns the encrypted items the current key opens, with their content.
+// Nothing is returned while the repository is locked.
+func (r *Repository) unlockedItems() ([]*models.Item, []string, error) {
+	if _, _, ok := r.keys.get(); !ok {
+		return nil, nil, nil
+	}
+
+	var items []*models.Item
+	var contents []string
+	for _, itemType := range r.ItemTypes() {
+		listed, err := r.ListItems(itemType)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, listedItem := range listed {
+			if !listedItem.Encrypted {
+				continue
+			}
+			item, content, err := r.LoadItem(listedItem.ID, itemType)
+			if err != nil || IsSealed(content) {
+				continue
+			}
+			items = append(items, item)
+			contents = append(contents, content)
+		}
+	}
+	return items, contents, nil
+}
+
+// deriveKey derives the AES-256 key from a passphrase
+func (c EncryptionConfig) deriveKey(passphrase string) ([]byte, error) {
+	if c.KDF != kdfName {
+		return nil, fmt.Errorf("unsupported key derivation %q", c.KDF)
+	}
+	salt, err := base64.StdEncoding.DecodeString(c.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt in config: %w", err)
+	}
+	if c.Iterations <= 0 || c.MemoryKiB <= 0 || c.Threads <= 0 || c.Threads > 255 {
+		return nil, fmt.Errorf("invalid key derivation parameters in config")
+	}
+	return argon2.IDKey([]byte(passphrase), salt, uint32(c.Iterations), uint32(c.MemoryKiB), uint8(c.Threads), 32), nil
+}
+
+// seal encrypts with AES-256-GCM, the random nonce goes first
+func seal(key, additionalData, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts and authenticates what seal produced
+func open(key, additionalData, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+}
+
+// newAEAD creates the AES-GCM cipher for a key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestEncryptedItems(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	encryption := NewEncryptionService(repo)
+	search := NewSearchService(repo)
+	tags := NewTagService(repo)
+
+	note := models.NewItem(models.TypeNote, "note1")
+	note.Title = "Launch plan"
+	require.NoError(t, repo.SaveItem(note, "Codename bluebird #project"))
+
+	// Nothing can be encrypted before a passphrase is set
+	assert.ErrorIs(t, encryption.EncryptItem(note, true), ErrLocked)
+	require.NoError(t, encryption.Setup("correct horse"))
+	assert.ErrorIs(t, encryption.Setup("again"), ErrEncryptionSetUp)
+	config, err := repo.Config()
+	require.NoError(t, err)
+	assert.Equal(t, "argon2id", config.Encryption.KDF)
+	assert.Equal(t, kdfMemoryKiB, config.Encryption.MemoryKiB)
+	require.NoError(t, encryption.EncryptItem(note, true))
+
+	// Content, title and tags never reach the disk in plain text
+	stored, err := os.ReadFile(repo.getContentPath(note))
+	require.NoError(t, err)
+	assert.True(t, IsSealed(string(stored)))
+	assert.NotContains(t, string(stored), "bluebird")
+	metadata, err := os.ReadFile(repo.getMetaPath(note))
+	require.NoError(t, err)
+	assert.NotContains(t, string(metadata), "Launch plan")
+	assert.NotContains(t, string(metadata), "project")
+	revisions, err := NewHistoryService(repo).Revisions(note)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	// Encrypted items still list, under a placeholder title
+	listed, err := repo.ListItems(models.TypeNote)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, lockedTitle, listed[0].Title)
+
+	// While unlocked they load, search and match tags
+	item, content, err := repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Codename bluebird #project", content)
+	assert.Equal(t, "Launch plan", item.Title)
+	assert.Equal(t, []string{"project"}, item.Tags)
+	results, err := search.Search("bluebird", 0)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+	tagged, err := tags.GetItemsByTag("project")
+	require.NoError(t, err)
+	assert.Len(t, tagged, 1)
+
+	// Locked, they load sealed and stay out of search and tags
+	encryption.Lock()
+	item, content, err = repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.True(t, IsSealed(content))
+	assert.Equal(t, lockedTitle, item.Title)
+	results, err = search.Search("bluebird", 0)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	tagged, err = tags.GetItemsByTag("project")
+	require.NoError(t, err)
+	assert.Empty(t, tagged)
+	assert.ErrorIs(t, repo.UpdateContent(item, "Overwritten"), ErrLocked)
+	// Triage can't append to the sealed content or reach the sealed title and tags
+	assert.False(t, IsUnprocessed(item))
+	assert.ErrorIs(t, NewInboxService(repo).AddTags(item, []string{"later"}), ErrLocked)
+
+	// The tag index and the rebuilt indexes agree with each other
+	require.NoError(t, search.Rebuild())
+	require.NoError(t, tags.Rebuild())
+	report, err := NewFsckService(repo).Check()
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues)
+
+	// A wrong passphrase doesn't unlock
+	assert.ErrorIs(t, encryption.Unlock("wrong"), ErrWrongPassphrase)
+	status, err := encryption.Status()
+	require.NoError(t, err)
+	assert.True(t, status.Configured)
+	assert.False(t, status.Unlocked)
+
+	// Edits while unlocked are sealed again
+	require.NoError(t, encryption.Unlock("correct horse"))
+	item, content, err = repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Codename bluebird #project", content)
+	require.NoError(t, repo.UpdateContent(item, "Codename kestrel #project #q3"))
+	stored, err = os.ReadFile(repo.getContentPath(note))
+	require.NoError(t, err)
+	assert.NotContains(t, string(stored), "kestrel")
+
+	// Decrypting puts the item back in the indexes
+	require.NoError(t, encryption.DecryptItem(item))
+	assert.False(t, item.Encrypted)
+	stored, err = os.ReadFile(repo.getContentPath(note))
+	require.NoError(t, err)
+	assert.Equal(t, "Codename kestrel #project #q3", string(stored))
+	tagged, err = tags.GetItemsByTag("q3")
+	require.NoError(t, err)
+	require.Len(t, tagged, 1)
+	assert.Equal(t, "Launch plan", tagged[0].Title)
+}
@@ -0,0 +1,421 @@
+package services
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"vovere/internal/app/models"
+	"vovere/internal/markdown"
+)
+
+// SiteOptions controls what a site export includes
+type SiteOptions struct {
+	// Name is shown on every page, empty uses the repository name
+	Name string
+	// Tags limits the export to items with at least one of these tags, empty exports everything
+	Tags []string
+}
+
+// SiteReport is the result of exporting a repository as a site
+type SiteReport struct {
+	Items int `json:"items"`
+	Tags  int `json:"tags"`
+	Files int `json:"files"`
+	// Encrypted counts the encrypted items, which are never published
+	Encrypted int `json:"encrypted"`
+	// Skipped lists items that couldn't be read
+	Skipped []string `json:"skipped"`
+}
+
+// siteItem is an exported item and its content
+type siteItem struct {
+	item    *models.Item
+	content string
+}
+
+// sitePage is the data of an exported page
+type sitePage struct {
+	Site  string
+	Title string
+	// Root is the relative path from the page to the site root
+	Root    string
+	Item    *siteItemView
+	Content template.HTML
+	Links   []siteLink
+	Groups  []siteGroup
+}
+
+// siteItemView is an item as shown on its page
+type siteItemView struct {
+	*models.Item
+	Tags  []siteLink
+	Items []siteLink
+	// File is the link to the stored file of file items
+	File string
+}
+
+// siteLink is a link on an exported page
+type siteLink struct {
+	URL   string
+	Title string
+	Info  string
+}
+
+// siteGroup is a titled list of links
+type siteGroup struct {
+	Title string
+	URL   string
+	Links []siteLink
+}
+
+// ExportService exports a repository as a static HTML site
+type ExportService struct {
+	repo *Repository
+}
+
+// NewExportService creates a new export service
+func NewExportService(repo *Repository) *ExportService {
+	return &ExportService{
+		repo: repo,
+	}
+}
+
+// ExportSite writes a browsable site to dir: a page per item, index pages per type and
+// per tag, and the stored files of file items. Every link is relative, so the site works
+// from any location and straight from disk. Links to items left out by the tag filter are
+// shown as unresolved. The directory must not exist or be empty.
+func (s *ExportService) ExportSite(dir string, options SiteOptions) (*SiteReport, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read export directory: %w", err)
+	}
+
+	if options.Name == "" {
+		config, err := s.repo.Config()
+		if err != nil {
+			return nil, err
+		}
+		options.Name = config.Name
+	}
+
+	report := &SiteReport{Skipped: []string{}}
+	items, err := s.collect(options, report)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := make(map[string]*models.Item, len(items))
+	tags := make(map[string][]*models.Item)
+	for _, entry := range items {
+		exported[ItemRef(entry.item)] = entry.item
+		for _, tag := range entry.item.Tags {
+			tags[tag] = append(tags[tag], entry.item)
+		}
+	}
+	resolver := &siteResolver{items: NewItemResolver(s.repo), exported: exported}
+
+	w := &siteWriter{dir: dir, name: options.Name}
+	files := NewFileService(s.repo)
+	for _, entry := range items {
+		item := entry.item
+		page := itemPagePath(item)
+		resolver.from = page
+
+		view := &siteItemView{Item: item}
+		for _, tag := range item.Tags {
+			view.Tags = append(view.Tags, siteLink{URL: relativeURL(page, tagPagePath(tag)), Title: tag})
+		}
+		for _, ref := range item.Items {
+			if linked, ok := exported[ref]; ok {
+				view.Items = append(view.Items, itemLink(page, linked))
+			}
+		}
+		if item.Type == models.TypeFile && item.Filename != "" {
+			target := path.Join("files", item.ID, item.Filename)
+			if err := copyFile(files.Path(item), filepath.Join(dir, filepath.FromSlash(target))); err != nil {
+				return nil, fmt.Errorf("failed to copy %s: %w", ItemRef(item), err)
+			}
+			view.File = relativeURL(page, target)
+			report.Files++
+		}
+
+		content := markdown.RenderWithLinks(entry.content, resolver, func(tag string) string {
+			return relativeURL(page, tagPagePath(tag))
+		})
+		if err := w.write(page, sitePage{Title: itemTitle(item), Item: view, Content: template.HTML(content)}); err != nil {
+			return nil, err
+		}
+		report.Items++
+	}
+
+	// Index pages per type
+	var home sitePage
+	for _, def := range s.repo.Types() {
+		itemType := def.Name
+		page := string(itemType) + "s/index.html"
+		var links, recent []siteLink
+		for _, entry := range items {
+			if entry.item.Type != itemType {
+				continue
+			}
+			links = append(links, itemLink(page, entry.item))
+			// The home page shows the latest items of each type
+			if len(recent) < 10 {
+				recent = append(recent, itemLink("index.html", entry.item))
+			}
+		}
+		if len(links) == 0 {
+			continue
+		}
+		if err := w.write(page, sitePage{Title: def.Plural, Links: links}); err != nil {
+			return nil, err
+		}
+		home.Groups = append(home.Groups, siteGroup{Title: def.Plural, URL: page, Links: recent})
+	}
+
+	// Index pages per tag
+	tagNames := make([]string, 0, len(tags))
+	for tag := range tags {
+		tagNames = append(tagNames, tag)
+	}
+	sort.Strings(tagNames)
+	var tagLinks []siteLink
+	for _, tag := range tagNames {
+		page := tagPagePath(tag)
+		var links []siteLink
+		for _, item := range tags[tag] {
+			links = append(links, itemLink(page, item))
+		}
+		if err := w.write(page, sitePage{Title: "#" + tag, Links: links}); err != nil {
+			return nil, err
+		}
+		tagLinks = append(tagLinks, siteLink{URL: relativeURL("tags/index.html", page), Title: "#" + tag, Info: plural(len(links), "item")})
+		report.Tags++
+	}
+	if err := w.write("tags/index.html", sitePage{Title: "Tags", Links: tagLinks}); err != nil {
+		return nil, err
+	}
+
+	home.Title = options.Name
+	if err := w.write("index.html", home); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// collect loads the items to export, newest first
+func (s *ExportService) collect(options SiteOptions, report *SiteReport) ([]siteItem, error) {
+	var items []siteItem
+	for _, itemType := range s.repo.ItemTypes() {
+		listed, err := s.repo.ListItems(itemType)
+		if err != nil {
+			return nil, err
+		}
+		for _, listedItem := range listed {
+			if len(options.Tags) > 0 && !hasAnyTag(listedItem, options.Tags) {
+				continue
+			}
+			// Encrypted items are never published
+			if listedItem.Encrypted {
+				report.Encrypted++
+				continue
+			}
+			item, content, err := s.repo.LoadItem(listedItem.ID, itemType)
+			if err != nil {
+				report.Skipped = append(report.Skipped, ItemRef(listedItem))
+				continue
+			}
+			items = append(items, siteItem{item: item, content: content})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].item.Created.After(items[j].item.Created)
+	})
+	return items, nil
+}
+
+// siteResolver resolves wiki links to the pages of exported items, relative to the page being written
+type siteResolver struct {
+	items    *ItemResolver
+	exported map[string]*models.Item
+	from     string
+}
+
+// ResolveLink returns the relative URL and title of an exported item
+func (r *siteResolver) ResolveLink(target string) (string, string, bool) {
+	item, ok := r.items.Resolve(target)
+	if !ok {
+		return "", "", false
+	}
+	if _, ok := r.exported[ItemRef(item)]; !ok {
+		return "", "", false
+	}
+	return relativeURL(r.from, itemPagePath(item)), item.Title, true
+}
+
+// siteWriter renders pages into the export directory
+type siteWriter struct {
+	dir  string
+	name string
+}
+
+// write renders a page at a slash-separated path relative to the site root
+func (w *siteWriter) write(page string, data sitePage) error {
+	data.Site = w.name
+	data.Root = relativeURL(page, "")
+	if data.Root == "" {
+		data.Root = "./"
+	}
+
+	target := filepath.Join(w.dir, filepath.FromSlash(page))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	var out strings.Builder
+	if err := siteTemplate.Execute(&out, data); err != nil {
+		return fmt.Errorf("failed to render %s: %w", page, err)
+	}
+	if err := os.WriteFile(target, []byte(out.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", page, err)
+	}
+	return nil
+}
+
+// itemPagePath returns the site path of an item's page
+func itemPagePath(item *models.Item) string {
+	return path.Join(string(item.Type)+"s", item.ID+".html")
+}
+
+// tagPagePath returns the site path of a tag's page
+func tagPagePath(tag string) string {
+	return path.Join("tags", tagFileName(tag)+".html")
+}
+
+// relativeURL returns a link from one site page to another path of the site
+func relativeURL(from, target string) string {
+	segments := strings.Split(target, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Repeat("../", strings.Count(from, "/")) + strings.Join(segments, "/")
+}
+
+// itemLink returns a link from a page to an item
+func itemLink(from string, item *models.Item) siteLink {
+	return siteLink{
+		URL:   relativeURL(from, itemPagePath(item)),
+		Title: itemTitle(item),
+		Info:  item.Created.Format("2006-01-02"),
+	}
+}
+
+// itemTitle returns the title of an item, or its ID when it has none
+func itemTitle(item *models.Item) string {
+	if item.Title != "" {
+		return item.Title
+	}
+	return item.ID
+}
+
+// plural returns a count followed by a noun, pluralized when needed
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// copyFile copies a file, creating the target directory
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// siteTemplate is the layout of every exported page
+var siteTemplate = template.Must(template.New("site").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}{{if ne .Title .Site}} - {{.Site}}{{end}}</title>
+<style>
+body { font-family: system-ui, sans-serif; line-height: 1.5; max-width: 48rem; margin: 0 auto; padding: 1rem; color: #222; }
+nav { border-bottom: 1px solid #ddd; padding-bottom: .5rem; margin-bottom: 1rem; }
+nav a { margin-right: 1rem; }
+.meta { color: #666; font-size: .9rem; }
+.tag-link, .tags a { color: #2a7; }
+.wiki-link-unresolved { color: #999; border-bottom: 1px dashed #999; }
+pre { background: #f5f5f5; padding: .5rem; overflow-x: auto; }
+ul.links { list-style: none; padding: 0; }
+ul.links li { margin: .25rem 0; }
+ul.links .meta { margin-left: .5rem; }
+</style>
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">{{.Site}}</a><a href="{{.Root}}tags/index.html">Tags</a></nav>
+<main>
+<h1>{{.Title}}</h1>
+{{- with .Item}}
+<p class="meta">{{.Type}} &middot; created {{.Created.Format "2006-01-02 15:04"}} &middot; modified {{.Modified.Format "2006-01-02 15:04"}}{{if .Status}} &middot; {{.Status}}{{end}}</p>
+{{- if .Tags}}
+<p class="tags">{{range .Tags}}<a href="{{.URL}}">#{{.Title}}</a> {{end}}</p>
+{{- end}}
+{{- if .URL}}
+<p><a href="{{.URL}}">{{.URL}}</a></p>
+{{- end}}
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+{{- if .File}}
+<p><a href="{{.File}}">{{.Filename}}</a></p>
+{{- end}}
+{{- end}}
+{{.Content}}
+{{- with .Item}}{{if .Items}}
+<ul class="links">{{range .Items}}
+<li><a href="{{.URL}}">{{.Title}}</a></li>{{end}}
+</ul>
+{{- end}}{{end}}
+{{- if .Links}}
+<ul class="links">{{range .Links}}
+<li><a href="{{.URL}}">{{.Title}}</a>{{if .Info}}<span class="meta">{{.Info}}</span>{{end}}</li>{{end}}
+</ul>
+{{- end}}
+{{- range .Groups}}
+<h2><a href="{{.URL}}">{{.Title}}</a></h2>
+{{- if .Links}}
+<ul class="links">{{range .Links}}
+<li><a href="{{.URL}}">{{.Title}}</a>{{if .Info}}<span class="meta">{{.Info}}</span>{{end}}</li>{{end}}
+</ul>
+{{- end}}
+{{- end}}
+</main>
+</body>
+</html>
+`))
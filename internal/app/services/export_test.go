@@ -0,0 +1,94 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+// readSitePage reads a page of an exported site
+func readSitePage(t *testing.T, dir, page string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(page)))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestExportSiteLeavesOutEncrypted(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	note := models.NewItem(models.TypeNote, "note1")
+	note.Title = "Secret"
+	require.NoError(t, repo.UpdateContent(note, "Hidden"))
+	encryption := NewEncryptionService(repo)
+	require.NoError(t, encryption.Setup("correct horse"))
+	require.NoError(t, encryption.EncryptItem(note, false))
+
+	dir := filepath.Join(t.TempDir(), "site")
+	report, err := NewExportService(repo).ExportSite(dir, SiteOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Items)
+	assert.Equal(t, 1, report.Encrypted)
+	assert.Empty(t, report.Skipped)
+	assert.NoFileExists(t, filepath.Join(dir, "notes", "note1.html"))
+}
+
+func TestExportSite(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	public := models.NewItem(models.TypeNote, "note1")
+	public.Title = "Public note"
+	require.NoError(t, repo.UpdateContent(public, "Shared with [[Other note]] and [[Private note]] #public"))
+	other := models.NewItem(models.TypeNote, "note2")
+	other.Title = "Other note"
+	require.NoError(t, repo.UpdateContent(other, "Also #public"))
+	private := models.NewItem(models.TypeNote, "note3")
+	private.Title = "Private note"
+	require.NoError(t, repo.UpdateContent(private, "Secret #private"))
+	file, err := NewFileService(repo).Add("report.pdf", time.Now(), strings.NewReader("pdf"))
+	require.NoError(t, err)
+
+	// Everything is exported
+	all := filepath.Join(t.TempDir(), "all")
+	report, err := NewExportService(repo).ExportSite(all, SiteOptions{Name: "Test"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Items)
+	assert.Equal(t, 2, report.Tags)
+	assert.Equal(t, 1, report.Files)
+	assert.FileExists(t, filepath.Join(all, "files", file.ID, "report.pdf"))
+	assert.Contains(t, readSitePage(t, all, "files/"+file.ID+".html"), `href="../files/`+file.ID+`/report.pdf"`)
+	assert.Contains(t, readSitePage(t, all, "notes/index.html"), `href="../notes/note1.html"`)
+	assert.Contains(t, readSitePage(t, all, "index.html"), `href="notes/note1.html"`)
+
+	// Item and tag links are relative
+	page := readSitePage(t, all, "notes/note1.html")
+	assert.Contains(t, page, `<a href="../notes/note2.html" class="wiki-link">Other note</a>`)
+	assert.Contains(t, page, `<a href="../notes/note3.html" class="wiki-link">Private note</a>`)
+	assert.Contains(t, page, `<a href="../tags/public.html" class="tag-link">#public</a>`)
+	assert.Contains(t, readSitePage(t, all, "tags/public.html"), `href="../notes/note2.html"`)
+
+	// The tag filter leaves out everything else, links to it included
+	published := filepath.Join(t.TempDir(), "public")
+	report, err = NewExportService(repo).ExportSite(published, SiteOptions{Tags: []string{"public"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Items)
+	assert.Equal(t, 1, report.Tags)
+	assert.NoFileExists(t, filepath.Join(published, "notes", "note3.html"))
+	assert.NoFileExists(t, filepath.Join(published, "tags", "private.html"))
+	page = readSitePage(t, published, "notes/note1.html")
+	assert.NotContains(t, page, "note3.html")
+	assert.Contains(t, page, "wiki-link-unresolved")
+
+	// Exports never write over existing files
+	_, err = NewExportService(repo).ExportSite(published, SiteOptions{})
+	assert.Error(t, err)
+}
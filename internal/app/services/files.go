@@ -0,0 +1,268 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"vovere/internal/app/models"
+)
+
+// Preview kinds of file items, see FileService.Preview
+const (
+	PreviewImage = "image"
+	PreviewPDF   = "pdf"
+	PreviewText  = "text"
+)
+
+// ErrFileTooLarge is returned for an upload over the repository's size limit
+var ErrFileTooLarge = errors.New("file is too large")
+
+// ErrFileConversion is returned when converting a file item, or another item into a file,
+// since the stored file belongs to the file item's ID and type
+var ErrFileConversion = errors.New("file items cannot change type")
+
+// FileService stores the files of file items. Each file lives in files/{id}/ under its
+// original name, next to the optional files/{id}.md description.
+type FileService struct {
+	repo *Repository
+}
+
+// NewFileService creates a new file service
+func NewFileService(repo *Repository) *FileService {
+	return &FileService{
+		repo: repo,
+	}
+}
+
+// Add creates a file item for a file with the given name and creation time and stores its data
+func (s *FileService) Add(name string, created time.Time, data io.Reader) (*models.Item, error) {
+	filename, err := cleanFilename(name)
+	if err != nil {
+		return nil, err
+	}
+
+	spooled, err := spool(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer spooled.Close()
+
+	return s.create(filename, created, "", spooled)
+}
+
+// Upload creates a file item for an uploaded file, with a markdown description as its content.
+// Files over the repository's size limit are refused with ErrFileTooLarge. When a file item
+// already has the same data, nothing is stored and that item is returned as a duplicate.
+func (s *FileService) Upload(name string, data io.Reader, description string) (item *models.Item, duplicate bool, err error) {
+	filename, err := cleanFilename(name)
+	if err != nil {
+		return nil, false, err
+	}
+	config, err := s.repo.Config()
+	if err != nil {
+		return nil, false, err
+	}
+
+	spooled, err := spool(data, config.Files.MaxUploadSize())
+	if err != nil {
+		return nil, false, err
+	}
+	defer spooled.Close()
+
+	existing, err := s.findByHash(spooled.hash)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, true, nil
+	}
+
+	item, err = s.create(filename, time.Time{}, description, spooled)
+	return item, false, err
+}
+
+// Path returns the location of a file item's stored file
+func (s *FileService) Path(item *models.Item) string {
+	return filepath.Join(s.repo.fileDir(item), item.Filename)
+}
+
+// ContentType returns the media type of a file item's stored file, from its extension
+// or, failing that, its first bytes
+func (s *FileService) ContentType(item *models.Item) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(item.Filename)); contentType != "" {
+		return contentType
+	}
+
+	f, err := os.Open(s.Path(item))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return http.DetectContentType(head[:n])
+}
+
+// Preview returns how a file item can be previewed: PreviewImage, PreviewPDF, PreviewText, or "" when it can't
+func (s *FileService) Preview(item *models.Item) string {
+	mediaType, _, _ := mime.ParseMediaType(s.ContentType(item))
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return PreviewImage
+	case mediaType == "application/pdf":
+		return PreviewPDF
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json", mediaType == "application/xml":
+		return PreviewText
+	default:
+		return ""
+	}
+}
+
+// Text returns the start of a text file item, up to limit bytes, and whether there is more.
+// Files that aren't valid UTF-8 are refused.
+func (s *FileService) Text(item *models.Item, limit int) (string, bool, error) {
+	f, err := os.Open(s.Path(item))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	data := make([]byte, limit+1)
+	n, err := io.ReadFull(f, data)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", false, fmt.Errorf("failed to read file: %w", err)
+	}
+	truncated := n > limit
+	if truncated {
+		n = limit
+		// Don't cut a character in half
+		for n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+	}
+	if !utf8.Valid(data[:n]) {
+		return "", false, fmt.Errorf("file is not UTF-8 text")
+	}
+	return string(data[:n]), truncated, nil
+}
+
+// create makes a file item for spooled data and stores it
+func (s *FileService) create(filename string, created time.Time, description string, spooled *spooledFile) (*models.Item, error) {
+	item := models.NewItem(models.TypeFile, "")
+	item.Title = filename
+	item.Filename = filename
+	item.Hash = spooled.hash
+	item.Size = spooled.size
+	if !created.IsZero() {
+		item.Created = created.UTC()
+	}
+	if err := s.repo.CreateItem(item, description); err != nil {
+		return nil, err
+	}
+
+	if err := s.store(item, spooled); err != nil {
+		// Don't leave an item without its file behind
+		if deleteErr := s.repo.DeleteItem(item); deleteErr != nil {
+			return nil, fmt.Errorf("%w (cleanup failed: %v)", err, deleteErr)
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// store copies spooled data to a file item's directory
+func (s *FileService) store(item *models.Item, spooled *spooledFile) error {
+	if err := os.MkdirAll(s.repo.fileDir(item), 0755); err != nil {
+		return fmt.Errorf("failed to create file directory: %w", err)
+	}
+
+	if _, err := spooled.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := writeFileAtomicFrom(s.Path(item), spooled.file, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// findByHash returns the file item whose stored file has a hash, or nil.
+// Items written before files were hashed never match.
+func (s *FileService) findByHash(hash string) (*models.Item, error) {
+	items, err := s.repo.ListItems(models.TypeFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.Hash != hash {
+			continue
+		}
+		// The stored file may have been removed by hand
+		if _, err := os.Stat(s.Path(item)); err == nil {
+			return item, nil
+		}
+	}
+	return nil, nil
+}
+
+// spooledFile is data written to a temporary file, so it can be hashed before it is stored
+type spooledFile struct {
+	file *os.File
+	hash string
+	size int64
+}
+
+// spool writes data to a temporary file, refusing more than limit bytes with ErrFileTooLarge.
+// A limit of zero accepts any size.
+func spool(data io.Reader, limit int64) (*spooledFile, error) {
+	f, err := os.CreateTemp("", "vovere-file-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	spooled := &spooledFile{file: f}
+
+	if limit > 0 {
+		data = io.LimitReader(data, limit+1)
+	}
+	hash := sha256.New()
+	spooled.size, err = io.Copy(io.MultiWriter(f, hash), data)
+	if err != nil {
+		spooled.Close()
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if limit > 0 && spooled.size > limit {
+		spooled.Close()
+		return nil, fmt.Errorf("%w: the limit is %d MB", ErrFileTooLarge, limit>>20)
+	}
+	spooled.hash = hex.EncodeToString(hash.Sum(nil))
+	return spooled, nil
+}
+
+// Close removes the temporary file
+func (f *spooledFile) Close() {
+	f.file.Close()
+	os.Remove(f.file.Name())
+}
+
+// fileDir returns the directory holding the stored file of a file item
+func (r *Repository) fileDir(item *models.Item) string {
+	return filepath.Join(r.basePath, string(item.Type)+"s", item.ID)
+}
+
+// cleanFilename returns the base name of a file, rejecting names that can't be stored
+func cleanFilename(name string) (string, error) {
+	filename := filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if filename == "." || filename == ".." || filename == "/" || strings.HasPrefix(filename, ".") {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return filename, nil
+}
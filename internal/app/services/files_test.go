@@ -0,0 +1,139 @@
+package services
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestFileLifecycle(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	files := NewFileService(repo)
+	trash := NewTrashService(repo)
+
+	created := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	item, err := files.Add("attachments/diagram.png", created, strings.NewReader("image data"))
+	require.NoError(t, err)
+	assert.Equal(t, "20230501100000", item.ID)
+	assert.Equal(t, "diagram.png", item.Filename)
+	data, err := os.ReadFile(files.Path(item))
+	require.NoError(t, err)
+	assert.Equal(t, "image data", string(data))
+
+	// The stored file follows the item into the trash and back
+	require.NoError(t, trash.Trash(item))
+	assert.NoFileExists(t, files.Path(item))
+	restored, err := trash.Restore(models.TypeFile, item.ID)
+	require.NoError(t, err)
+	assert.FileExists(t, files.Path(restored))
+
+	require.NoError(t, repo.DeleteItem(restored))
+	assert.NoDirExists(t, repo.fileDir(restored))
+
+	_, err = files.Add(".hidden", created, strings.NewReader(""))
+	assert.Error(t, err)
+}
+
+func TestFileUpload(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	files := NewFileService(repo)
+
+	item, duplicate, err := files.Upload("notes.txt", strings.NewReader("plain text"), "# Meeting notes\n")
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, "notes.txt", item.Filename)
+	assert.Equal(t, int64(len("plain text")), item.Size)
+	assert.NotEmpty(t, item.Hash)
+
+	_, content, err := repo.LoadItem(item.ID, models.TypeFile)
+	require.NoError(t, err)
+	assert.Equal(t, "# Meeting notes\n", content)
+
+	assert.Equal(t, "text/plain; charset=utf-8", files.ContentType(item))
+	assert.Equal(t, PreviewText, files.Preview(item))
+	text, truncated, err := files.Text(item, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "plain", text)
+	assert.True(t, truncated)
+
+	// The same data is stored once, whatever its name
+	again, duplicate, err := files.Upload("copy.txt", strings.NewReader("plain text"), "")
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+	assert.Equal(t, item.ID, again.ID)
+	items, err := repo.ListItems(models.TypeFile)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	// Once the stored file is gone the data is stored again
+	require.NoError(t, os.Remove(files.Path(item)))
+	_, duplicate, err = files.Upload("notes.txt", strings.NewReader("plain text"), "")
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	pdf, _, err := files.Upload("paper.pdf", strings.NewReader("%PDF-1.4"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", files.ContentType(pdf))
+	assert.Equal(t, PreviewPDF, files.Preview(pdf))
+
+	binary, _, err := files.Upload("data", strings.NewReader("\x00\x01\x02"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "", files.Preview(binary))
+
+	_, _, err = files.Upload("../", strings.NewReader("data"), "")
+	assert.Error(t, err)
+}
+
+func TestFileUploadLimit(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	config, err := repo.Config()
+	require.NoError(t, err)
+	config.Files.MaxUploadMB = 1
+	require.NoError(t, repo.SaveConfig(config))
+
+	files := NewFileService(repo)
+	_, _, err = files.Upload("large.bin", strings.NewReader(strings.Repeat("x", 1<<20+1)), "")
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+
+	items, err := repo.ListItems(models.TypeFile)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	_, _, err = files.Upload("small.bin", strings.NewReader(strings.Repeat("x", 1<<20)), "")
+	assert.NoError(t, err)
+}
+
+func TestFileConversionRefused(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	files := NewFileService(repo)
+	inbox := NewInboxService(repo)
+
+	item, _, err := files.Upload("scan.pdf", strings.NewReader("%PDF-1.4"), "")
+	require.NoError(t, err)
+	assert.ErrorIs(t, inbox.ConvertType(item, models.TypeNote), ErrFileConversion)
+	assert.ErrorIs(t, repo.ConvertItem(item, models.TypeNote), ErrFileConversion)
+
+	// The file item and its stored file are left alone
+	assert.Equal(t, models.TypeFile, item.Type)
+	assert.FileExists(t, files.Path(item))
+	_, _, err = repo.LoadItem(item.ID, models.TypeFile)
+	require.NoError(t, err)
+
+	note := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(note, "Not a file"))
+	assert.ErrorIs(t, repo.ConvertItem(note, models.TypeFile), ErrFileConversion)
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vovere/internal/app/models"
+	"vovere/internal/markdown"
+)
+
+// Front matter precedence values of FrontMatterConfig
+const (
+	// PrecedenceMetadata keeps the metadata when it disagrees with the front matter
+	PrecedenceMetadata = "metadata"
+	// PrecedenceContent takes fields from the front matter over the metadata
+	PrecedenceContent = "content"
+)
+
+// frontMatterFields are the front matter keys mapped to item fields,
+// every other key is kept in the item's properties
+var frontMatterFields = []string{"title", "tags", "url", "status", "description"}
+
+// frontMatter is the parsed YAML front matter of a content file.
+// It keeps the YAML node so key order and comments survive rewriting.
+type frontMatter struct {
+	node *yaml.Node
+	body string
+}
+
+// parseFrontMatter reads the front matter of content. It returns nil when the content has
+// none or it isn't a YAML mapping, such content is left alone.
+func parseFrontMatter(content string) *frontMatter {
+	raw, body, ok := markdown.SplitFrontMatter(content)
+	if !ok {
+		return nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		node = doc.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	return &frontMatter{node: node, body: body}
+}
+
+// value returns the node of a key, or nil
+func (f *frontMatter) value(key string) *yaml.Node {
+	for i := 0; i+1 < len(f.node.Content); i += 2 {
+		if f.node.Content[i].Value == key {
+			return f.node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// str returns the value of a key as a string
+func (f *frontMatter) str(key string) string {
+	if node := f.value(key); node != nil && node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	return ""
+}
+
+// tags returns the tags listed in the front matter, as a YAML list or a comma or space separated string
+func (f *frontMatter) tags() []string {
+	node := f.value("tags")
+	if node == nil {
+		return nil
+	}
+
+	var values []string
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			if child.Kind == yaml.ScalarNode {
+				values = append(values, child.Value)
+			}
+		}
+	case yaml.ScalarNode:
+		values = strings.FieldsFunc(node.Value, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+
+	var tags []string
+	for _, value := range values {
+		tag := strings.TrimLeft(strings.TrimSpace(value), "#")
+		if tag != "" && !contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// properties returns the keys that don't map to item fields
+func (f *frontMatter) properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i+1 < len(f.node.Content); i += 2 {
+		key := f.node.Content[i].Value
+		if contains(frontMatterFields, key) {
+			continue
+		}
+		if value, err := propertyValue(f.node.Content[i+1]); err == nil {
+			properties[key] = value
+		}
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+	return properties
+}
+
+// propertyValue decodes a front matter value. Dates stay as written, decoded they would be
+// stored as timestamps and written back in another format.
+func propertyValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return propertyValue(node.Alias)
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!timestamp" {
+			return node.Value, nil
+		}
+	case yaml.SequenceNode:
+		values := make([]interface{}, 0, len(node.Content))
+		for _, child := range node.Content {
+			value, err := propertyValue(child)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case yaml.MappingNode:
+		values := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].ShortTag() == "!!merge" {
+				// Merged mappings are left to the decoder
+				var value interface{}
+				err := node.Decode(&value)
+				return value, err
+			}
+			value, err := propertyValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			values[node.Content[i].Value] = value
+		}
+		return values, nil
+	}
+
+	var value interface{}
+	err := node.Decode(&value)
+	return value, err
+}
+
+// set replaces the value of a key, adding it at the end if it's new.
+// A nil value removes the key.
+func (f *frontMatter) set(key string, value interface{}) error {
+	if value == nil {
+		f.remove(key)
+		return nil
+	}
+
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode front matter %s: %w", key, err)
+	}
+
+	for i := 0; i+1 < len(f.node.Content); i += 2 {
+		if f.node.Content[i].Value == key {
+			// Leave equal values as written, in their original style
+			current, currentErr := propertyValue(f.node.Content[i+1])
+			updated, updatedErr := propertyValue(&node)
+			if currentErr == nil && updatedErr == nil && reflect.DeepEqual(current, updated) {
+				return nil
+			}
+			// Keep comments attached to the old value
+			node.HeadComment = f.node.Content[i+1].HeadComment
+			node.LineComment = f.node.Content[i+1].LineComment
+			f.node.Content[i+1] = &node
+			return nil
+		}
+	}
+	f.node.Content = append(f.node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &node)
+	return nil
+}
+
+// remove deletes a key
+func (f *frontMatter) remove(key string) {
+	for i := 0; i+1 < len(f.node.Content); i += 2 {
+		if f.node.Content[i].Value == key {
+			f.node.Content = append(f.node.Content[:i], f.node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// String returns the content with the front matter
+func (f *frontMatter) String() (string, error) {
+	if len(f.node.Content) == 0 {
+		return f.body, nil
+	}
+
+	var out strings.Builder
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(f.node); err != nil {
+		return "", fmt.Errorf("failed to encode front matter: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode front matter: %w", err)
+	}
+	return markdown.JoinFrontMatter(out.String(), f.body), nil
+}
+
+// frontMatterTags returns the tags listed in the front matter of content
+func frontMatterTags(content string) []string {
+	if fm := parseFrontMatter(content); fm != nil {
+		return fm.tags()
+	}
+	return nil
+}
+
+// ContentTitle returns the title of content: the front matter title, or else the one
+// markdown.ExtractTitleFromContent finds in the body
+func ContentTitle(content string, itemType models.ItemType) string {
+	if fm := parseFrontMatter(content); fm != nil {
+		if title := fm.str("title"); title != "" {
+			return title
+		}
+	}
+	return markdown.ExtractTitleFromContent(content, string(itemType))
+}
+
+// applyFrontMatter copies the front matter fields of content onto an item.
+// With overwrite the front matter wins, otherwise it only fills fields the item doesn't have.
+// Tags are left alone, they come from the content through ExtractTags.
+func applyFrontMatter(item *models.Item, content string, overwrite bool) {
+	fm := parseFrontMatter(content)
+	if fm == nil {
+		return
+	}
+
+	setString := func(field *string, key string) {
+		if value := fm.str(key); value != "" && (overwrite || *field == "") {
+			*field = value
+		}
+	}
+	setString(&item.Title, "title")
+	setString(&item.URL, "url")
+	setString(&item.Description, "description")
+	if item.Type == models.TypeTask {
+		status := models.TaskStatus(fm.str("status"))
+		if (status == models.TaskStatusTodo || status == models.TaskStatusDone) && (overwrite || item.Status == "") {
+			item.Status = status
+		}
+	}
+
+	if properties := fm.properties(); properties != nil && (overwrite || len(item.Properties) == 0) {
+		item.Properties = properties
+	}
+}
+
+// writeFrontMatter returns content with its front matter updated from the item.
+// Tags already in the body as hashtags aren't repeated. Content without front matter
+// only gets one when the item has something to put in it.
+func writeFrontMatter(item *models.Item, content string) (string, error) {
+	fm := parseFrontMatter(content)
+	if fm == nil {
+		if _, _, ok := markdown.SplitFrontMatter(content); ok {
+			return content, nil // Not YAML we understand, leave it alone
+		}
+		fm = &frontMatter{node: &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, body: content}
+	}
+
+	optional := func(value string) interface{} {
+		if value == "" {
+			return nil
+		}
+		return value
+	}
+
+	// Tags keep their order in the front matter, new ones are added sorted
+	hashtags := extractHashtags(fm.body)
+	var tags, added []string
+	for _, tag := range fm.tags() {
+		if contains(item.Tags, tag) && !contains(hashtags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	for _, tag := range item.Tags {
+		if !contains(hashtags, tag) && !contains(tags, tag) {
+			added = append(added, tag)
+		}
+	}
+	sort.Strings(added)
+	tags = append(tags, added...)
+	var tagsValue interface{}
+	if len(tags) > 0 {
+		tagsValue = tags
+	}
+
+	// A title equal to the ID is a placeholder, not worth recording
+	var title interface{}
+	if item.Title != item.ID {
+		title = optional(item.Title)
+	}
+
+	fields := []struct {
+		key   string
+		value interface{}
+	}{
+		{"title", title},
+		{"tags", tagsValue},
+		{"url", optional(item.URL)},
+		{"status", optional(string(item.Status))},
+		{"description", optional(item.Description)},
+	}
+	for _, field := range fields {
+		if err := fm.set(field.key, field.value); err != nil {
+			return "", err
+		}
+	}
+
+	// Properties the item no longer has are removed
+	for _, key := range fm.propertyKeys() {
+		if _, ok := item.Properties[key]; !ok {
+			fm.remove(key)
+		}
+	}
+	keys := make([]string, 0, len(item.Properties))
+	for key := range item.Properties {
+		if !contains(frontMatterFields, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fm.set(key, item.Properties[key]); err != nil {
+			return "", err
+		}
+	}
+
+	return fm.String()
+}
+
+// propertyKeys returns the keys that don't map to item fields, in order
+func (f *frontMatter) propertyKeys() []string {
+	var keys []string
+	for i := 0; i+1 < len(f.node.Content); i += 2 {
+		if key := f.node.Content[i].Value; !contains(frontMatterFields, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
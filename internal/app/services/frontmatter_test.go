@@ -0,0 +1,120 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestExtractTagsFromFrontMatter(t *testing.T) {
+	tagService := NewTagService(nil)
+
+	tags := tagService.ExtractTags("---\ntags: [project, \"#urgent\"]\n---\nBody with #inline")
+	assert.ElementsMatch(t, []string{"project", "urgent", "inline"}, tags)
+
+	tags = tagService.ExtractTags("---\ntags: one, two\n---\n")
+	assert.ElementsMatch(t, []string{"one", "two"}, tags)
+}
+
+func TestFrontMatterFillsMetadata(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	content := "---\ntitle: From Obsidian\ntags:\n  - shared\nurl: https://example.com\naliases: [other]\n---\nBody #inline"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "obsidian.md"), []byte(content), 0644))
+	require.NoError(t, repo.adoptContent(models.TypeNote, "obsidian"))
+
+	item, loaded, err := repo.LoadItem("obsidian", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, content, loaded, "front matter isn't written unless configured")
+	assert.Equal(t, "From Obsidian", item.Title)
+	assert.Equal(t, "https://example.com", item.URL)
+	assert.ElementsMatch(t, []string{"shared", "inline"}, item.Tags)
+	assert.Equal(t, []interface{}{"other"}, item.Properties["aliases"])
+
+	items, err := NewTagService(repo).GetItemsByTag("shared")
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	// With metadata precedence front matter only fills empty fields
+	item.Title = "Renamed"
+	require.NoError(t, repo.SaveItem(item, ""))
+	item, _, err = repo.LoadItem("obsidian", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", item.Title)
+}
+
+func TestFrontMatterContentPrecedence(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{FrontMatter: FrontMatterConfig{Precedence: PrecedenceContent}}))
+
+	task := models.NewItem(models.TypeTask, "task1")
+	task.Title = "Metadata title"
+	task.Status = models.TaskStatusTodo
+	require.NoError(t, repo.SaveItem(task, "Plain content"))
+
+	require.NoError(t, repo.UpdateContent(task, "---\ntitle: Content title\nstatus: done\n---\nPlain content"))
+
+	loaded, _, err := repo.LoadItem("task1", models.TypeTask)
+	require.NoError(t, err)
+	assert.Equal(t, "Content title", loaded.Title)
+	assert.Equal(t, models.TaskStatusDone, loaded.Status)
+}
+
+func TestWriteFrontMatter(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{FrontMatter: FrontMatterConfig{Write: true}}))
+
+	bookmark := models.NewItem(models.TypeBookmark, "bookmark1")
+	bookmark.Title = "Example"
+	bookmark.URL = "https://example.com"
+	bookmark.Tags = []string{"reading", "inline"}
+	require.NoError(t, repo.SaveItem(bookmark, "---\n# Kept for Hugo\nweight: 10\n---\nNotes #inline"))
+
+	_, content, err := repo.LoadItem("bookmark1", models.TypeBookmark)
+	require.NoError(t, err)
+	assert.Equal(t, "---\n# Kept for Hugo\nweight: 10\ntitle: Example\ntags:\n  - reading\nurl: https://example.com\n---\nNotes #inline", content)
+
+	// Tags in the front matter are indexed like hashtags
+	items, err := NewTagService(repo).GetItemsByTag("reading")
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	// Saving the same content again doesn't change it
+	loaded, content, err := repo.LoadItem("bookmark1", models.TypeBookmark)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateContent(loaded, content))
+	_, again, err := repo.LoadItem("bookmark1", models.TypeBookmark)
+	require.NoError(t, err)
+	assert.Equal(t, content, again)
+
+	// Metadata-only saves keep the front matter in line
+	loaded.URL = "https://example.org"
+	require.NoError(t, repo.SaveItem(loaded, ""))
+	_, content, err = repo.LoadItem("bookmark1", models.TypeBookmark)
+	require.NoError(t, err)
+	assert.Contains(t, content, "url: https://example.org\n")
+
+	// Dates keep the format they were written in, after the cache is read from disk too
+	note := models.NewItem(models.TypeNote, "note1")
+	note.Title = "Dated"
+	require.NoError(t, repo.SaveItem(note, "---\ndue: 2024-01-05\n---\nBody"))
+	repo.items.reset()
+	loaded, _, err = repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-05", loaded.Properties["due"])
+	loaded.Title = "Still dated"
+	require.NoError(t, repo.SaveItem(loaded, ""))
+	_, content, err = repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Contains(t, content, "due: 2024-01-05\n")
+}
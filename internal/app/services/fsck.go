@@ -0,0 +1,452 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"vovere/internal/app/models"
+)
+
+// IssueCategory groups the inconsistencies found by the consistency checker
+type IssueCategory string
+
+const (
+	// IssueInvalidMetadata is a metadata file that can't be parsed or names another item
+	IssueInvalidMetadata IssueCategory = "invalid-metadata"
+	// IssueOrphanContent is a content file without metadata
+	IssueOrphanContent IssueCategory = "orphan-content"
+	// IssueMissingContent is metadata whose content file is gone although its history has content
+	IssueMissingContent IssueCategory = "missing-content"
+	// IssueTagMismatch is metadata whose tags differ from the hashtags in its content
+	IssueTagMismatch IssueCategory = "tag-mismatch"
+	// IssueInvalidTagFile is a tag index file that can't be parsed
+	IssueInvalidTagFile IssueCategory = "invalid-tag-file"
+	// IssueStaleTagEntry is a tag index entry for a missing item or an item without the tag
+	IssueStaleTagEntry IssueCategory = "stale-tag-entry"
+	// IssueMissingTagEntry is an item tag the tag index doesn't list
+	IssueMissingTagEntry IssueCategory = "missing-tag-entry"
+	// IssueDanglingReference is a workstream reference to a missing item
+	IssueDanglingReference IssueCategory = "dangling-reference"
+	// IssueTempFile is a temporary file left behind by an interrupted write
+	IssueTempFile IssueCategory = "temp-file"
+)
+
+// Issue is a single inconsistency in a repository
+type Issue struct {
+	Category IssueCategory `json:"category"`
+	// Path is relative to the repository root
+	Path     string `json:"path"`
+	Item     string `json:"item,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+	Message  string `json:"message"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+// FsckReport is the result of a consistency check
+type FsckReport struct {
+	Items  int     `json:"items"`
+	Issues []Issue `json:"issues"`
+	// Repaired is set when the report comes from a repair run
+	Repaired bool `json:"repaired"`
+}
+
+// ByCategory groups the issues of a report by category
+func (r *FsckReport) ByCategory() map[IssueCategory][]Issue {
+	groups := make(map[IssueCategory][]Issue)
+	for _, issue := range r.Issues {
+		groups[issue.Category] = append(groups[issue.Category], issue)
+	}
+	return groups
+}
+
+// Remaining returns the number of issues that weren't repaired
+func (r *FsckReport) Remaining() int {
+	remaining := 0
+	for _, issue := range r.Issues {
+		if !issue.Repaired {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// FsckService checks that metadata, content and the tag index of a repository agree
+type FsckService struct {
+	repo *Repository
+}
+
+// NewFsckService creates a new consistency checker
+func NewFsckService(repo *Repository) *FsckService {
+	return &FsckService{
+		repo: repo,
+	}
+}
+
+// fsckItem is an item whose metadata could be read
+type fsckItem struct {
+	item    *models.Item
+	content string
+}
+
+// Check scans the repository and reports every inconsistency without changing anything
+func (s *FsckService) Check() (*FsckReport, error) {
+	report := &FsckReport{Issues: []Issue{}}
+	items := make(map[string]*fsckItem)
+	history := NewHistoryService(s.repo)
+	tagService := NewTagService(s.repo)
+
+	for _, itemType := range s.repo.ItemTypes() {
+		metaIDs, err := s.listIDs(filepath.Join(".meta", string(itemType)+"s"), ".json")
+		if err != nil {
+			return nil, err
+		}
+		contentIDs, err := s.listIDs(string(itemType)+"s", ".md")
+		if err != nil {
+			return nil, err
+		}
+		hasMeta, hasContent := idSet(metaIDs), idSet(contentIDs)
+
+		for _, id := range metaIDs {
+			ref := ItemRef(&models.Item{ID: id, Type: itemType})
+			metaPath := s.relative(s.repo.getMetaPath(&models.Item{ID: id, Type: itemType}))
+
+			item, content, err := s.repo.LoadItem(id, itemType)
+			if err != nil {
+				report.add(Issue{Category: IssueInvalidMetadata, Path: metaPath, Item: ref, Message: err.Error()})
+				continue
+			}
+			if item.ID != id || item.Type != itemType {
+				report.add(Issue{Category: IssueInvalidMetadata, Path: metaPath, Item: ref,
+					Message: fmt.Sprintf("metadata describes %s", ItemRef(item))})
+				item.ID, item.Type = id, itemType
+				content = ""
+				if data, err := os.ReadFile(s.repo.getContentPath(item)); err == nil {
+					content = string(data)
+				}
+			}
+
+			report.Items++
+			items[ref] = &fsckItem{item: item, content: content}
+
+			if !hasContent[id] {
+				revisions, err := history.readRevisions(item)
+				if err != nil {
+					return nil, err
+				}
+				if len(revisions) > 0 && revisions[len(revisions)-1].Size > 0 {
+					report.add(Issue{Category: IssueMissingContent, Path: s.relative(s.repo.getContentPath(item)), Item: ref,
+						Message: "content file is missing, the latest revision can restore it"})
+				}
+			}
+
+			// Encrypted content has no readable hashtags
+			if item.Encrypted {
+				continue
+			}
+			if expected := tagService.ExtractTags(content); !sameTags(item.Tags, expected) {
+				report.add(Issue{Category: IssueTagMismatch, Path: metaPath, Item: ref,
+					Message: fmt.Sprintf("metadata tags %v differ from content tags %v", sortedTags(item.Tags), sortedTags(expected))})
+			}
+		}
+
+		for _, id := range contentIDs {
+			if !hasMeta[id] {
+				item := &models.Item{ID: id, Type: itemType}
+				report.add(Issue{Category: IssueOrphanContent, Path: s.relative(s.repo.getContentPath(item)), Item: ItemRef(item),
+					Message: "content file has no metadata"})
+			}
+		}
+	}
+
+	if err := s.checkTagIndex(report, items); err != nil {
+		return nil, err
+	}
+	s.checkWorkstreams(report, items)
+	if err := s.checkTempFiles(report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Repair checks the repository, fixes what it can and reports which issues were repaired.
+// Orphan content is adopted, missing content is restored from history, workstream references
+// to missing items are dropped and the tag index is rebuilt from the hashtags in all content.
+func (s *FsckService) Repair() (*FsckReport, error) {
+	report, err := s.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, issue := range report.Issues {
+		if err := s.repair(issue); err != nil {
+			return nil, fmt.Errorf("failed to repair %s %s: %w", issue.Category, issue.Path, err)
+		}
+	}
+
+	// Adopting orphans runs after unreadable metadata was moved aside
+	for _, issue := range report.Issues {
+		if issue.Category != IssueOrphanContent && !(issue.Category == IssueInvalidMetadata && s.isAside(issue)) {
+			continue
+		}
+		id, itemType, ok := parseItemRef(issue.Item)
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(s.repo.getContentPath(&models.Item{ID: id, Type: itemType})); err != nil {
+			continue
+		}
+		if err := s.repo.adoptContent(itemType, id); err != nil {
+			return nil, fmt.Errorf("failed to adopt %s: %w", issue.Path, err)
+		}
+	}
+
+	// Rebuild the indexes from the repaired files
+	if err := NewTagService(s.repo).Rebuild(); err != nil {
+		return nil, fmt.Errorf("failed to rebuild tag index: %w", err)
+	}
+	if err := NewLinkService(s.repo).Rebuild(); err != nil {
+		return nil, fmt.Errorf("failed to rebuild link index: %w", err)
+	}
+	search := NewSearchService(s.repo)
+	if _, err := os.Stat(search.indexDir()); err == nil {
+		if err := search.Rebuild(); err != nil {
+			return nil, fmt.Errorf("failed to rebuild search index: %w", err)
+		}
+	}
+
+	// Anything the second check doesn't find any more was repaired
+	after, err := s.Check()
+	if err != nil {
+		return nil, err
+	}
+	remaining := make(map[string]bool, len(after.Issues))
+	for _, issue := range after.Issues {
+		remaining[issue.key()] = true
+	}
+	for i := range report.Issues {
+		report.Issues[i].Repaired = !remaining[report.Issues[i].key()]
+	}
+	report.Items = after.Items
+	report.Repaired = true
+
+	return report, nil
+}
+
+// repair fixes a single issue in place; tag index issues are left to the rebuild
+func (s *FsckService) repair(issue Issue) error {
+	path := filepath.Join(s.repo.BasePath(), filepath.FromSlash(issue.Path))
+
+	switch issue.Category {
+	case IssueTempFile:
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+	case IssueInvalidMetadata:
+		id, itemType, _ := parseItemRef(issue.Item)
+		item, _, err := s.repo.LoadItem(id, itemType)
+		if err != nil {
+			// Keep unreadable metadata for inspection, the content is adopted again
+			return os.Rename(path, path+".corrupt")
+		}
+		item.ID, item.Type = id, itemType
+		return s.repo.writeMetadata(item)
+
+	case IssueMissingContent:
+		id, itemType, _ := parseItemRef(issue.Item)
+		item := &models.Item{ID: id, Type: itemType}
+		history := NewHistoryService(s.repo)
+		revisions, err := history.readRevisions(item)
+		if err != nil || len(revisions) == 0 {
+			return err
+		}
+		_, content, err := history.Revision(item, revisions[len(revisions)-1].ID)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return writeFileAtomic(path, []byte(content), 0644)
+
+	case IssueDanglingReference:
+		id := strings.TrimSuffix(filepath.Base(issue.Path), ".json")
+		workstream, _, err := s.repo.LoadItem(id, models.TypeWorkstream)
+		if err != nil {
+			return err
+		}
+		workstream.Items = removeString(workstream.Items, issue.Item)
+		return s.repo.writeMetadata(workstream)
+	}
+
+	return nil
+}
+
+// isAside reports whether an invalid metadata issue is unreadable, so its file was moved aside
+func (s *FsckService) isAside(issue Issue) bool {
+	path := filepath.Join(s.repo.BasePath(), filepath.FromSlash(issue.Path))
+	_, err := os.Stat(path + ".corrupt")
+	return err == nil
+}
+
+// checkTagIndex compares the tag index with the tags in item metadata
+func (s *FsckService) checkTagIndex(report *FsckReport, items map[string]*fsckItem) error {
+	tagsDir := filepath.Join(s.repo.BasePath(), ".meta", "tags")
+	entries, err := os.ReadDir(tagsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read tags directory: %w", err)
+	}
+
+	indexed := make(map[string][]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".json" || strings.HasPrefix(name, ".") {
+			continue
+		}
+		tag := tagFromFileName(strings.TrimSuffix(name, ".json"))
+		path := s.relative(filepath.Join(tagsDir, name))
+
+		data, err := os.ReadFile(filepath.Join(tagsDir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read tag file: %w", err)
+		}
+		var refs []string
+		if err := json.Unmarshal(data, &refs); err != nil {
+			report.add(Issue{Category: IssueInvalidTagFile, Path: path, Tag: tag, Message: err.Error()})
+			continue
+		}
+
+		for _, ref := range refs {
+			indexed[ref] = append(indexed[ref], tag)
+			switch entry, ok := items[ref]; {
+			case !ok:
+				report.add(Issue{Category: IssueStaleTagEntry, Path: path, Item: ref, Tag: tag, Message: "tag lists an item that doesn't exist"})
+			case entry.item.Encrypted:
+				report.add(Issue{Category: IssueStaleTagEntry, Path: path, Item: ref, Tag: tag, Message: "tag lists an encrypted item"})
+			case !contains(entry.item.Tags, tag):
+				report.add(Issue{Category: IssueStaleTagEntry, Path: path, Item: ref, Tag: tag, Message: "tag lists an item without the tag"})
+			}
+		}
+	}
+
+	for _, ref := range sortedKeys(items) {
+		entry := items[ref]
+		if entry.item.Encrypted {
+			continue
+		}
+		for _, tag := range sortedTags(entry.item.Tags) {
+			if !contains(indexed[ref], tag) {
+				report.add(Issue{Category: IssueMissingTagEntry, Path: s.relative(filepath.Join(tagsDir, tagFileName(tag)+".json")), Item: ref, Tag: tag,
+					Message: "item tag is missing from the tag index"})
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkWorkstreams reports workstream references to items that don't exist
+func (s *FsckService) checkWorkstreams(report *FsckReport, items map[string]*fsckItem) {
+	for _, ref := range sortedKeys(items) {
+		workstream := items[ref].item
+		if workstream.Type != models.TypeWorkstream {
+			continue
+		}
+		for _, member := range workstream.Items {
+			if _, ok := items[member]; !ok {
+				report.add(Issue{Category: IssueDanglingReference, Path: s.relative(s.repo.getMetaPath(workstream)), Item: member,
+					Message: fmt.Sprintf("workstream %s references an item that doesn't exist", workstream.ID)})
+			}
+		}
+	}
+}
+
+// checkTempFiles reports temporary files left by interrupted atomic writes
+func (s *FsckService) checkTempFiles(report *FsckReport) error {
+	return filepath.WalkDir(s.repo.BasePath(), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") && strings.HasSuffix(entry.Name(), ".tmp") {
+			report.add(Issue{Category: IssueTempFile, Path: s.relative(path), Message: "temporary file left by an interrupted write"})
+		}
+		return nil
+	})
+}
+
+// idSet returns the IDs of a listing as a set
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// listIDs returns the IDs of the files with an extension in a directory relative to the repository
+func (s *FsckService) listIDs(dir, ext string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.repo.BasePath(), dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ext || strings.HasPrefix(name, ".") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ext))
+	}
+	return ids, nil
+}
+
+// relative returns a path relative to the repository root with forward slashes
+func (s *FsckService) relative(path string) string {
+	if rel, err := filepath.Rel(s.repo.BasePath(), path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// add appends an issue to the report
+func (r *FsckReport) add(issue Issue) {
+	r.Issues = append(r.Issues, issue)
+}
+
+// key identifies an issue across two checks
+func (i Issue) key() string {
+	return strings.Join([]string{string(i.Category), i.Path, i.Item, i.Tag}, "\x00")
+}
+
+// sortedTags returns a sorted copy of a tag list
+func sortedTags(tags []string) []string {
+	sorted := append([]string{}, tags...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// sortedKeys returns the keys of the checked items in order
+func sortedKeys(items map[string]*fsckItem) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestFsckConsistentRepository(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	note := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(note, "A note #tagged"))
+
+	report, err := NewFsckService(repo).Check()
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Items)
+	assert.Empty(t, report.Issues)
+}
+
+func TestFsckRepairsInconsistencies(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	fsck := NewFsckService(repo)
+
+	note := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(note, "A note #kept"))
+	lost := models.NewItem(models.TypeNote, "lost")
+	require.NoError(t, repo.SaveItem(lost, "Content that went missing"))
+	corrupt := models.NewItem(models.TypeNote, "corrupt")
+	require.NoError(t, repo.SaveItem(corrupt, "Metadata got truncated #broken"))
+	workstream := models.NewItem(models.TypeWorkstream, "ws1")
+	workstream.Items = []string{"note1:note", "gone:task"}
+	require.NoError(t, repo.SaveItem(workstream, ""))
+
+	// A tag entry for an item that doesn't exist and an item tag missing from the index
+	writeJSON(t, filepath.Join(tempDir, ".meta", "tags", "stale.json"), []string{"gone:note"})
+	require.NoError(t, os.Remove(filepath.Join(tempDir, ".meta", "tags", "kept.json")))
+	// Content without metadata, metadata without content, unreadable metadata and a leftover temp file
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "orphan.md"), []byte("# Orphan\n\nFound #adopted"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(tempDir, "notes", "lost.md")))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".meta", "notes", "corrupt.json"), []byte(`{"id": "corr`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".meta", "notes", ".note1.json.123.tmp"), []byte("{"), 0644))
+
+	report, err := fsck.Check()
+	require.NoError(t, err)
+	groups := report.ByCategory()
+	// The unreadable item's tag entry is stale as well
+	assert.Len(t, groups[IssueStaleTagEntry], 2)
+	assert.Len(t, groups[IssueMissingTagEntry], 1)
+	assert.Len(t, groups[IssueOrphanContent], 1)
+	assert.Len(t, groups[IssueMissingContent], 1)
+	assert.Len(t, groups[IssueInvalidMetadata], 1)
+	assert.Len(t, groups[IssueDanglingReference], 1)
+	assert.Len(t, groups[IssueTempFile], 1)
+	assert.Equal(t, "notes/orphan.md", groups[IssueOrphanContent][0].Path)
+	assert.Equal(t, "gone:task", groups[IssueDanglingReference][0].Item)
+
+	// Checking doesn't change anything
+	_, err = os.Stat(filepath.Join(tempDir, ".meta", "notes", ".note1.json.123.tmp"))
+	assert.NoError(t, err)
+
+	report, err = fsck.Repair()
+	require.NoError(t, err)
+	assert.True(t, report.Repaired)
+	assert.Equal(t, 0, report.Remaining())
+
+	after, err := fsck.Check()
+	require.NoError(t, err)
+	assert.Empty(t, after.Issues)
+
+	tagService := NewTagService(repo)
+	tags, err := tagService.GetAllTags()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"kept", "broken", "adopted"}, tags)
+
+	_, content, err := repo.LoadItem("lost", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Content that went missing", content)
+
+	orphan, _, err := repo.LoadItem("orphan", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Orphan", orphan.Title)
+
+	// Unreadable metadata is kept aside and the item is adopted again from its content
+	_, err = os.Stat(filepath.Join(tempDir, ".meta", "notes", "corrupt.json.corrupt"))
+	assert.NoError(t, err)
+	adopted, _, err := repo.LoadItem("corrupt", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"broken"}, adopted.Tags)
+
+	loaded, _, err := repo.LoadItem("ws1", models.TypeWorkstream)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"note1:note"}, loaded.Items)
+}
+
+func TestTagRebuildFollowsContent(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	note := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(note, "Tagged #first"))
+
+	// Edit the content behind the repository's back
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "note1.md"), []byte("Tagged #second"), 0644))
+
+	tagService := NewTagService(repo)
+	require.NoError(t, tagService.Rebuild())
+
+	tags, err := tagService.GetAllTags()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second"}, tags)
+
+	loaded, _, err := repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second"}, loaded.Tags)
+}
+
+// writeJSON writes a value as JSON, creating the parent directory
+func writeJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, data, 0644))
+}
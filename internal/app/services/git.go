@@ -0,0 +1,297 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"vovere/internal/app/models"
+)
+
+// defaultGitQuietSeconds is how long saves must stop before they are committed when not configured
+const defaultGitQuietSeconds = 30
+
+// defaultGitRemote is the remote pushed to when pushing is enabled without naming one
+const defaultGitRemote = "origin"
+
+// gitIgnoredPaths are the indexes that can be rebuilt from the items, they are never committed
+var gitIgnoredPaths = []string{".meta/index", ".meta/links", ".meta/tags"}
+
+// ErrGitUnavailable is returned when the git binary can't be found
+var ErrGitUnavailable = errors.New("git is not installed")
+
+// GitStatus describes the git state of a repository
+type GitStatus struct {
+	// Enabled reports whether saves are committed automatically
+	Enabled    bool   `json:"enabled"`
+	Branch     string `json:"branch"`
+	LastCommit string `json:"lastCommit"`
+	// Pending are the changes waiting for the quiet period before they are committed
+	Pending []string `json:"pending"`
+	// Changes are the uncommitted files, rebuildable indexes left out
+	Changes []GitChange `json:"changes"`
+}
+
+// GitChange is an uncommitted file
+type GitChange struct {
+	Path string `json:"path"`
+	// Status is the two-letter code of git status --porcelain
+	Status string `json:"status"`
+}
+
+// GitService commits a repository with the local git binary. Saves are queued and
+// committed together once the repository has been quiet for a while. It never talks
+// to a remote unless pushing is enabled in config.json.
+type GitService struct {
+	repo *Repository
+
+	mu      sync.Mutex
+	pending []string
+	timer   *time.Timer
+
+	// commitMu serializes commits, which run outside mu
+	commitMu sync.Mutex
+}
+
+// NewGitService creates a new git service
+func NewGitService(repo *Repository) *GitService {
+	return &GitService{
+		repo: repo,
+	}
+}
+
+// Init makes the repository a git work tree if it isn't one yet
+func (s *GitService) Init() error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return ErrGitUnavailable
+	}
+	if _, err := s.git("rev-parse", "--is-inside-work-tree"); err == nil {
+		return nil
+	}
+	if _, err := s.git("init", "--quiet"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Queue records a change to commit once the repository has been quiet for the configured period
+func (s *GitService) Queue(message string) {
+	config, err := s.repo.Config()
+	if err != nil {
+		log.Printf("Error reading config of %s: %v", s.repo.BasePath(), err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !contains(s.pending, message) {
+		s.pending = append(s.pending, message)
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.timer = time.AfterFunc(config.Git.quietPeriod(), func() {
+		if err := s.Commit(); err != nil {
+			log.Printf("Error committing %s: %v", s.repo.BasePath(), err)
+		}
+	})
+}
+
+// Commit commits every change now, with a message listing the queued ones.
+// It does nothing when there is nothing to commit.
+func (s *GitService) Commit() error {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	s.commitMu.Lock()
+	defer s.commitMu.Unlock()
+
+	committed, err := s.commit(pending)
+	if err != nil {
+		// Keep the messages for the next attempt
+		s.mu.Lock()
+		s.pending = append(pending, s.pending...)
+		s.mu.Unlock()
+		return err
+	}
+	if !committed {
+		return nil
+	}
+
+	config, err := s.repo.Config()
+	if err != nil {
+		return err
+	}
+	if config.Git.Push {
+		if _, err := s.git("push", "--quiet", config.Git.remote(), "HEAD"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit stages and commits the repository, reporting whether there was anything to commit
+func (s *GitService) commit(messages []string) (bool, error) {
+	// Staging waits for running saves, so a commit never holds half of one
+	unlock := s.repo.lock()
+	_, err := s.git(append([]string{"add", "--all", "--"}, gitPathspec()...)...)
+	unlock()
+	if err != nil {
+		return false, err
+	}
+
+	// Nothing staged, e.g. a save that didn't change any file
+	if _, err := s.git("diff", "--cached", "--quiet"); err == nil {
+		return false, nil
+	}
+
+	args := []string{"commit", "--quiet", "-m", commitSubject(messages)}
+	if len(messages) > 1 {
+		args = append(args, "-m", strings.Join(messages, "\n"))
+	}
+	// Commits work without a configured identity
+	if _, err := s.git("config", "user.email"); err != nil {
+		args = append([]string{"-c", "user.name=Vovere", "-c", "user.email=vovere@localhost"}, args...)
+	}
+	if _, err := s.git(args...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Stop commits the queued changes without waiting for the quiet period
+func (s *GitService) Stop() {
+	s.mu.Lock()
+	hasPending := len(s.pending) > 0
+	s.mu.Unlock()
+
+	if hasPending {
+		if err := s.Commit(); err != nil {
+			log.Printf("Error committing %s: %v", s.repo.BasePath(), err)
+		}
+	}
+}
+
+// Status returns the branch, the last commit and the uncommitted changes of the repository
+func (s *GitService) Status() (*GitStatus, error) {
+	config, err := s.repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &GitStatus{
+		Enabled: config.Git.Enabled,
+		Pending: []string{},
+		Changes: []GitChange{},
+	}
+	s.mu.Lock()
+	status.Pending = append(status.Pending, s.pending...)
+	s.mu.Unlock()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, ErrGitUnavailable
+	}
+	if _, err := s.git("rev-parse", "--is-inside-work-tree"); err != nil {
+		return status, nil // Not a git repository, nothing else to report
+	}
+
+	if branch, err := s.git("symbolic-ref", "--short", "HEAD"); err == nil {
+		status.Branch = strings.TrimSpace(branch)
+	}
+	if last, err := s.git("log", "-1", "--format=%h %s"); err == nil {
+		status.LastCommit = strings.TrimSpace(last)
+	}
+
+	out, err := s.git(append([]string{"status", "--porcelain", "-z", "--untracked-files=all", "--"}, gitPathspec()...)...)
+	if err != nil {
+		return nil, err
+	}
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		code := entry[:2]
+		status.Changes = append(status.Changes, GitChange{Path: entry[3:], Status: code})
+		// Renames and copies are followed by their original path
+		if code[0] == 'R' || code[0] == 'C' {
+			i++
+		}
+	}
+	return status, nil
+}
+
+// git runs a git command in the repository and returns its output
+func (s *GitService) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.repo.BasePath()
+	// Never wait for credentials, a push without them fails instead
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s failed: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// gitPathspec returns the pathspec of everything but the rebuildable indexes and temporary files
+func gitPathspec() []string {
+	pathspec := []string{"."}
+	for _, path := range gitIgnoredPaths {
+		pathspec = append(pathspec, ":(exclude)"+path)
+	}
+	return append(pathspec, ":(exclude,glob)**/.*.tmp")
+}
+
+// commitSubject returns the first line of a commit message for the queued changes
+func commitSubject(messages []string) string {
+	switch len(messages) {
+	case 0:
+		return "Update repository"
+	case 1:
+		return messages[0]
+	default:
+		return fmt.Sprintf("Update %d items", len(messages))
+	}
+}
+
+// Git returns the git service committing the repository, or a new one for repositories
+// without automatic commits, which can still report their status and commit by hand
+func (r *Repository) Git() *GitService {
+	if r.git != nil {
+		return r.git
+	}
+	return NewGitService(r)
+}
+
+// recordChange queues a commit for a change to an item when automatic commits are on
+func (r *Repository) recordChange(action string, item *models.Item) {
+	if r.git == nil {
+		return
+	}
+	title := item.Title
+	if title == "" {
+		title = item.ID
+	}
+	r.git.Queue(fmt.Sprintf("%s %s: %s", action, item.Type, title))
+}
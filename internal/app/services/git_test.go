@@ -0,0 +1,177 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestGitCommitsSaves(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{Name: "test", Git: GitConfig{Enabled: true, QuietSeconds: 3600}}))
+
+	registry := NewRegistry(0)
+	defer registry.Close()
+	opened, err := registry.Open(tempDir)
+	require.NoError(t, err)
+	git := opened.Git()
+
+	// A single save gets its own message
+	note := models.NewItem(models.TypeNote, "note1")
+	note.Title = "Shopping"
+	require.NoError(t, opened.SaveItem(note, "Milk #errands"))
+	status, err := git.Status()
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, []string{"Add note: Shopping"}, status.Pending)
+	assert.NotEmpty(t, status.Changes)
+
+	require.NoError(t, git.Commit())
+	status, err = git.Status()
+	require.NoError(t, err)
+	assert.Empty(t, status.Pending)
+	assert.Empty(t, status.Changes)
+	assert.Contains(t, status.LastCommit, "Add note: Shopping")
+
+	// Rebuildable indexes are never committed
+	files, err := git.git("ls-files")
+	require.NoError(t, err)
+	assert.Contains(t, files, "notes/note1.md")
+	assert.NotContains(t, files, ".meta/tags/")
+	assert.NotContains(t, files, ".meta/links/")
+
+	// Saves within the quiet period end up in one commit
+	require.NoError(t, opened.UpdateContent(note, "Milk and bread #errands"))
+	task := models.NewItem(models.TypeTask, "task1")
+	task.Title = "Call home"
+	require.NoError(t, opened.SaveItem(task, "Call home"))
+	require.NoError(t, opened.DeleteItem(task))
+	require.NoError(t, git.Commit())
+
+	message, err := git.git("log", "-1", "--format=%B")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	assert.Equal(t, "Update 3 items", lines[0])
+	assert.Contains(t, lines, "Update note: Shopping")
+	assert.Contains(t, lines, "Add task: Call home")
+	assert.Contains(t, lines, "Delete task: Call home")
+
+	// Nothing to commit is not an error
+	require.NoError(t, git.Commit())
+}
+
+func TestGitDisabledByDefault(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	registry := NewRegistry(0)
+	defer registry.Close()
+	opened, err := registry.Open(tempDir)
+	require.NoError(t, err)
+	assert.Nil(t, opened.git)
+
+	require.NoError(t, repo.SaveItem(models.NewItem(models.TypeNote, "note1"), "Not committed"))
+	assert.NoDirExists(t, filepath.Join(tempDir, ".git"))
+}
+
+func TestGitStopCommitsPending(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{Name: "test", Git: GitConfig{Enabled: true, QuietSeconds: 3600}}))
+
+	registry := NewRegistry(0)
+	opened, err := registry.Open(tempDir)
+	require.NoError(t, err)
+	git := opened.Git()
+
+	note := models.NewItem(models.TypeNote, "note1")
+	note.Title = "Shopping"
+	require.NoError(t, opened.SaveItem(note, "Milk"))
+
+	// Stopping commits the save still waiting for its quiet period
+	git.Stop()
+	status, err := git.Status()
+	require.NoError(t, err)
+	assert.Empty(t, status.Pending)
+	assert.Empty(t, status.Changes)
+	assert.Contains(t, status.LastCommit, "Add note: Shopping")
+
+	// So does closing the registry when the server shuts down
+	require.NoError(t, opened.UpdateContent(note, "Milk and bread"))
+	registry.Close()
+	message, err := git.git("log", "-1", "--format=%s")
+	require.NoError(t, err)
+	assert.Equal(t, "Update note: Shopping", strings.TrimSpace(message))
+}
+
+func TestGitCommitsFirstScan(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{Name: "test", Git: GitConfig{Enabled: true, QuietSeconds: 3600}}))
+
+	// A note written while Vovere wasn't running is adopted by the watcher's first scan
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "offline.md"), []byte("# Offline\n\nWritten elsewhere"), 0644))
+
+	registry := NewRegistry(time.Hour)
+	opened, err := registry.Open(tempDir)
+	require.NoError(t, err)
+	git := opened.Git()
+	registry.Close()
+
+	message, err := git.git("log", "-1", "--format=%s")
+	require.NoError(t, err)
+	assert.Equal(t, "Add note: Offline", strings.TrimSpace(message))
+}
+
+func TestGitKeepsSealedTitlesOut(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{Name: "test", Git: GitConfig{Enabled: true, QuietSeconds: 3600}}))
+
+	registry := NewRegistry(0)
+	defer registry.Close()
+	opened, err := registry.Open(tempDir)
+	require.NoError(t, err)
+	git := opened.Git()
+
+	encryption := NewEncryptionService(opened)
+	require.NoError(t, encryption.Setup("correct horse"))
+	note := models.NewItem(models.TypeNote, "note1")
+	note.Title = "Secret plan"
+	require.NoError(t, opened.SaveItem(note, "Hidden"))
+	require.NoError(t, encryption.EncryptItem(note, true))
+	require.NoError(t, git.Commit())
+
+	require.NoError(t, opened.DeleteItem(note))
+	require.NoError(t, git.Commit())
+
+	message, err := git.git("log", "-1", "--format=%B")
+	require.NoError(t, err)
+	assert.Contains(t, message, "Delete note: "+lockedTitle)
+	assert.NotContains(t, message, "Secret plan")
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vovere/internal/app/models"
+)
+
+// Edge kinds connecting graph nodes
+const (
+	EdgeLink       = "link"
+	EdgeTag        = "tag"
+	EdgeWorkstream = "workstream"
+)
+
+// ErrFocusNotFound is returned when the focus item of a graph doesn't exist
+var ErrFocusNotFound = errors.New("focus item not found")
+
+// maxSharedTagItems skips shared-tag edges for tags on more items than this,
+// since every pair of items sharing a tag gets an edge
+const maxSharedTagItems = 50
+
+// GraphNode is an item in the graph
+type GraphNode struct {
+	// ID is the combined "id:type" reference of the item
+	ID     string          `json:"id"`
+	ItemID string          `json:"itemId"`
+	Type   models.ItemType `json:"type"`
+	Title  string          `json:"title"`
+	Tags   []string        `json:"tags"`
+	URL    string          `json:"url"`
+}
+
+// GraphEdge connects two nodes
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// Kind is one of EdgeLink, EdgeTag or EdgeWorkstream
+	Kind string `json:"kind"`
+	// Tags lists the shared tags of EdgeTag edges
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Graph is a set of connected items
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphFilter restricts the items included in a graph
+type GraphFilter struct {
+	// Types keeps only items of these types, all types when empty
+	Types []models.ItemType
+	// Tags keeps only items with at least one of these tags, all items when empty
+	Tags []string
+	// Focus is the ID or title of the item the graph is centered on
+	Focus string
+	// Depth is the number of hops around the focus item to include
+	Depth int
+}
+
+// GraphService builds graphs of items connected by links, tags and workstreams
+type GraphService struct {
+	repo *Repository
+}
+
+// NewGraphService creates a new graph service
+func NewGraphService(repo *Repository) *GraphService {
+	return &GraphService{
+		repo: repo,
+	}
+}
+
+// Build returns the graph of items matching a filter
+func (s *GraphService) Build(filter GraphFilter) (*Graph, error) {
+	items, err := s.filteredItems(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the focus item, which is kept even if the filters exclude it
+	focus := ""
+	if filter.Focus != "" {
+		item, ok := NewItemResolver(s.repo).Resolve(filter.Focus)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrFocusNotFound, filter.Focus)
+		}
+		focus = ItemRef(item)
+		if _, ok := items[focus]; !ok {
+			items[focus] = item
+		}
+	}
+
+	edges, err := s.edges(items)
+	if err != nil {
+		return nil, err
+	}
+
+	if focus != "" {
+		items = neighbourhood(items, edges, focus, filter.Depth)
+	}
+
+	graph := &Graph{
+		Nodes: make([]GraphNode, 0, len(items)),
+		Edges: make([]GraphEdge, 0, len(edges)),
+	}
+
+	for ref, item := range items {
+		tags := item.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:     ref,
+			ItemID: item.ID,
+			Type:   item.Type,
+			Title:  item.Title,
+			Tags:   tags,
+			URL:    fmt.Sprintf("/items/%s/%s", item.Type, item.ID),
+		})
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].ID < graph.Nodes[j].ID
+	})
+
+	for _, edge := range edges {
+		_, sourceOK := items[edge.Source]
+		_, targetOK := items[edge.Target]
+		if sourceOK && targetOK {
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+
+	return graph, nil
+}
+
+// filteredItems loads the items matching the type and tag filters, keyed by reference
+func (s *GraphService) filteredItems(filter GraphFilter) (map[string]*models.Item, error) {
+	types := filter.Types
+	if len(types) == 0 {
+		types = s.repo.ItemTypes()
+	}
+
+	items := make(map[string]*models.Item)
+	for _, itemType := range types {
+		typeItems, err := s.repo.ListItems(itemType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range typeItems {
+			if len(filter.Tags) > 0 && !hasAnyTag(item, filter.Tags) {
+				continue
+			}
+			items[ItemRef(item)] = item
+		}
+	}
+
+	return items, nil
+}
+
+// edges returns the edges between the given items
+func (s *GraphService) edges(items map[string]*models.Item) ([]GraphEdge, error) {
+	var edges []GraphEdge
+	linkService := NewLinkService(s.repo)
+
+	// Sort references so the output is stable
+	refs := make([]string, 0, len(items))
+	for ref := range items {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	tagItems := make(map[string][]string)
+	for _, ref := range refs {
+		item := items[ref]
+
+		// Wiki links
+		targets, err := linkService.Outgoing(item)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range targets {
+			if _, ok := items[target]; ok {
+				edges = append(edges, GraphEdge{Source: ref, Target: target, Kind: EdgeLink})
+			}
+		}
+
+		// Workstream membership
+		if item.Type == models.TypeWorkstream {
+			for _, member := range item.Items {
+				if _, ok := items[member]; ok && member != ref {
+					edges = append(edges, GraphEdge{Source: ref, Target: member, Kind: EdgeWorkstream})
+				}
+			}
+		}
+
+		for _, tag := range item.Tags {
+			tagItems[strings.ToLower(tag)] = append(tagItems[strings.ToLower(tag)], ref)
+		}
+	}
+
+	// Shared tags, one edge per pair of items listing every tag they share
+	type pair struct{ a, b string }
+	shared := make(map[pair][]string)
+	var pairs []pair
+	for tag, tagged := range tagItems {
+		if len(tagged) < 2 || len(tagged) > maxSharedTagItems {
+			continue
+		}
+		for i := 0; i < len(tagged); i++ {
+			for j := i + 1; j < len(tagged); j++ {
+				p := pair{tagged[i], tagged[j]}
+				if _, ok := shared[p]; !ok {
+					pairs = append(pairs, p)
+				}
+				shared[p] = append(shared[p], tag)
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].a != pairs[j].a {
+			return pairs[i].a < pairs[j].a
+		}
+		return pairs[i].b < pairs[j].b
+	})
+	for _, p := range pairs {
+		tags := shared[p]
+		sort.Strings(tags)
+		edges = append(edges, GraphEdge{Source: p.a, Target: p.b, Kind: EdgeTag, Tags: tags})
+	}
+
+	return edges, nil
+}
+
+// neighbourhood returns the items within depth hops of the focus item, ignoring edge direction
+func neighbourhood(items map[string]*models.Item, edges []GraphEdge, focus string, depth int) map[string]*models.Item {
+	adjacent := make(map[string][]string)
+	for _, edge := range edges {
+		adjacent[edge.Source] = append(adjacent[edge.Source], edge.Target)
+		adjacent[edge.Target] = append(adjacent[edge.Target], edge.Source)
+	}
+
+	visited := map[string]bool{focus: true}
+	frontier := []string{focus}
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, ref := range frontier {
+			for _, neighbour := range adjacent[ref] {
+				if !visited[neighbour] {
+					visited[neighbour] = true
+					next = append(next, neighbour)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	result := make(map[string]*models.Item, len(visited))
+	for ref := range visited {
+		result[ref] = items[ref]
+	}
+	return result
+}
+
+// hasAnyTag reports whether an item has any of the tags, ignoring case
+func hasAnyTag(item *models.Item, tags []string) bool {
+	for _, tag := range item.Tags {
+		for _, wanted := range tags {
+			if strings.EqualFold(tag, wanted) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+// edgeKinds maps "source>target" to edge kind for easier assertions
+func edgeKinds(graph *Graph) map[string]string {
+	kinds := make(map[string]string)
+	for _, edge := range graph.Edges {
+		kinds[edge.Source+">"+edge.Target] = edge.Kind
+	}
+	return kinds
+}
+
+func TestGraphBuild(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	graphService := NewGraphService(repo)
+
+	a := models.NewItem(models.TypeNote, "a")
+	a.Title = "Alpha"
+	require.NoError(t, repo.SaveItem(a, "Alpha #shared"))
+
+	b := models.NewItem(models.TypeNote, "b")
+	b.Title = "Beta"
+	require.NoError(t, repo.SaveItem(b, "Links to [[a]] #shared"))
+
+	c := models.NewItem(models.TypeTask, "c")
+	c.Title = "Gamma"
+	require.NoError(t, repo.SaveItem(c, "See [[Beta]] #other"))
+
+	ws := models.NewItem(models.TypeWorkstream, "ws")
+	ws.Title = "Stream"
+	ws.Items = []string{"c:task"}
+	require.NoError(t, repo.SaveItem(ws, ""))
+
+	graph, err := graphService.Build(GraphFilter{})
+	require.NoError(t, err)
+	assert.Len(t, graph.Nodes, 4)
+
+	kinds := edgeKinds(graph)
+	assert.Equal(t, EdgeLink, kinds["b:note>a:note"])
+	assert.Equal(t, EdgeLink, kinds["c:task>b:note"])
+	assert.Equal(t, EdgeWorkstream, kinds["ws:workstream>c:task"])
+	assert.Equal(t, EdgeTag, kinds["a:note>b:note"])
+	assert.Len(t, graph.Edges, 4)
+
+	// Type filter drops nodes and their edges
+	graph, err = graphService.Build(GraphFilter{Types: []models.ItemType{models.TypeNote}})
+	require.NoError(t, err)
+	assert.Len(t, graph.Nodes, 2)
+	assert.Len(t, graph.Edges, 2)
+
+	// Tag filter
+	graph, err = graphService.Build(GraphFilter{Tags: []string{"OTHER"}})
+	require.NoError(t, err)
+	require.Len(t, graph.Nodes, 1)
+	assert.Equal(t, "c", graph.Nodes[0].ItemID)
+	assert.Empty(t, graph.Edges)
+
+	// Focus with depth
+	graph, err = graphService.Build(GraphFilter{Focus: "ws", Depth: 1})
+	require.NoError(t, err)
+	assert.Len(t, graph.Nodes, 2)
+
+	graph, err = graphService.Build(GraphFilter{Focus: "Stream", Depth: 2})
+	require.NoError(t, err)
+	assert.Len(t, graph.Nodes, 3)
+
+	graph, err = graphService.Build(GraphFilter{Focus: "a", Depth: 0})
+	require.NoError(t, err)
+	require.Len(t, graph.Nodes, 1)
+	assert.Equal(t, "/items/note/a", graph.Nodes[0].URL)
+
+	_, err = graphService.Build(GraphFilter{Focus: "missing"})
+	assert.ErrorIs(t, err, ErrFocusNotFound)
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vovere/internal/app/models"
+)
+
+// revisionIDFormat is the sortable timestamp format used for revision IDs
+const revisionIDFormat = "20060102T150405.000000000Z"
+
+// ErrRevisionNotFound is returned when a revision doesn't exist
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Revision is a snapshot of an item's content
+type Revision struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	// Hash is the SHA-256 of the content, snapshots with the same content share a file
+	Hash  string `json:"hash"`
+	Size  int    `json:"size"`
+	Title string `json:"title"`
+}
+
+// HistoryService keeps content revisions of items under .meta/history/{type}/{id}/.
+// Each directory holds a revisions.json list and one {hash}.md file per distinct snapshot.
+type HistoryService struct {
+	repo *Repository
+}
+
+// NewHistoryService creates a new history service
+func NewHistoryService(repo *Repository) *HistoryService {
+	return &HistoryService{
+		repo: repo,
+	}
+}
+
+// Record stores the content as a new revision unless it matches the latest one,
+// then applies the retention settings from the repository config
+func (s *HistoryService) Record(item *models.Item, content string) error {
+	// Revisions are stored in plain text, encrypted items have none
+	if item.Encrypted {
+		return nil
+	}
+
+	revisions, err := s.readRevisions(item)
+	if err != nil {
+		return err
+	}
+
+	// Don't start a history with an empty item
+	if len(revisions) == 0 && content == "" {
+		return nil
+	}
+
+	hash := contentHash(content)
+	if len(revisions) > 0 && revisions[len(revisions)-1].Hash == hash {
+		return nil
+	}
+
+	dir := s.historyDir(item)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	blobPath := filepath.Join(dir, hash+".md")
+	if _, err := os.Stat(blobPath); os.IsNotExist(err) {
+		if err := writeFileAtomic(blobPath, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write revision content: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	revision := Revision{
+		ID:      now.Format(revisionIDFormat),
+		Created: now,
+		Hash:    hash,
+		Size:    len(content),
+		Title:   item.Title,
+	}
+
+	// Keep IDs unique and increasing even if the clock goes backwards
+	if len(revisions) > 0 && revision.ID <= revisions[len(revisions)-1].ID {
+		last, _ := time.Parse(revisionIDFormat, revisions[len(revisions)-1].ID)
+		revision.ID = last.Add(time.Nanosecond).Format(revisionIDFormat)
+	}
+
+	revisions = append(revisions, revision)
+
+	config, err := s.repo.Config()
+	if err != nil {
+		return err
+	}
+	revisions = applyRetention(revisions, config.History, now)
+
+	if err := s.writeRevisions(item, revisions); err != nil {
+		return err
+	}
+	return s.removeUnusedBlobs(item, revisions)
+}
+
+// Revisions returns an item's revisions, newest first
+func (s *HistoryService) Revisions(item *models.Item) ([]Revision, error) {
+	revisions, err := s.readRevisions(item)
+	if err != nil {
+		return nil, err
+	}
+
+	newestFirst := make([]Revision, len(revisions))
+	for i, revision := range revisions {
+		newestFirst[len(revisions)-1-i] = revision
+	}
+	return newestFirst, nil
+}
+
+// Revision returns a revision and its content
+func (s *HistoryService) Revision(item *models.Item, revisionID string) (*Revision, string, error) {
+	revisions, err := s.readRevisions(item)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, revision := range revisions {
+		if revision.ID != revisionID {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(s.historyDir(item), revision.Hash+".md"))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read revision content: %w", err)
+		}
+		return &revision, string(content), nil
+	}
+
+	return nil, "", fmt.Errorf("%w: %s", ErrRevisionNotFound, revisionID)
+}
+
+// Diff returns a unified diff between two revisions.
+// The special revision ID "current" refers to the content on disk.
+func (s *HistoryService) Diff(item *models.Item, fromID, toID string) (string, error) {
+	from, err := s.revisionContent(item, fromID)
+	if err != nil {
+		return "", err
+	}
+	to, err := s.revisionContent(item, toID)
+	if err != nil {
+		return "", err
+	}
+
+	return UnifiedDiff(fromID, toID, from, to), nil
+}
+
+// Restore replaces an item's content with a revision.
+// The tag index is updated from the restored content and the restore is recorded as a new revision.
+func (s *HistoryService) Restore(item *models.Item, revisionID string) error {
+	_, content, err := s.Revision(item, revisionID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.UpdateContent(item, content)
+}
+
+// RemoveItem deletes the history of an item
+func (s *HistoryService) RemoveItem(item *models.Item) error {
+	if err := os.RemoveAll(s.historyDir(item)); err != nil {
+		return fmt.Errorf("failed to delete history: %w", err)
+	}
+	return nil
+}
+
+// MoveItem moves the history of an item to another item, used when an item changes type
+func (s *HistoryService) MoveItem(from, to *models.Item) error {
+	return s.moveDir(s.historyDir(from), s.historyDir(to))
+}
+
+// moveDir moves a history directory, replacing the target; a missing source is not an error
+func (s *HistoryService) moveDir(source, target string) error {
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return fmt.Errorf("failed to clear history directory: %w", err)
+	}
+	if err := os.Rename(source, target); err != nil {
+		return fmt.Errorf("failed to move history: %w", err)
+	}
+	return nil
+}
+
+// revisionContent returns the content of a revision, or the current content for "current"
+func (s *HistoryService) revisionContent(item *models.Item, revisionID string) (string, error) {
+	if revisionID == "current" {
+		_, content, err := s.repo.LoadItem(item.ID, item.Type)
+		return content, err
+	}
+
+	_, content, err := s.Revision(item, revisionID)
+	return content, err
+}
+
+// applyRetention drops revisions beyond the configured count and age, always keeping the latest
+func applyRetention(revisions []Revision, config HistoryConfig, now time.Time) []Revision {
+	if limit := config.revisionLimit(); limit > 0 && len(revisions) > limit {
+		revisions = revisions[len(revisions)-limit:]
+	}
+
+	if config.MaxAgeDays > 0 {
+		cutoff := now.AddDate(0, 0, -config.MaxAgeDays)
+		kept := revisions[:0]
+		for i, revision := range revisions {
+			if i == len(revisions)-1 || !revision.Created.Before(cutoff) {
+				kept = append(kept, revision)
+			}
+		}
+		revisions = kept
+	}
+
+	return revisions
+}
+
+// removeUnusedBlobs deletes content files no longer referenced by any revision
+func (s *HistoryService) removeUnusedBlobs(item *models.Item, revisions []Revision) error {
+	used := make(map[string]bool, len(revisions))
+	for _, revision := range revisions {
+		used[revision.Hash+".md"] = true
+	}
+
+	entries, err := os.ReadDir(s.historyDir(item))
+	if err != nil {
+		return fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".md" || used[entry.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.historyDir(item), entry.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete revision content: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// readRevisions loads an item's revisions, oldest first
+func (s *HistoryService) readRevisions(item *models.Item) ([]Revision, error) {
+	data, err := os.ReadFile(filepath.Join(s.historyDir(item), "revisions.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read revisions: %w", err)
+	}
+
+	var revisions []Revision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to parse revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// writeRevisions saves an item's revisions
+func (s *HistoryService) writeRevisions(item *models.Item, revisions []Revision) error {
+	data, err := json.MarshalIndent(revisions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal revisions: %w", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(s.historyDir(item), "revisions.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write revisions: %w", err)
+	}
+	return nil
+}
+
+// historyDir returns the directory holding an item's revisions
+func (s *HistoryService) historyDir(item *models.Item) string {
+	return filepath.Join(s.repo.BasePath(), ".meta", "history", string(item.Type), item.ID)
+}
+
+// contentHash returns the hex encoded SHA-256 of content
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
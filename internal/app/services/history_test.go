@@ -0,0 +1,135 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestHistoryRecordsRevisions(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	historyService := NewHistoryService(repo)
+
+	item := models.NewItem(models.TypeNote, "note1")
+	item.Title = "Note"
+	require.NoError(t, repo.SaveItem(item, "First draft #draft"))
+	require.NoError(t, repo.UpdateContent(item, "Second draft #draft"))
+
+	// Metadata-only saves and identical content don't create revisions
+	require.NoError(t, repo.SaveItem(item, ""))
+	require.NoError(t, repo.UpdateContent(item, "Second draft #draft"))
+
+	// Going back to earlier content is a new revision sharing the stored snapshot
+	require.NoError(t, repo.UpdateContent(item, "First draft #draft"))
+
+	revisions, err := historyService.Revisions(item)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	assert.Equal(t, revisions[0].Hash, revisions[2].Hash)
+	assert.True(t, revisions[0].ID > revisions[1].ID, "revisions are newest first")
+
+	historyDir := filepath.Join(tempDir, ".meta", "history", "note", "note1")
+	entries, err := os.ReadDir(historyDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "revisions.json and two distinct snapshots")
+
+	_, content, err := historyService.Revision(item, revisions[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Second draft #draft", content)
+
+	_, _, err = historyService.Revision(item, "missing")
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+
+	diff, err := historyService.Diff(item, revisions[1].ID, "current")
+	require.NoError(t, err)
+	assert.Contains(t, diff, "-Second draft #draft\n+First draft #draft\n")
+
+	// Items without content don't get a history
+	empty := models.NewItem(models.TypeBookmark, "bm1")
+	require.NoError(t, repo.SaveItem(empty, ""))
+	revisions, err = historyService.Revisions(empty)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	// Deleting the item removes its history
+	require.NoError(t, repo.DeleteItem(item))
+	assert.NoDirExists(t, historyDir)
+}
+
+func TestHistoryRetention(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{
+		Name:    "test",
+		History: HistoryConfig{MaxRevisions: 2},
+	}))
+
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "v1"))
+	require.NoError(t, repo.UpdateContent(item, "v2"))
+	require.NoError(t, repo.UpdateContent(item, "v3"))
+
+	revisions, err := NewHistoryService(repo).Revisions(item)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+
+	_, err = os.Stat(filepath.Join(tempDir, ".meta", "history", "note", "note1", contentHash("v1")+".md"))
+	assert.True(t, os.IsNotExist(err), "pruned snapshots are deleted")
+
+	// Age based retention always keeps the latest revision
+	now := time.Now().UTC()
+	old := []Revision{
+		{ID: "1", Created: now.AddDate(0, 0, -10)},
+		{ID: "2", Created: now.AddDate(0, 0, -5)},
+		{ID: "3", Created: now.AddDate(0, 0, -1)},
+	}
+	kept := applyRetention(old, HistoryConfig{MaxRevisions: -1, MaxAgeDays: 7}, now)
+	assert.Len(t, kept, 2)
+
+	kept = applyRetention([]Revision{{ID: "1", Created: now.AddDate(-1, 0, 0)}}, HistoryConfig{MaxAgeDays: 1}, now)
+	assert.Len(t, kept, 1)
+}
+
+func TestHistoryRestoreUpdatesTags(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	historyService := NewHistoryService(repo)
+	tagService := NewTagService(repo)
+
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "Original #keep"))
+	require.NoError(t, repo.UpdateContent(item, "Broken autosave #oops"))
+
+	revisions, err := historyService.Revisions(item)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+
+	require.NoError(t, historyService.Restore(item, revisions[1].ID))
+
+	_, content, err := repo.LoadItem(item.ID, item.Type)
+	require.NoError(t, err)
+	assert.Equal(t, "Original #keep", content)
+	assert.Equal(t, []string{"keep"}, item.Tags)
+
+	items, err := tagService.GetItemsByTag("keep")
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	items, err = tagService.GetItemsByTag("oops")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	// The restore itself is recorded
+	revisions, err = historyService.Revisions(item)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 3)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"vovere/internal/app/models"
+)
+
+// itemIDFormat is the timestamp format of item IDs, e.g. 20240102150405
+const itemIDFormat = "20060102150405"
+
+// IDAllocator hands out item IDs that are unique across every item type of a repository.
+// IDs are the creation second, with a -001, -002... suffix for further items created in
+// the same second, so they sort by creation time and older timestamp IDs stay valid.
+type IDAllocator struct {
+	repo *Repository
+}
+
+// NewIDAllocator creates a new ID allocator
+func NewIDAllocator(repo *Repository) *IDAllocator {
+	return &IDAllocator{
+		repo: repo,
+	}
+}
+
+// allocate returns an ID for an item created at a time that no item, content file or trashed
+// item uses. The caller must hold the save lock until the item is saved, or the ID may be
+// handed out twice.
+func (a *IDAllocator) allocate(created time.Time) (string, error) {
+	base := created.UTC().Format(itemIDFormat)
+
+	for n := 0; ; n++ {
+		id := base
+		if n > 0 {
+			id = fmt.Sprintf("%s-%03d", base, n)
+		}
+
+		taken, err := a.taken(id)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+}
+
+// taken reports whether any item type already uses an ID
+func (a *IDAllocator) taken(id string) (bool, error) {
+	trash := NewTrashService(a.repo)
+	for _, itemType := range a.repo.ItemTypes() {
+		item := &models.Item{ID: id, Type: itemType}
+		for _, path := range []string{a.repo.getMetaPath(item), a.repo.getContentPath(item), trash.entryDir(itemType, id)} {
+			if _, err := os.Stat(path); err == nil {
+				return true, nil
+			} else if !os.IsNotExist(err) {
+				return false, fmt.Errorf("failed to check item ID: %w", err)
+			}
+		}
+	}
+	return false, nil
+}
+
+// CreateItem saves a new item under a freshly allocated ID, which is set on the item.
+// The ID follows the item's creation time, so imported items can keep their original dates.
+func (r *Repository) CreateItem(item *models.Item, content string) error {
+	unlock := r.lock()
+	defer unlock()
+
+	if item.Created.IsZero() {
+		item.Created = time.Now().UTC()
+	}
+	id, err := NewIDAllocator(r).allocate(item.Created)
+	if err != nil {
+		return err
+	}
+	item.ID = id
+
+	// Tags come from the content unless they were set by the caller
+	tags := item.Tags
+	if content != "" && len(item.Tags) == 0 {
+		item.Tags = NewTagService(r).ExtractTags(content)
+	}
+
+	if err := r.saveItem(item, content, content != "", nil); err != nil {
+		item.ID = ""
+		item.Tags = tags
+		return err
+	}
+	return nil
+}
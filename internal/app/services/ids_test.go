@@ -0,0 +1,81 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestIDAllocatorAvoidsCollisions(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	allocator := NewIDAllocator(repo)
+
+	id, err := allocator.allocate(now)
+	require.NoError(t, err)
+	assert.Equal(t, "20240102150405", id)
+
+	// Existing timestamp IDs of any type are skipped
+	require.NoError(t, repo.SaveItem(models.NewItem(models.TypeTask, "20240102150405"), ""))
+	id, err = allocator.allocate(now)
+	require.NoError(t, err)
+	assert.Equal(t, "20240102150405-001", id)
+
+	// So are trashed items, which could be restored
+	trashed := models.NewItem(models.TypeNote, "20240102150405-001")
+	require.NoError(t, repo.SaveItem(trashed, "Trashed"))
+	require.NoError(t, NewTrashService(repo).Trash(trashed))
+	id, err = allocator.allocate(now)
+	require.NoError(t, err)
+	assert.Equal(t, "20240102150405-002", id)
+
+	// IDs sort by creation time
+	ids := []string{"20240102150406", "20240102150405-002", "20240102150405", "20240102150405-001"}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"20240102150405", "20240102150405-001", "20240102150405-002", "20240102150406"}, ids)
+}
+
+func TestCreateItemConcurrently(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	const count = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			itemType := models.TypeNote
+			if i%2 == 1 {
+				itemType = models.TypeTask
+			}
+			errs <- repo.CreateItem(models.NewItem(itemType, ""), "Created in bulk #bulk")
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	ids := make(map[string]bool)
+	for _, itemType := range []models.ItemType{models.TypeNote, models.TypeTask} {
+		items, err := repo.ListItems(itemType)
+		require.NoError(t, err)
+		for _, item := range items {
+			assert.False(t, ids[item.ID], "duplicate ID %s", item.ID)
+			ids[item.ID] = true
+			assert.Equal(t, []string{"bulk"}, item.Tags)
+		}
+	}
+	assert.Len(t, ids, count)
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"vovere/internal/app/models"
+	"vovere/internal/markdown"
+)
+
+// importH1 matches the first level one header of a markdown file
+var importH1 = regexp.MustCompile(`(?m)^#\s+(.+?)\s*#*\s*$`)
+
+// ImportReport is the result of importing a directory
+type ImportReport struct {
+	Notes      int              `json:"notes"`
+	Files      int              `json:"files"`
+	Imported   []ImportedFile   `json:"imported"`
+	Unresolved []UnresolvedLink `json:"unresolved"`
+	Skipped    []SkippedFile    `json:"skipped"`
+}
+
+// ImportedFile is a file that became an item
+type ImportedFile struct {
+	// Source is the file path relative to the imported directory
+	Source string `json:"source"`
+	// Item is the combined "id:type" of the new item
+	Item  string `json:"item"`
+	Title string `json:"title"`
+}
+
+// UnresolvedLink is a wiki link that matched no imported file or existing item.
+// It is kept as written.
+type UnresolvedLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// SkippedFile is a file that wasn't imported
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// importedNote is a note created from a markdown file, waiting for its links to be rewritten
+type importedNote struct {
+	source  string
+	item    *models.Item
+	content string
+}
+
+// importSource is a file found in the imported directory
+type importSource struct {
+	rel     string
+	path    string
+	modTime time.Time
+}
+
+// ImportService imports Obsidian vaults and folders of markdown files
+type ImportService struct {
+	repo *Repository
+}
+
+// NewImportService creates a new import service
+func NewImportService(repo *Repository) *ImportService {
+	return &ImportService{
+		repo: repo,
+	}
+}
+
+// ImportDirectory imports every markdown file under dir as a note and every other file as a file item.
+// Items are created in the order the files were last modified, with IDs from those times.
+// Wiki links between imported files are rewritten to [[id|title]] once every file is in.
+// Hidden files and directories, like .obsidian and .trash, are left out.
+func (s *ImportService) ImportDirectory(dir string) (*ImportReport, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	report := &ImportReport{
+		Imported:   []ImportedFile{},
+		Unresolved: []UnresolvedLink{},
+		Skipped:    []SkippedFile{},
+	}
+
+	sources, err := s.walk(dir, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// Targets are matched by path and by name, both without case and without the .md extension
+	targets := make(map[string]*models.Item)
+	byName := make(map[string]*models.Item)
+	addTarget := func(rel string, item *models.Item) {
+		key := strings.ToLower(rel)
+		if ext := path.Ext(key); ext == ".md" || ext == ".markdown" {
+			key = strings.TrimSuffix(key, ext)
+		}
+		targets[key] = item
+		// Files with the same name are told apart by path, a bare name goes to the first one
+		if name := path.Base(key); byName[name] == nil {
+			byName[name] = item
+		}
+	}
+
+	var notes []importedNote
+	files := NewFileService(s.repo)
+	for _, source := range sources {
+		if isMarkdownFile(source.rel) {
+			note, err := s.importNote(source)
+			if err != nil {
+				report.Skipped = append(report.Skipped, SkippedFile{Path: source.rel, Reason: err.Error()})
+				continue
+			}
+			notes = append(notes, *note)
+			addTarget(source.rel, note.item)
+			report.Notes++
+			report.Imported = append(report.Imported, ImportedFile{Source: source.rel, Item: ItemRef(note.item), Title: note.item.Title})
+			continue
+		}
+
+		file, err := os.Open(source.path)
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedFile{Path: source.rel, Reason: err.Error()})
+			continue
+		}
+		item, err := files.Add(path.Base(source.rel), source.modTime, file)
+		file.Close()
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedFile{Path: source.rel, Reason: err.Error()})
+			continue
+		}
+		addTarget(source.rel, item)
+		report.Files++
+		report.Imported = append(report.Imported, ImportedFile{Source: source.rel, Item: ItemRef(item), Title: item.Title})
+	}
+
+	// Links are rewritten last, when every target has an ID
+	resolver := NewItemResolver(s.repo)
+	resolve := func(from, target string) (*models.Item, bool) {
+		key := strings.ToLower(strings.TrimPrefix(target, "/"))
+		if ext := path.Ext(key); ext == ".md" || ext == ".markdown" {
+			key = strings.TrimSuffix(key, ext)
+		}
+		for _, candidate := range []string{key, path.Join(path.Dir(strings.ToLower(from)), key)} {
+			if item, ok := targets[candidate]; ok {
+				return item, true
+			}
+		}
+		if !strings.Contains(key, "/") {
+			if item, ok := byName[key]; ok {
+				return item, true
+			}
+		}
+		// Links to items already in the repository work as well
+		return resolver.Resolve(target)
+	}
+
+	for _, note := range notes {
+		content, unresolved := rewriteImportLinks(note.content, func(target string) (*models.Item, bool) {
+			return resolve(note.source, target)
+		})
+		for _, target := range unresolved {
+			report.Unresolved = append(report.Unresolved, UnresolvedLink{Source: note.source, Target: target})
+		}
+		if content == note.content {
+			continue
+		}
+		if err := s.repo.UpdateContent(note.item, content); err != nil {
+			report.Skipped = append(report.Skipped, SkippedFile{Path: note.source, Reason: fmt.Sprintf("imported, but links weren't rewritten: %v", err)})
+		}
+	}
+
+	return report, nil
+}
+
+// walk lists the files to import, oldest first, and reports the ones it leaves out
+func (s *ImportService) walk(dir string, report *ImportReport) ([]importSource, error) {
+	var sources []importSource
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			report.Skipped = append(report.Skipped, SkippedFile{Path: rel, Reason: "not a regular file"})
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedFile{Path: rel, Reason: err.Error()})
+			return nil
+		}
+		sources = append(sources, importSource{rel: rel, path: p, modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import directory: %w", err)
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		if !sources[i].modTime.Equal(sources[j].modTime) {
+			return sources[i].modTime.Before(sources[j].modTime)
+		}
+		return sources[i].rel < sources[j].rel
+	})
+	return sources, nil
+}
+
+// importNote creates a note from a markdown file, keeping its content as written
+func (s *ImportService) importNote(source importSource) (*importedNote, error) {
+	data, err := os.ReadFile(source.path)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("not valid UTF-8")
+	}
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	item := models.NewItem(models.TypeNote, "")
+	item.Created = source.modTime.UTC()
+	item.Title = importTitle(source.rel, content)
+	if err := s.repo.CreateItem(item, content); err != nil {
+		return nil, err
+	}
+	return &importedNote{source: source.rel, item: item, content: content}, nil
+}
+
+// importTitle returns the title of an imported note: its front matter title,
+// its first H1, or else its file name
+func importTitle(rel, content string) string {
+	if fm := parseFrontMatter(content); fm != nil {
+		if title := fm.str("title"); title != "" {
+			return title
+		}
+	}
+	if matches := importH1.FindStringSubmatch(markdown.StripFrontMatter(content)); matches != nil {
+		return matches[1]
+	}
+	name := path.Base(rel)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// rewriteImportLinks rewrites the wiki links of content to [[id|title]] with resolve.
+// Heading and block references are dropped, Vovere links to whole items, and embeds
+// become plain links. It returns the new content and the targets it couldn't resolve.
+func rewriteImportLinks(content string, resolve func(target string) (*models.Item, bool)) (string, []string) {
+	links := markdown.ParseWikiLinks(content)
+	if len(links) == 0 {
+		return content, nil
+	}
+
+	var result strings.Builder
+	var unresolved []string
+	i := 0
+	for _, link := range links {
+		// Obsidian escapes the pipe of links inside tables
+		target := strings.TrimSpace(strings.TrimSuffix(link.Target, `\`))
+		if cut, _, found := strings.Cut(target, "#"); found {
+			target = strings.TrimSpace(cut)
+		}
+		if target == "" {
+			continue // A link within the same note
+		}
+
+		item, ok := resolve(target)
+		if !ok {
+			if !contains(unresolved, target) {
+				unresolved = append(unresolved, target)
+			}
+			continue
+		}
+
+		start := link.Start
+		if start > 0 && content[start-1] == '!' {
+			start--
+		}
+		label := link.Label
+		if label == "" {
+			label = item.Title
+		}
+		result.WriteString(content[i:start])
+		if label == "" || label == item.ID {
+			fmt.Fprintf(&result, "[[%s]]", item.ID)
+		} else {
+			fmt.Fprintf(&result, "[[%s|%s]]", item.ID, label)
+		}
+		i = link.End
+	}
+	result.WriteString(content[i:])
+
+	return result.String(), unresolved
+}
+
+// isMarkdownFile reports whether a file is imported as a note
+func isMarkdownFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
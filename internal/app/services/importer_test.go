@@ -0,0 +1,79 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+// writeVaultFile writes a file of a test vault with a modification time
+func writeVaultFile(t *testing.T, dir, name, content string, modTime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestImportDirectory(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	vault := t.TempDir()
+	base := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	writeVaultFile(t, vault, "Projects/Garden.md", "# Garden plans\n\nSee [[Seeds#Spring]] and ![[sketch.png]]. #garden", base)
+	writeVaultFile(t, vault, "Seeds.md", "---\ntags: [plants]\n---\nList for [[Projects/Garden|the garden]] and [[Missing note]].", base.Add(time.Hour))
+	writeVaultFile(t, vault, "attachments/sketch.png", "png", base.Add(2*time.Hour))
+	writeVaultFile(t, vault, ".obsidian/app.json", "{}", base)
+
+	report, err := NewImportService(repo).ImportDirectory(vault)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Notes)
+	assert.Equal(t, 1, report.Files)
+	assert.Empty(t, report.Skipped)
+	assert.Equal(t, []UnresolvedLink{{Source: "Seeds.md", Target: "Missing note"}}, report.Unresolved)
+
+	// IDs come from the modification times, titles from the H1 or the file name
+	garden, gardenContent, err := repo.LoadItem("20220304050607", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Garden plans", garden.Title)
+	seeds, seedsContent, err := repo.LoadItem("20220304060607", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Seeds", seeds.Title)
+	sketch, _, err := repo.LoadItem("20220304070607", models.TypeFile)
+	require.NoError(t, err)
+	assert.FileExists(t, NewFileService(repo).Path(sketch))
+
+	// Links point at the new IDs, unresolved ones are left as written
+	assert.Equal(t, "# Garden plans\n\nSee [[20220304060607|Seeds]] and [[20220304070607|sketch.png]]. #garden", gardenContent)
+	assert.True(t, strings.HasSuffix(seedsContent, "List for [[20220304050607|the garden]] and [[Missing note]]."))
+
+	// Tags come from hashtags and front matter
+	assert.Equal(t, []string{"garden"}, garden.Tags)
+	assert.Equal(t, []string{"plants"}, seeds.Tags)
+
+	backlinks, err := NewLinkService(repo).Backlinks(seeds)
+	require.NoError(t, err)
+	assert.Len(t, backlinks, 1)
+}
+
+func TestImportSkipsUnreadableFiles(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	vault := t.TempDir()
+	writeVaultFile(t, vault, "binary.md", "\xff\xfe", time.Now())
+
+	report, err := NewImportService(repo).ImportDirectory(vault)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Notes)
+	require.Len(t, report.Skipped, 1)
+	assert.Equal(t, "binary.md", report.Skipped[0].Path)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"vovere/internal/app/models"
+)
+
+// InboxService finds unprocessed items and applies triage actions to them
+type InboxService struct {
+	repo *Repository
+}
+
+// NewInboxService creates a new inbox service
+func NewInboxService(repo *Repository) *InboxService {
+	return &InboxService{
+		repo: repo,
+	}
+}
+
+// IsUnprocessed reports whether an item still needs triage.
+// An item is unprocessed until it has both a title and tags, unless it was archived.
+// Items with a sealed title and tags are left out, their stored metadata is a placeholder.
+func IsUnprocessed(item *models.Item) bool {
+	if item.Archived || (item.Encrypted && item.SealMetadata) {
+		return false
+	}
+	untitled := item.Title == "" || item.Title == item.ID
+	return untitled || len(item.Tags) == 0
+}
+
+// Items returns all unprocessed items, most recently modified first
+func (s *InboxService) Items() ([]*models.Item, error) {
+	var items []*models.Item
+	for _, itemType := range s.repo.ItemTypes() {
+		typeItems, err := s.repo.ListItems(itemType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range typeItems {
+			if IsUnprocessed(item) {
+				items = append(items, item)
+			}
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Modified.After(items[j].Modified)
+	})
+
+	return items, nil
+}
+
+// Count returns the number of unprocessed items
+func (s *InboxService) Count() (int, error) {
+	items, err := s.Items()
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// AddTags appends hashtags to an item's content so they survive later edits
+func (s *InboxService) AddTags(item *models.Item, tags []string) error {
+	var hashtags []string
+	for _, tag := range tags {
+		tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+		if tag == "" {
+			continue
+		}
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+		if !contains(item.Tags, tag) {
+			hashtags = append(hashtags, "#"+tag)
+		}
+	}
+
+	if len(hashtags) == 0 {
+		return nil
+	}
+
+	_, content, err := s.repo.LoadItem(item.ID, item.Type)
+	if err != nil {
+		return err
+	}
+
+	// Keep the hashtags on their own line at the end of the content
+	content = strings.TrimRight(content, "\n")
+	if content != "" {
+		content += "\n\n"
+	}
+	content += strings.Join(hashtags, " ") + "\n"
+
+	// Tags are extracted from content, keep any existing ones that aren't written in it
+	extracted := NewTagService(s.repo).ExtractTags(content)
+	for _, tag := range item.Tags {
+		if !contains(extracted, tag) {
+			content = strings.TrimRight(content, "\n") + " #" + tag + "\n"
+		}
+	}
+
+	return s.repo.UpdateContent(item, content)
+}
+
+// SetTitle sets an item's title
+func (s *InboxService) SetTitle(item *models.Item, title string) error {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return fmt.Errorf("title cannot be empty")
+	}
+
+	item.Title = title
+	return s.repo.SaveItem(item, "")
+}
+
+// ConvertType changes an item into another type
+func (s *InboxService) ConvertType(item *models.Item, newType models.ItemType) error {
+	if newType == models.TypeFile {
+		return ErrFileConversion
+	}
+	if err := s.repo.ValidateItemType(newType); err != nil {
+		return err
+	}
+
+	return s.repo.ConvertItem(item, newType)
+}
+
+// MoveToWorkstream adds an item to a workstream
+func (s *InboxService) MoveToWorkstream(item *models.Item, workstreamID string) error {
+	if item.Type == models.TypeWorkstream && item.ID == workstreamID {
+		return fmt.Errorf("a workstream cannot contain itself")
+	}
+
+	workstream, _, err := s.repo.LoadItem(workstreamID, models.TypeWorkstream)
+	if err != nil {
+		return fmt.Errorf("workstream not found: %w", err)
+	}
+
+	ref := ItemRef(item)
+	if contains(workstream.Items, ref) {
+		return nil
+	}
+
+	workstream.Items = append(workstream.Items, ref)
+	return s.repo.SaveItem(workstream, "")
+}
+
+// Archive marks an item as archived so it leaves the inbox
+func (s *InboxService) Archive(item *models.Item) error {
+	item.Archived = true
+	return s.repo.SaveItem(item, "")
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestIsUnprocessed(t *testing.T) {
+	item := models.NewItem(models.TypeNote, "20250101120000")
+	assert.True(t, IsUnprocessed(item))
+
+	item.Title = item.ID
+	item.Tags = []string{"work"}
+	assert.True(t, IsUnprocessed(item), "title equal to the ID counts as untitled")
+
+	item.Title = "Meeting notes"
+	assert.False(t, IsUnprocessed(item))
+
+	item.Tags = nil
+	assert.True(t, IsUnprocessed(item))
+
+	item.Archived = true
+	assert.False(t, IsUnprocessed(item))
+}
+
+func TestInboxItems(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	inbox := NewInboxService(repo)
+
+	untitled := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(untitled, "Quick thought #idea"))
+
+	untagged := models.NewItem(models.TypeTask, "task1")
+	untagged.Title = "Call the bank"
+	require.NoError(t, repo.SaveItem(untagged, "Before Friday"))
+
+	processed := models.NewItem(models.TypeNote, "note2")
+	processed.Title = "Reading list"
+	require.NoError(t, repo.SaveItem(processed, "Books #reading"))
+
+	items, err := inbox.Items()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	count, err := inbox.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	require.NoError(t, inbox.Archive(untagged))
+	count, err = inbox.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestInboxAddTagsAndTitle(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	inbox := NewInboxService(repo)
+	tagService := NewTagService(repo)
+
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "Some text"))
+
+	require.NoError(t, inbox.AddTags(item, []string{"#work", "urgent", ""}))
+	assert.ElementsMatch(t, []string{"work", "urgent"}, item.Tags)
+
+	_, content, err := repo.LoadItem(item.ID, item.Type)
+	require.NoError(t, err)
+	assert.Contains(t, content, "#work #urgent")
+
+	items, err := tagService.GetItemsByTag("urgent")
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	// Existing tags are kept and duplicates ignored
+	require.NoError(t, inbox.AddTags(item, []string{"work", "later"}))
+	assert.ElementsMatch(t, []string{"work", "urgent", "later"}, item.Tags)
+
+	assert.Error(t, inbox.AddTags(item, []string{"two words"}))
+
+	assert.Error(t, inbox.SetTitle(item, "  "))
+	require.NoError(t, inbox.SetTitle(item, "Plans"))
+	assert.False(t, IsUnprocessed(item))
+
+	loaded, _, err := repo.LoadItem(item.ID, item.Type)
+	require.NoError(t, err)
+	assert.Equal(t, "Plans", loaded.Title)
+	assert.ElementsMatch(t, []string{"work", "urgent", "later"}, loaded.Tags)
+}
+
+func TestInboxConvertType(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	inbox := NewInboxService(repo)
+	tagService := NewTagService(repo)
+
+	workstream := models.NewItem(models.TypeWorkstream, "ws1")
+	workstream.Title = "Home"
+	workstream.Items = []string{"note1:note"}
+	require.NoError(t, repo.SaveItem(workstream, ""))
+
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "Fix the sink #home"))
+
+	assert.Error(t, inbox.ConvertType(item, models.TypeFile))
+	require.NoError(t, inbox.ConvertType(item, models.TypeTask))
+
+	assert.Equal(t, models.TypeTask, item.Type)
+	assert.Equal(t, models.TaskStatusTodo, item.Status)
+
+	_, err := os.Stat(filepath.Join(tempDir, "notes", "note1.md"))
+	assert.True(t, os.IsNotExist(err))
+	_, content, err := repo.LoadItem("note1", models.TypeTask)
+	require.NoError(t, err)
+	assert.Equal(t, "Fix the sink #home", content)
+
+	items, err := tagService.GetItemsByTag("home")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, models.TypeTask, items[0].Type)
+
+	workstream, _, err = repo.LoadItem("ws1", models.TypeWorkstream)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"note1:task"}, workstream.Items)
+}
+
+func TestInboxConvertToDeclaredType(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	config, err := repo.Config()
+	require.NoError(t, err)
+	config.Types = []TypeConfig{{Name: "meeting"}}
+	require.NoError(t, repo.SaveConfig(config))
+
+	inbox := NewInboxService(repo)
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "Weekly sync"))
+
+	assert.ErrorIs(t, inbox.ConvertType(item, "recipe"), ErrInvalidItemType)
+	require.NoError(t, inbox.ConvertType(item, "meeting"))
+
+	_, content, err := repo.LoadItem("note1", "meeting")
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly sync", content)
+}
+
+func TestInboxMoveToWorkstream(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	inbox := NewInboxService(repo)
+
+	workstream := models.NewItem(models.TypeWorkstream, "ws1")
+	workstream.Title = "Garden"
+	require.NoError(t, repo.SaveItem(workstream, ""))
+
+	item := models.NewItem(models.TypeBookmark, "bm1")
+	require.NoError(t, repo.SaveItem(item, ""))
+
+	require.NoError(t, inbox.MoveToWorkstream(item, "ws1"))
+	require.NoError(t, inbox.MoveToWorkstream(item, "ws1"))
+
+	loaded, _, err := repo.LoadItem("ws1", models.TypeWorkstream)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bm1:bookmark"}, loaded.Items)
+
+	assert.Error(t, inbox.MoveToWorkstream(item, "missing"))
+	assert.Error(t, inbox.MoveToWorkstream(workstream, "ws1"))
+}
@@ -0,0 +1,608 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"vovere/internal/app/models"
+	"vovere/internal/markdown"
+)
+
+// maxContextLength caps the length of the context stored with a backlink
+const maxContextLength = 240
+
+// Backlink is a reference from another item to the current one
+type Backlink struct {
+	// Source is the combined "id:type" of the linking item
+	Source string `json:"source"`
+	// Context is the sentence around the link, with wiki links replaced by their text
+	Context string `json:"context"`
+}
+
+// LinkService maintains the wiki-link index under .meta/links.
+// Outgoing files list the items an item links to and incoming files
+// list the backlinks of an item, so both directions are cheap to read.
+// Links that match no item yet are kept in unresolved.json until one is saved.
+type LinkService struct {
+	repo *Repository
+}
+
+// NewLinkService creates a new link service
+func NewLinkService(repo *Repository) *LinkService {
+	return &LinkService{
+		repo: repo,
+	}
+}
+
+// UpdateItemLinks updates the link index for an item from its content
+func (s *LinkService) UpdateItemLinks(item *models.Item, content string) error {
+	source := ItemRef(item)
+
+	previous, err := s.readOutgoing(item)
+	if err != nil {
+		return err
+	}
+
+	links, unresolved := s.extractLinks(item, content)
+	if strings.Contains(content, "[[") {
+		if err := s.updatePending(source, unresolved); err != nil {
+			return err
+		}
+	}
+
+	// Nothing linked before or now, skip touching the index
+	if len(previous) == 0 && len(links) == 0 {
+		return nil
+	}
+
+	// Remove backlinks from items no longer linked
+	for _, target := range previous {
+		if _, ok := links[target]; !ok {
+			if err := s.removeBacklink(target, source); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Add or refresh backlinks to the current targets
+	targets := make([]string, 0, len(links))
+	for _, link := range orderedLinks(links) {
+		targets = append(targets, link.target)
+		if err := s.addBacklink(link.target, Backlink{Source: source, Context: link.context}); err != nil {
+			return err
+		}
+	}
+
+	return s.writeOutgoing(item, targets)
+}
+
+// RemoveItem removes an item and its outgoing links from the link index
+func (s *LinkService) RemoveItem(item *models.Item) error {
+	previous, err := s.readOutgoing(item)
+	if err != nil {
+		return err
+	}
+
+	source := ItemRef(item)
+	for _, target := range previous {
+		if err := s.removeBacklink(target, source); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(s.outgoingPath(item.ID, item.Type)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete outgoing links file: %w", err)
+	}
+	if err := os.Remove(s.incomingPath(item.ID, item.Type)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete incoming links file: %w", err)
+	}
+
+	return nil
+}
+
+// Backlinks returns the backlinks of an item, skipping sources that no longer exist
+func (s *LinkService) Backlinks(item *models.Item) ([]Backlink, error) {
+	backlinks, err := s.readIncoming(ItemRef(item))
+	if err != nil {
+		return nil, err
+	}
+
+	valid := make([]Backlink, 0, len(backlinks))
+	for _, backlink := range backlinks {
+		id, itemType, ok := parseItemRef(backlink.Source)
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(s.repo.getMetaPath(&models.Item{ID: id, Type: itemType})); err == nil {
+			valid = append(valid, backlink)
+		}
+	}
+
+	return valid, nil
+}
+
+// Outgoing returns the combined "id:type" references an item links to
+func (s *LinkService) Outgoing(item *models.Item) ([]string, error) {
+	return s.readOutgoing(item)
+}
+
+// ResolvePending indexes the links waiting for an item, made to its ID or title
+// before it was created or renamed
+func (s *LinkService) ResolvePending(item *models.Item) error {
+	pending, err := s.readPending()
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	var sources []string
+	for _, key := range []string{pendingKey(item.ID), pendingKey(item.Title)} {
+		if key == "" {
+			continue
+		}
+		sources = append(sources, pending[key]...)
+		delete(pending, key)
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+	// Sources whose links still don't resolve add themselves back
+	if err := s.writePending(pending); err != nil {
+		return err
+	}
+
+	self := ItemRef(item)
+	for _, ref := range sources {
+		id, itemType, ok := parseItemRef(ref)
+		if !ok || ref == self {
+			continue
+		}
+		source, content, err := s.repo.LoadItem(id, itemType)
+		if err != nil {
+			continue // The source is gone
+		}
+		if err := s.UpdateItemLinks(source, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rebuild recreates the link index from the content of every item.
+// Links to items that didn't exist when their source was saved are picked up here.
+func (s *LinkService) Rebuild() error {
+	linksDir := filepath.Join(s.repo.BasePath(), ".meta", "links")
+	if err := os.RemoveAll(linksDir); err != nil {
+		return fmt.Errorf("failed to clear link index: %w", err)
+	}
+
+	for _, itemType := range s.repo.ItemTypes() {
+		items, err := s.repo.ListItems(itemType)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			_, content, err := s.repo.LoadItem(item.ID, item.Type)
+			if err != nil {
+				continue
+			}
+			if err := s.UpdateItemLinks(item, content); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolvedLink is a link target found in content with the context of its first occurrence
+type resolvedLink struct {
+	target  string
+	context string
+	order   int
+}
+
+// extractLinks resolves the wiki links in content, keyed by target reference, and returns
+// the targets that match no item. Links inside fenced code blocks and links to the item
+// itself are ignored.
+func (s *LinkService) extractLinks(item *models.Item, content string) (map[string]resolvedLink, []string) {
+	links := make(map[string]resolvedLink)
+	var unresolved []string
+	// Links of encrypted items would reveal what they are about
+	if item.Encrypted || !strings.Contains(content, "[[") {
+		return links, unresolved
+	}
+
+	resolver := NewItemResolver(s.repo)
+	self := ItemRef(item)
+	inFence := false
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		for _, link := range markdown.ParseWikiLinks(line) {
+			target, ok := resolver.Resolve(link.Target)
+			if !ok {
+				if key := pendingKey(link.Target); key != "" && !contains(unresolved, key) {
+					unresolved = append(unresolved, key)
+				}
+				continue
+			}
+			ref := ItemRef(target)
+			if ref == self {
+				continue
+			}
+			if _, seen := links[ref]; seen {
+				continue
+			}
+			links[ref] = resolvedLink{
+				target:  ref,
+				context: linkContext(line, link, resolver),
+				order:   len(links),
+			}
+		}
+	}
+
+	return links, unresolved
+}
+
+// orderedLinks returns links in the order they appear in the content
+func orderedLinks(links map[string]resolvedLink) []resolvedLink {
+	ordered := make([]resolvedLink, len(links))
+	for _, link := range links {
+		ordered[link.order] = link
+	}
+	return ordered
+}
+
+// listMarkerRegex matches heading, quote and list markers at the start of a line
+var listMarkerRegex = regexp.MustCompile(`^\s*(#+|>+|[-*+]|\d+[.)])\s+(\[[ xX]\]\s+)?`)
+
+// linkContext returns the sentence of a line surrounding a link
+func linkContext(line string, link markdown.WikiLink, resolver *ItemResolver) string {
+	start := 0
+	for i := link.Start - 1; i > 0; i-- {
+		if isSentenceEnd(line, i-1) {
+			start = i
+			break
+		}
+	}
+
+	end := len(line)
+	for i := link.End; i < len(line); i++ {
+		if isSentenceEnd(line, i) {
+			end = i + 1
+			break
+		}
+	}
+
+	sentence := line[start:end]
+	sentence = listMarkerRegex.ReplaceAllString(sentence, "")
+
+	// Replace wiki links with the text they render as
+	var result strings.Builder
+	i := 0
+	for _, l := range markdown.ParseWikiLinks(sentence) {
+		result.WriteString(sentence[i:l.Start])
+		text := l.Label
+		if text == "" {
+			if target, ok := resolver.Resolve(l.Target); ok && target.Title != "" {
+				text = target.Title
+			} else {
+				text = l.Target
+			}
+		}
+		result.WriteString(text)
+		i = l.End
+	}
+	result.WriteString(sentence[i:])
+
+	context := strings.TrimSpace(result.String())
+	if len(context) > maxContextLength {
+		cut := maxContextLength
+		for cut > 0 && !isRuneStart(context[cut]) {
+			cut--
+		}
+		context = context[:cut] + "..."
+	}
+	return context
+}
+
+// isSentenceEnd reports whether the byte at i ends a sentence
+func isSentenceEnd(line string, i int) bool {
+	switch line[i] {
+	case '.', '!', '?':
+		return i+1 == len(line) || line[i+1] == ' '
+	}
+	return false
+}
+
+// parseItemRef splits a combined "id:type" reference
+func parseItemRef(ref string) (string, models.ItemType, bool) {
+	id, itemType, ok := strings.Cut(ref, ":")
+	if !ok || id == "" || itemType == "" {
+		return "", "", false
+	}
+	return id, models.ItemType(itemType), true
+}
+
+// addBacklink adds or refreshes a backlink on a target
+func (s *LinkService) addBacklink(target string, backlink Backlink) error {
+	backlinks, err := s.readIncoming(target)
+	if err != nil {
+		return err
+	}
+
+	for i, existing := range backlinks {
+		if existing.Source == backlink.Source {
+			if existing.Context == backlink.Context {
+				return nil
+			}
+			backlinks[i] = backlink
+			return s.writeIncoming(target, backlinks)
+		}
+	}
+
+	return s.writeIncoming(target, append(backlinks, backlink))
+}
+
+// removeBacklink removes a source from a target's backlinks
+func (s *LinkService) removeBacklink(target, source string) error {
+	backlinks, err := s.readIncoming(target)
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]Backlink, 0, len(backlinks))
+	for _, backlink := range backlinks {
+		if backlink.Source != source {
+			remaining = append(remaining, backlink)
+		}
+	}
+
+	if len(remaining) == len(backlinks) {
+		return nil
+	}
+	return s.writeIncoming(target, remaining)
+}
+
+// pendingKey returns the key of an unresolved link target, which matches IDs and titles ignoring case
+func pendingKey(target string) string {
+	return strings.ToLower(strings.TrimSpace(target))
+}
+
+// updatePending records the unresolved targets of a source, replacing the ones recorded before
+func (s *LinkService) updatePending(source string, unresolved []string) error {
+	pending, err := s.readPending()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for key, sources := range pending {
+		if contains(unresolved, key) {
+			continue
+		}
+		remaining := make([]string, 0, len(sources))
+		for _, existing := range sources {
+			if existing != source {
+				remaining = append(remaining, existing)
+			}
+		}
+		if len(remaining) != len(sources) {
+			changed = true
+			if len(remaining) == 0 {
+				delete(pending, key)
+			} else {
+				pending[key] = remaining
+			}
+		}
+	}
+	for _, key := range unresolved {
+		if !contains(pending[key], source) {
+			pending[key] = append(pending[key], source)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return s.writePending(pending)
+}
+
+// readPending returns the sources of unresolved links, keyed by target
+func (s *LinkService) readPending() (map[string][]string, error) {
+	pending := make(map[string][]string)
+	if err := readLinkFile(s.pendingPath(), &pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// writePending saves the sources of unresolved links, removing the file when there are none
+func (s *LinkService) writePending(pending map[string][]string) error {
+	return writeLinkFile(s.pendingPath(), pending, len(pending) == 0)
+}
+
+// readOutgoing returns the targets an item linked to when it was last indexed
+func (s *LinkService) readOutgoing(item *models.Item) ([]string, error) {
+	var targets []string
+	if err := readLinkFile(s.outgoingPath(item.ID, item.Type), &targets); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// writeOutgoing saves the targets of an item, removing the file when there are none
+func (s *LinkService) writeOutgoing(item *models.Item, targets []string) error {
+	return writeLinkFile(s.outgoingPath(item.ID, item.Type), targets, len(targets) == 0)
+}
+
+// readIncoming returns the backlinks of a target reference
+func (s *LinkService) readIncoming(target string) ([]Backlink, error) {
+	id, itemType, ok := parseItemRef(target)
+	if !ok {
+		return nil, nil
+	}
+
+	var backlinks []Backlink
+	if err := readLinkFile(s.incomingPath(id, itemType), &backlinks); err != nil {
+		return nil, err
+	}
+	return backlinks, nil
+}
+
+// writeIncoming saves the backlinks of a target, removing the file when there are none
+func (s *LinkService) writeIncoming(target string, backlinks []Backlink) error {
+	id, itemType, ok := parseItemRef(target)
+	if !ok {
+		return nil
+	}
+	return writeLinkFile(s.incomingPath(id, itemType), backlinks, len(backlinks) == 0)
+}
+
+// pendingPath returns the path of the file listing unresolved links
+func (s *LinkService) pendingPath() string {
+	return filepath.Join(s.repo.BasePath(), ".meta", "links", "unresolved.json")
+}
+
+// outgoingPath returns the path of the file listing an item's links
+func (s *LinkService) outgoingPath(id string, itemType models.ItemType) string {
+	return filepath.Join(s.repo.BasePath(), ".meta", "links", "outgoing", string(itemType)+"s", id+".json")
+}
+
+// incomingPath returns the path of the file listing an item's backlinks
+func (s *LinkService) incomingPath(id string, itemType models.ItemType) string {
+	return filepath.Join(s.repo.BasePath(), ".meta", "links", "incoming", string(itemType)+"s", id+".json")
+}
+
+// readLinkFile decodes a link index file, leaving v untouched if it doesn't exist
+func readLinkFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read link file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse link file: %w", err)
+	}
+	return nil
+}
+
+// writeLinkFile encodes a link index file, or removes it when empty is set
+func writeLinkFile(path string, v interface{}, empty bool) error {
+	if empty {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete link file: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create links directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal links: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write link file: %w", err)
+	}
+	return nil
+}
+
+// ItemResolver resolves wiki-link targets to items by ID or title.
+// It implements markdown.LinkResolver.
+type ItemResolver struct {
+	repo *Repository
+
+	// titles maps lowercase titles to items, built on the first title lookup
+	titles map[string]*models.Item
+}
+
+// NewItemResolver creates a new item resolver
+func NewItemResolver(repo *Repository) *ItemResolver {
+	return &ItemResolver{
+		repo: repo,
+	}
+}
+
+// Resolve finds the item a wiki-link target refers to.
+// IDs are tried first across all item types, then titles, ignoring case.
+func (r *ItemResolver) Resolve(target string) (*models.Item, bool) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, false
+	}
+
+	// IDs never contain path separators, anything else can only be a title
+	if !strings.ContainsAny(target, `/\`) {
+		for _, itemType := range r.repo.ItemTypes() {
+			probe := &models.Item{ID: target, Type: itemType}
+			if _, err := os.Stat(r.repo.getMetaPath(probe)); err != nil {
+				continue
+			}
+			if item, _, err := r.repo.LoadItem(target, itemType); err == nil {
+				return item, true
+			}
+		}
+	}
+
+	if r.titles == nil {
+		if err := r.loadTitles(); err != nil {
+			return nil, false
+		}
+	}
+
+	item, ok := r.titles[strings.ToLower(target)]
+	return item, ok
+}
+
+// ResolveLink returns the URL and title of the item a wiki-link target refers to
+func (r *ItemResolver) ResolveLink(target string) (string, string, bool) {
+	item, ok := r.Resolve(target)
+	if !ok {
+		return "", "", false
+	}
+	return fmt.Sprintf("/items/%s/%s", item.Type, item.ID), item.Title, true
+}
+
+// loadTitles indexes every item by title. When titles collide the most recently modified item wins.
+func (r *ItemResolver) loadTitles() error {
+	titles := make(map[string]*models.Item)
+	for _, itemType := range r.repo.ItemTypes() {
+		items, err := r.repo.ListItems(itemType)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if item.Title == "" {
+				continue
+			}
+			key := strings.ToLower(item.Title)
+			if existing, ok := titles[key]; !ok || item.Modified.After(existing.Modified) {
+				titles[key] = item
+			}
+		}
+	}
+
+	r.titles = titles
+	return nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestItemResolver(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	note := models.NewItem(models.TypeNote, "20240222095100")
+	note.Title = "Project Ideas"
+	require.NoError(t, repo.SaveItem(note, "Ideas"))
+
+	task := models.NewItem(models.TypeTask, "20240222095200")
+	task.Title = "Call the bank"
+	require.NoError(t, repo.SaveItem(task, "Before Friday"))
+
+	resolver := NewItemResolver(repo)
+
+	url, title, ok := resolver.ResolveLink("20240222095100")
+	require.True(t, ok)
+	assert.Equal(t, "/items/note/20240222095100", url)
+	assert.Equal(t, "Project Ideas", title)
+
+	url, _, ok = resolver.ResolveLink("call the BANK")
+	require.True(t, ok)
+	assert.Equal(t, "/items/task/20240222095200", url)
+
+	_, _, ok = resolver.ResolveLink("Unknown")
+	assert.False(t, ok)
+	_, _, ok = resolver.ResolveLink("../notes/20240222095100")
+	assert.False(t, ok)
+
+	// Duplicate titles resolve to the most recently modified item
+	time.Sleep(10 * time.Millisecond)
+	newer := models.NewItem(models.TypeNote, "20240222095300")
+	newer.Title = "Project ideas"
+	require.NoError(t, repo.SaveItem(newer, "More ideas"))
+
+	item, ok := NewItemResolver(repo).Resolve("Project Ideas")
+	require.True(t, ok)
+	assert.Equal(t, "20240222095300", item.ID)
+}
+
+func TestLinkServiceBacklinks(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	linkService := NewLinkService(repo)
+
+	target := models.NewItem(models.TypeNote, "target")
+	target.Title = "Garden Plan"
+	require.NoError(t, repo.SaveItem(target, "Plants"))
+
+	source := models.NewItem(models.TypeTask, "source")
+	source.Title = "Buy seeds"
+	require.NoError(t, repo.SaveItem(source, "Shopping first. Check the [[garden plan|plan]] for seeds! Then [[target]] again.\n\n```\n[[target]]\n```"))
+
+	backlinks, err := linkService.Backlinks(target)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, "source:task", backlinks[0].Source)
+	assert.Equal(t, "Check the plan for seeds!", backlinks[0].Context)
+
+	assert.FileExists(t, filepath.Join(tempDir, ".meta", "links", "outgoing", "tasks", "source.json"))
+	assert.FileExists(t, filepath.Join(tempDir, ".meta", "links", "incoming", "notes", "target.json"))
+
+	// Editing the content refreshes the context
+	require.NoError(t, repo.UpdateContent(source, "- [ ] Water the [[target]]"))
+	backlinks, err = linkService.Backlinks(target)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, "Water the Garden Plan", backlinks[0].Context)
+
+	// Removing the link removes the backlink
+	require.NoError(t, repo.UpdateContent(source, "No links here"))
+	backlinks, err = linkService.Backlinks(target)
+	require.NoError(t, err)
+	assert.Empty(t, backlinks)
+	assert.NoFileExists(t, filepath.Join(tempDir, ".meta", "links", "incoming", "notes", "target.json"))
+
+	// Deleting the source removes its backlinks
+	require.NoError(t, repo.UpdateContent(source, "See [[target]]"))
+	require.NoError(t, repo.DeleteItem(source))
+	backlinks, err = linkService.Backlinks(target)
+	require.NoError(t, err)
+	assert.Empty(t, backlinks)
+}
+
+func TestLinkServiceConvertAndRebuild(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	linkService := NewLinkService(repo)
+
+	// The target doesn't exist yet, the link is indexed once it's created
+	source := models.NewItem(models.TypeNote, "source")
+	source.Title = "Source"
+	require.NoError(t, repo.SaveItem(source, "Depends on [[later]]."))
+
+	target := models.NewItem(models.TypeNote, "later")
+	target.Title = "Later"
+	require.NoError(t, repo.SaveItem(target, "Created afterwards"))
+
+	backlinks, err := linkService.Backlinks(target)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+
+	require.NoError(t, linkService.Rebuild())
+	backlinks, err = linkService.Backlinks(target)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+
+	// Converting the target keeps its backlinks
+	require.NoError(t, repo.ConvertItem(target, models.TypeTask))
+	backlinks, err = linkService.Backlinks(target)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, "source:note", backlinks[0].Source)
+
+	// Converting the source moves the backlink source
+	require.NoError(t, repo.ConvertItem(source, models.TypeTask))
+	backlinks, err = linkService.Backlinks(target)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, "source:task", backlinks[0].Source)
+}
+
+func TestLinkServicePendingLinks(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	linkService := NewLinkService(repo)
+
+	source := models.NewItem(models.TypeNote, "source")
+	source.Title = "Source"
+	require.NoError(t, repo.SaveItem(source, "See the [[Garden Plan]] and [[Budget]]."))
+	assert.FileExists(t, filepath.Join(tempDir, ".meta", "links", "unresolved.json"))
+
+	// Renaming an item to a pending title links it
+	plan := models.NewItem(models.TypeNote, "plan")
+	plan.Title = "Draft"
+	require.NoError(t, repo.SaveItem(plan, "Plants"))
+	backlinks, err := linkService.Backlinks(plan)
+	require.NoError(t, err)
+	assert.Empty(t, backlinks)
+
+	plan.Title = "Garden plan"
+	require.NoError(t, repo.SaveItem(plan, ""))
+	backlinks, err = linkService.Backlinks(plan)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, "source:note", backlinks[0].Source)
+
+	// Creating an item with a pending title links it
+	budget := models.NewItem(models.TypeTask, "budget")
+	budget.Title = "Budget"
+	require.NoError(t, repo.SaveItem(budget, "Numbers"))
+	backlinks, err = linkService.Backlinks(budget)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+
+	outgoing, err := linkService.Outgoing(source)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plan:note", "budget:task"}, outgoing)
+	assert.NoFileExists(t, filepath.Join(tempDir, ".meta", "links", "unresolved.json"))
+}
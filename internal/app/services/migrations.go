@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vovere/internal/app/models"
+)
+
+// SchemaVersion is the metadata format this version of Vovere reads and writes.
+// It must match the version of the last migration.
+const SchemaVersion = 2
+
+// legacySchemaVersion is the format of repositories and items written before versioning
+const legacySchemaVersion = 1
+
+// ErrSchemaTooNew is returned for repositories and items written by a newer Vovere
+var ErrSchemaTooNew = errors.New("repository was written by a newer version of Vovere")
+
+// Migration upgrades item metadata from the previous schema version to Version
+type Migration struct {
+	Version     int
+	Description string
+	// Item changes an item's metadata in place and describes each change.
+	// The content is only read, migrations never change it.
+	Item func(item *models.Item, content string) []string
+}
+
+// migrations lists every schema change in order, append new ones at the end
+var migrations = []Migration{
+	{
+		Version:     2,
+		Description: "Record the schema version and fill in fields missing from older metadata",
+		Item: func(item *models.Item, content string) []string {
+			// Untitled items are left alone, the inbox relies on them
+			var changes []string
+			if item.Tags == nil {
+				item.Tags = []string{}
+				changes = append(changes, "tags set to an empty list")
+			}
+			if item.Type == models.TypeTask && item.Status == "" {
+				item.Status = models.TaskStatusTodo
+				changes = append(changes, fmt.Sprintf("status set to %q", item.Status))
+			}
+			return changes
+		},
+	},
+}
+
+// migrateItem applies the migrations an item hasn't had yet and stamps the current version.
+// It returns the changes, including the version bump.
+func migrateItem(item *models.Item, content string) ([]string, error) {
+	from := itemSchemaVersion(item)
+	if from > SchemaVersion {
+		return nil, fmt.Errorf("%w: %s has schema version %d, this version supports %d", ErrSchemaTooNew, ItemRef(item), from, SchemaVersion)
+	}
+	if from == SchemaVersion {
+		return nil, nil
+	}
+
+	var changes []string
+	for _, migration := range migrations {
+		if migration.Version > from {
+			changes = append(changes, migration.Item(item, content)...)
+		}
+	}
+	item.SchemaVersion = SchemaVersion
+	return append(changes, fmt.Sprintf("schema version %d to %d", from, SchemaVersion)), nil
+}
+
+// itemSchemaVersion returns the schema version an item was written with
+func itemSchemaVersion(item *models.Item) int {
+	if item.SchemaVersion == 0 {
+		return legacySchemaVersion
+	}
+	return item.SchemaVersion
+}
+
+// schemaVersion returns the schema version of the repository
+func (c *RepositoryConfig) schemaVersion() int {
+	if c.SchemaVersion == 0 {
+		return legacySchemaVersion
+	}
+	return c.SchemaVersion
+}
+
+// CheckSchema returns ErrSchemaTooNew if the repository was written by a newer Vovere.
+// Older repositories can be opened, their items are upgraded as they are saved.
+func (r *Repository) CheckSchema() error {
+	config, err := r.Config()
+	if err != nil {
+		return err
+	}
+	if version := config.schemaVersion(); version > SchemaVersion {
+		return fmt.Errorf("%w: schema version %d, this version supports %d", ErrSchemaTooNew, version, SchemaVersion)
+	}
+	return nil
+}
+
+// ItemMigration describes the changes a migration makes to one item
+type ItemMigration struct {
+	Item string `json:"item"`
+	// Path is relative to the repository root
+	Path    string   `json:"path"`
+	From    int      `json:"from"`
+	Changes []string `json:"changes"`
+}
+
+// MigrationReport is the result of migrating a repository
+type MigrationReport struct {
+	From   int  `json:"from"`
+	To     int  `json:"to"`
+	DryRun bool `json:"dryRun"`
+	Items  int  `json:"items"`
+	// Steps are the migrations between From and To
+	Steps    []Migration     `json:"-"`
+	Migrated []ItemMigration `json:"migrated"`
+	// Skipped lists metadata files that couldn't be read, fsck can repair them
+	Skipped []string `json:"skipped"`
+}
+
+// MigrationService upgrades repositories to the current schema version
+type MigrationService struct {
+	repo *Repository
+}
+
+// NewMigrationService creates a new migration service
+func NewMigrationService(repo *Repository) *MigrationService {
+	return &MigrationService{
+		repo: repo,
+	}
+}
+
+// Migrate upgrades every item and the repository configuration to the current schema version.
+// With dryRun nothing is written and the report shows what would change.
+func (s *MigrationService) Migrate(dryRun bool) (*MigrationReport, error) {
+	config, err := s.repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	from := config.schemaVersion()
+	if from > SchemaVersion {
+		return nil, fmt.Errorf("%w: schema version %d, this version supports %d", ErrSchemaTooNew, from, SchemaVersion)
+	}
+
+	report := &MigrationReport{
+		From:     from,
+		To:       SchemaVersion,
+		DryRun:   dryRun,
+		Migrated: []ItemMigration{},
+		Skipped:  []string{},
+	}
+	for _, migration := range migrations {
+		if migration.Version > from {
+			report.Steps = append(report.Steps, migration)
+		}
+	}
+
+	unlock := s.repo.lock()
+	defer unlock()
+
+	// Items are versioned one by one, so a repository saved by a newer server can be partly upgraded already
+	for _, itemType := range s.repo.ItemTypes() {
+		metaDir := filepath.Join(s.repo.BasePath(), ".meta", string(itemType)+"s")
+		entries, err := os.ReadDir(metaDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read metadata directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || filepath.Ext(name) != ".json" || strings.HasPrefix(name, ".") {
+				continue
+			}
+			id := strings.TrimSuffix(name, ".json")
+			relPath := filepath.ToSlash(filepath.Join(".meta", string(itemType)+"s", name))
+
+			item, content, err := s.repo.LoadItem(id, itemType)
+			if err != nil {
+				report.Skipped = append(report.Skipped, relPath)
+				continue
+			}
+			report.Items++
+
+			itemFrom := itemSchemaVersion(item)
+			changes, err := migrateItem(item, content)
+			if err != nil {
+				return nil, err
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			report.Migrated = append(report.Migrated, ItemMigration{
+				Item:    ItemRef(item),
+				Path:    relPath,
+				From:    itemFrom,
+				Changes: changes,
+			})
+
+			if !dryRun {
+				if err := s.repo.writeMetadata(item); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// The repository version goes last, an interrupted migration is simply run again
+	if !dryRun && from != SchemaVersion {
+		config.SchemaVersion = SchemaVersion
+		if err := s.repo.SaveConfig(config); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	require.NotEmpty(t, migrations)
+	for i := 1; i < len(migrations); i++ {
+		assert.Greater(t, migrations[i].Version, migrations[i-1].Version)
+	}
+	assert.Equal(t, SchemaVersion, migrations[len(migrations)-1].Version)
+}
+
+func TestMigrateLegacyRepository(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// Metadata written before versioning, without tags or a task status
+	legacyTask := filepath.Join(tempDir, ".meta", "tasks", "20240101120000.json")
+	require.NoError(t, os.WriteFile(legacyTask, []byte(`{"id":"20240101120000","type":"task","title":"Old task","tags":null}`), 0644))
+	current := models.NewItem(models.TypeNote, "current")
+	require.NoError(t, repo.SaveItem(current, "Saved by this version"))
+
+	migrationService := NewMigrationService(repo)
+
+	report, err := migrationService.Migrate(true)
+	require.NoError(t, err)
+	assert.Equal(t, legacySchemaVersion, report.From)
+	assert.Equal(t, SchemaVersion, report.To)
+	assert.Equal(t, 2, report.Items)
+	require.Len(t, report.Migrated, 1)
+	assert.Equal(t, "20240101120000:task", report.Migrated[0].Item)
+	assert.Contains(t, report.Migrated[0].Changes, `status set to "todo"`)
+
+	// A dry run writes nothing
+	data, err := os.ReadFile(legacyTask)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "schemaVersion")
+	_, err = os.Stat(filepath.Join(tempDir, "config.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	report, err = migrationService.Migrate(false)
+	require.NoError(t, err)
+	require.Len(t, report.Migrated, 1)
+
+	task, _, err := repo.LoadItem("20240101120000", models.TypeTask)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, task.SchemaVersion)
+	assert.Equal(t, models.TaskStatusTodo, task.Status)
+	assert.NotNil(t, task.Tags)
+
+	config, err := repo.Config()
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, config.SchemaVersion)
+
+	// Migrating again has nothing left to do
+	report, err = migrationService.Migrate(false)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, report.From)
+	assert.Empty(t, report.Migrated)
+}
+
+func TestSaveUpgradesLegacyItems(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".meta", "tasks", "old.json"), []byte(`{"id":"old","type":"task","title":"Old"}`), 0644))
+
+	task, _, err := repo.LoadItem("old", models.TypeTask)
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveItem(task, ""))
+
+	var saved map[string]interface{}
+	data, err := os.ReadFile(filepath.Join(tempDir, ".meta", "tasks", "old.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.EqualValues(t, SchemaVersion, saved["schemaVersion"])
+	assert.Equal(t, "todo", saved["status"])
+}
+
+func TestNewerSchemaIsRefused(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// Items written by a newer version aren't downgraded
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".meta", "notes", "future.json"), []byte(`{"id":"future","type":"note","schemaVersion":99}`), 0644))
+	future, _, err := repo.LoadItem("future", models.TypeNote)
+	require.NoError(t, err)
+	assert.ErrorIs(t, repo.SaveItem(future, "Changed"), ErrSchemaTooNew)
+
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{SchemaVersion: SchemaVersion + 1}))
+
+	assert.ErrorIs(t, repo.CheckSchema(), ErrSchemaTooNew)
+	_, err = NewMigrationService(repo).Migrate(true)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+
+	registry := NewRegistry(0)
+	defer registry.Close()
+	_, err = registry.Open(tempDir)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+}
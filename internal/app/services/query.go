@@ -0,0 +1,430 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"vovere/internal/app/models"
+)
+
+// Query is a parsed search query. All clauses must match for an item to match.
+//
+// Supported syntax:
+//
+//	word               full-text term (the last one also matches as a prefix)
+//	"some phrase"      exact phrase in the title or content
+//	type:task          item type (singular or plural), comma separated values are OR-ed
+//	tag:project:web    exact tag, a trailing * matches tag prefixes (tag:project:*)
+//	status:todo        task status
+//	id:2025*           item ID, * acts as a wildcard
+//	title:"weekly"     substring of the title
+//	url:github.com     substring of the URL
+//	filename:*.pdf     filename, * acts as a wildcard
+//	created:>2025-01-01, modified:<=2025-02, created:2024..2025-06
+//	-clause            negates any of the above
+type Query struct {
+	Clauses []Clause
+}
+
+// Clause is a single condition of a query
+type Clause struct {
+	// Field is the item field the clause applies to, empty for full-text clauses
+	Field string
+	// Values holds the alternatives to match, any of them is enough
+	Values []string
+	// Phrase marks a quoted full-text clause
+	Phrase bool
+	// Negated inverts the clause
+	Negated bool
+
+	// Date range for created and modified clauses, either bound may be zero
+	from time.Time
+	to   time.Time
+}
+
+// queryFields lists the fields accepted in field:value clauses
+var queryFields = map[string]bool{
+	"type":     true,
+	"tag":      true,
+	"status":   true,
+	"id":       true,
+	"title":    true,
+	"url":      true,
+	"filename": true,
+	"created":  true,
+	"modified": true,
+}
+
+// ParseQuery parses a search query string
+func ParseQuery(input string) (*Query, error) {
+	q := &Query{}
+
+	for _, token := range splitQuery(input) {
+		clause := Clause{}
+
+		raw := token
+		if strings.HasPrefix(raw, "-") && len(raw) > 1 {
+			clause.Negated = true
+			raw = raw[1:]
+		}
+
+		// Field clause
+		if field, value, ok := strings.Cut(raw, ":"); ok && queryFields[strings.ToLower(field)] {
+			clause.Field = strings.ToLower(field)
+			value = unquote(value)
+			if value == "" {
+				continue // Field still being typed
+			}
+
+			if err := clause.parseValue(value); err != nil {
+				return nil, err
+			}
+			q.Clauses = append(q.Clauses, clause)
+			continue
+		}
+
+		// Phrase clause
+		if strings.HasPrefix(raw, `"`) {
+			phrase := strings.Join(Tokenize(unquote(raw)), " ")
+			if phrase == "" {
+				continue
+			}
+			clause.Phrase = true
+			clause.Values = []string{phrase}
+			q.Clauses = append(q.Clauses, clause)
+			continue
+		}
+
+		// Plain terms, split the same way the index is
+		for _, term := range Tokenize(raw) {
+			q.Clauses = append(q.Clauses, Clause{
+				Values:  []string{term},
+				Negated: clause.Negated,
+			})
+		}
+	}
+
+	return q, nil
+}
+
+// IsEmpty reports whether the query has no clauses
+func (q *Query) IsEmpty() bool {
+	return len(q.Clauses) == 0
+}
+
+// Terms returns the positive full-text terms used for ranking
+func (q *Query) Terms() []string {
+	var terms []string
+	for _, clause := range q.Clauses {
+		if clause.Field != "" || clause.Negated {
+			continue
+		}
+		for _, value := range clause.Values {
+			terms = append(terms, strings.Fields(value)...)
+		}
+	}
+	return terms
+}
+
+// prefixTerm returns the trailing full-text term that also matches as a prefix
+func (q *Query) prefixTerm() string {
+	if len(q.Clauses) == 0 {
+		return ""
+	}
+	last := q.Clauses[len(q.Clauses)-1]
+	if last.Field != "" || last.Phrase || last.Negated {
+		return ""
+	}
+	return last.Values[0]
+}
+
+// Match reports whether an item and its content satisfy every clause of the query
+func (q *Query) Match(item *models.Item, content string) bool {
+	terms := make(map[string]int)
+	for _, part := range []string{item.Title, strings.Join(item.Tags, " "), string(item.Type), content} {
+		for _, token := range Tokenize(part) {
+			terms[token]++
+		}
+	}
+	return q.matches(item, &matchText{item: item, content: content, terms: terms})
+}
+
+// matches evaluates every clause against an item and its searchable text
+func (q *Query) matches(item *models.Item, text *matchText) bool {
+	prefix := q.prefixTerm()
+
+	for _, clause := range q.Clauses {
+		if clause.match(item, text, prefix) == clause.Negated {
+			return false
+		}
+	}
+	return true
+}
+
+// matchText holds the searchable text of an item
+type matchText struct {
+	item    *models.Item
+	content string
+	terms   map[string]int
+	joined  string
+}
+
+// phraseText returns the normalised text used for phrase matching, built on first use
+func (t *matchText) phraseText() string {
+	if t.joined != "" {
+		return t.joined
+	}
+
+	var all []string
+	for _, part := range []string{t.item.Title, strings.Join(t.item.Tags, " "), string(t.item.Type), t.content} {
+		all = append(all, strings.Join(Tokenize(part), " "))
+	}
+
+	// Pad with spaces so phrases only match whole words
+	t.joined = " " + strings.Join(all, " \n ") + " "
+	return t.joined
+}
+
+// match evaluates a clause ignoring negation
+func (c *Clause) match(item *models.Item, text *matchText, prefix string) bool {
+	switch c.Field {
+	case "":
+		value := c.Values[0]
+		if c.Phrase {
+			return strings.Contains(text.phraseText(), " "+value+" ")
+		}
+		if text.terms[value] > 0 {
+			return true
+		}
+		if value == prefix {
+			for term := range text.terms {
+				if strings.HasPrefix(term, value) {
+					return true
+				}
+			}
+		}
+		return false
+	case "type":
+		for _, value := range c.Values {
+			if string(item.Type) == value || string(item.Type)+"s" == value {
+				return true
+			}
+		}
+	case "tag":
+		for _, value := range c.Values {
+			for _, tag := range item.Tags {
+				if wildcardMatch(value, strings.ToLower(tag)) {
+					return true
+				}
+			}
+		}
+	case "status":
+		for _, value := range c.Values {
+			if strings.EqualFold(string(item.Status), value) {
+				return true
+			}
+		}
+	case "id":
+		for _, value := range c.Values {
+			if wildcardMatch(value, strings.ToLower(item.ID)) {
+				return true
+			}
+		}
+	case "title":
+		return containsAny(item.Title, c.Values)
+	case "url":
+		return containsAny(item.URL, c.Values)
+	case "filename":
+		for _, value := range c.Values {
+			name := strings.ToLower(item.Filename)
+			if strings.Contains(value, "*") && wildcardMatch(value, name) ||
+				strings.Contains(name, value) {
+				return true
+			}
+		}
+	case "created":
+		return c.inRange(item.Created)
+	case "modified":
+		return c.inRange(item.Modified)
+	}
+	return false
+}
+
+// parseValue fills the clause values from the raw value of a field clause
+func (c *Clause) parseValue(value string) error {
+	if c.Field == "created" || c.Field == "modified" {
+		from, to, err := parseDateRange(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s filter %q: %w", c.Field, value, err)
+		}
+		c.from, c.to = from, to
+		c.Values = []string{value}
+		return nil
+	}
+
+	for _, alternative := range strings.Split(strings.ToLower(value), ",") {
+		if alternative = strings.TrimSpace(alternative); alternative != "" {
+			c.Values = append(c.Values, alternative)
+		}
+	}
+	if len(c.Values) == 0 {
+		return fmt.Errorf("empty %s filter", c.Field)
+	}
+	return nil
+}
+
+// inRange reports whether a time falls inside the clause date range
+func (c *Clause) inRange(t time.Time) bool {
+	if !c.from.IsZero() && t.Before(c.from) {
+		return false
+	}
+	if !c.to.IsZero() && !t.Before(c.to) {
+		return false
+	}
+	return true
+}
+
+// parseDateRange converts a date expression into a half-open [from, to) interval.
+// Accepted forms are DATE, >DATE, >=DATE, <DATE, <=DATE and DATE..DATE,
+// where DATE is YYYY, YYYY-MM or YYYY-MM-DD.
+func parseDateRange(value string) (time.Time, time.Time, error) {
+	if start, end, ok := strings.Cut(value, ".."); ok {
+		var from, to time.Time
+		if start != "" {
+			s, _, err := parseDate(start)
+			if err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+			from = s
+		}
+		if end != "" {
+			_, e, err := parseDate(end)
+			if err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+			to = e
+		}
+		return from, to, nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if !strings.HasPrefix(value, op) {
+			continue
+		}
+
+		start, end, err := parseDate(strings.TrimPrefix(value, op))
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		switch op {
+		case ">=":
+			return start, time.Time{}, nil
+		case "<=":
+			return time.Time{}, end, nil
+		case ">":
+			return end, time.Time{}, nil
+		case "<":
+			return time.Time{}, start, nil
+		default:
+			return start, end, nil
+		}
+	}
+
+	return parseDate(value)
+}
+
+// parseDate parses a date and returns the period it covers
+func parseDate(value string) (time.Time, time.Time, error) {
+	layouts := []struct {
+		layout string
+		next   func(time.Time) time.Time
+	}{
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+		{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+	}
+
+	for _, l := range layouts {
+		if t, err := time.Parse(l.layout, value); err == nil {
+			return t, l.next(t), nil
+		}
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("expected YYYY, YYYY-MM or YYYY-MM-DD")
+}
+
+// splitQuery splits a query on whitespace, keeping quoted sections together
+func splitQuery(input string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
+
+// unquote removes surrounding double quotes, tolerating a missing closing quote
+func unquote(value string) string {
+	value = strings.TrimPrefix(value, `"`)
+	return strings.TrimSuffix(value, `"`)
+}
+
+// wildcardMatch matches a lowercase value against a pattern where * matches any run of characters
+func wildcardMatch(pattern, value string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == value
+	}
+
+	// Escape everything but the wildcard so path.Match treats it literally
+	var escaped strings.Builder
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			escaped.WriteRune(r)
+		case '/':
+			escaped.WriteRune('\x00')
+		case '?', '[', ']', '\\':
+			escaped.WriteRune('\\')
+			escaped.WriteRune(r)
+		default:
+			escaped.WriteRune(r)
+		}
+	}
+
+	// path.Match stops * at slashes, which never matter for tags, IDs or filenames
+	matched, err := path.Match(escaped.String(), strings.ReplaceAll(value, "/", "\x00"))
+	return err == nil && matched
+}
+
+// containsAny reports whether s contains any of the values, ignoring case
+func containsAny(s string, values []string) bool {
+	s = strings.ToLower(s)
+	for _, value := range values {
+		if strings.Contains(s, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(`type:task status:todo tag:project:website created:>2025-01-01 "release notes" -tag:archive draft`)
+	require.NoError(t, err)
+	require.Len(t, q.Clauses, 7)
+
+	assert.Equal(t, "type", q.Clauses[0].Field)
+	assert.Equal(t, []string{"task"}, q.Clauses[0].Values)
+	assert.Equal(t, "tag", q.Clauses[2].Field)
+	assert.Equal(t, []string{"project:website"}, q.Clauses[2].Values)
+	assert.True(t, q.Clauses[4].Phrase)
+	assert.Equal(t, []string{"release notes"}, q.Clauses[4].Values)
+	assert.True(t, q.Clauses[5].Negated)
+	assert.Equal(t, []string{"release", "notes", "draft"}, q.Terms())
+
+	_, err = ParseQuery("created:>yesterday")
+	assert.Error(t, err)
+
+	// Unknown fields are treated as text
+	q, err = ParseQuery("https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https", "example", "com"}, q.Terms())
+}
+
+func TestQueryMatch(t *testing.T) {
+	item := models.NewItem(models.TypeTask, "20250115093000")
+	item.Title = "Publish release notes"
+	item.Tags = []string{"project:website", "Writing"}
+	item.Status = models.TaskStatusTodo
+	item.Created = time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC)
+	item.Modified = time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	content := "Draft the release notes for version 2.\n\nSee https://example.com/changelog"
+
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{`type:task status:todo tag:project:website created:>2025-01-01 "release notes" -tag:archive`, true},
+		{"type:tasks", true},
+		{"type:note,task", true},
+		{"type:note", false},
+		{"status:done", false},
+		{"tag:project:*", true},
+		{"tag:project", false},
+		{"tag:writing", true},
+		{"-tag:writing", false},
+		{"created:2025-01", true},
+		{"created:2025-01-15", true},
+		{"created:<2025-01-15", false},
+		{"created:<=2025-01-15", true},
+		{"created:>2025-01-15", false},
+		{"created:2024..2025-01", true},
+		{"modified:>=2025-02-01", true},
+		{"modified:2025-01", false},
+		{`"notes for version"`, true},
+		{`"version notes"`, false},
+		{`-"release notes"`, false},
+		{"release draft", true},
+		{"release -draft", false},
+		{"vers", true},
+		{"vers draft", false},
+		{"id:2025*", true},
+		{"title:release", true},
+		{"title:groceries", false},
+		{"url:example", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, q.Match(item, content))
+		})
+	}
+
+	bookmark := models.NewItem(models.TypeBookmark, "bookmark")
+	bookmark.URL = "https://github.com/darccio/vovere"
+	q, err := ParseQuery("url:github.com")
+	require.NoError(t, err)
+	assert.True(t, q.Match(bookmark, ""))
+
+	file := models.NewItem(models.TypeFile, "file")
+	file.Filename = "Report.PDF"
+	q, err = ParseQuery("filename:*.pdf")
+	require.NoError(t, err)
+	assert.True(t, q.Match(file, ""))
+}
+
+func TestSearchWithFilters(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	searchService := NewSearchService(repo)
+
+	task := models.NewItem(models.TypeTask, "task1")
+	task.Title = "Ship release"
+	task.Status = models.TaskStatusTodo
+	require.NoError(t, repo.SaveItem(task, "Ship the release #project"))
+
+	done := models.NewItem(models.TypeTask, "task2")
+	done.Title = "Old release"
+	done.Status = models.TaskStatusDone
+	require.NoError(t, repo.SaveItem(done, "Previous release #project #archive"))
+
+	note := models.NewItem(models.TypeNote, "note1")
+	note.Title = "Release notes"
+	require.NoError(t, repo.SaveItem(note, "Notes about the release #project"))
+
+	results, err := searchService.Search("type:task release", 0)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	results, err = searchService.Search("type:task status:todo", 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "task1", results[0].ID)
+
+	results, err = searchService.Search("tag:project -tag:archive", 0)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	_, err = searchService.Search("modified:>tomorrow", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Registry keeps one Repository per path for the lifetime of the server, so the item and
+// tag caches it shares with its services survive between requests
+type Registry struct {
+	watchInterval time.Duration
+
+	mu         sync.Mutex
+	repos      map[string]*Repository
+	watchers   map[string]*WatcherService
+	schedulers map[string]*BackupScheduler
+}
+
+// NewRegistry creates a registry whose repositories are watched for external changes
+// every watchInterval, or not at all when it is zero
+func NewRegistry(watchInterval time.Duration) *Registry {
+	return &Registry{
+		watchInterval: watchInterval,
+		repos:         make(map[string]*Repository),
+		watchers:      make(map[string]*WatcherService),
+		schedulers:    make(map[string]*BackupScheduler),
+	}
+}
+
+// Open returns the repository at a path, creating it and starting its watcher on first use.
+// Repositories written by a newer Vovere are refused with ErrSchemaTooNew.
+func (g *Registry) Open(path string) (*Repository, error) {
+	key := filepath.Clean(path)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if repo, ok := g.repos[key]; ok {
+		return repo, nil
+	}
+
+	repo := NewRepository(path)
+	if err := repo.CheckSchema(); err != nil {
+		return nil, err
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	g.repos[key] = repo
+
+	// Commit saves when automatic commits are on, the repository still opens without git.
+	// It's set up before the watcher so the edits of its first scan are committed too.
+	if config.Git.Enabled {
+		git := NewGitService(repo)
+		if err := git.Init(); err != nil {
+			log.Printf("Automatic commits disabled for %s: %v", path, err)
+		} else {
+			repo.git = git
+		}
+	}
+
+	// Pick up edits made outside Vovere
+	if g.watchInterval > 0 {
+		watcher := NewWatcherService(repo, g.watchInterval)
+		watcher.Start()
+		g.watchers[key] = watcher
+	}
+
+	// Write the backups configured in config.json
+	if config.Backup.Dir != "" {
+		scheduler := NewBackupScheduler(repo)
+		scheduler.Start()
+		g.schedulers[key] = scheduler
+	}
+
+	return repo, nil
+}
+
+// Close stops the watchers and backup schedulers of every open repository, commits
+// pending changes and forgets them
+func (g *Registry) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, watcher := range g.watchers {
+		watcher.Stop()
+	}
+	for _, scheduler := range g.schedulers {
+		scheduler.Stop()
+	}
+	// Commit what's still waiting for the quiet period
+	for _, repo := range g.repos {
+		if repo.git != nil {
+			repo.git.Stop()
+		}
+	}
+	g.repos = make(map[string]*Repository)
+	g.watchers = make(map[string]*WatcherService)
+	g.schedulers = make(map[string]*BackupScheduler)
+}
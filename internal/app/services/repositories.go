@@ -0,0 +1,307 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownRepository is returned for a repository ID the store doesn't know
+	ErrUnknownRepository = errors.New("unknown repository")
+	// ErrPathNotAllowed is returned for a repository outside the allowed root directories
+	ErrPathNotAllowed = errors.New("path is outside the allowed directories")
+)
+
+// KnownRepository is a repository the server has opened before. Browsers refer to it by ID,
+// so they never name a filesystem path after it was added.
+type KnownRepository struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Added      time.Time `json:"added"`
+	LastOpened time.Time `json:"lastOpened"`
+}
+
+// RepositoryStore keeps the known repositories of the server in a JSON file, usually
+// repositories.json in the user's config directory. With allowed roots, only directories
+// under one of them can be added or opened.
+type RepositoryStore struct {
+	path  string
+	roots []string
+
+	mu sync.Mutex
+}
+
+// NewRepositoryStore creates a store backed by the file at path, which is created on first add
+func NewRepositoryStore(path string, roots []string) *RepositoryStore {
+	return &RepositoryStore{
+		path:  path,
+		roots: roots,
+	}
+}
+
+// DefaultRepositoryStorePath returns vovere/repositories.json in the user's config directory
+func DefaultRepositoryStorePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, "vovere", "repositories.json"), nil
+}
+
+// List returns the known repositories, most recently opened first
+func (s *RepositoryStore) List() ([]*KnownRepository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repos, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(repos, func(i, j int) bool {
+		return repos[i].LastOpened.After(repos[j].LastOpened)
+	})
+	return repos, nil
+}
+
+// Get returns a known repository by ID. Repositories outside the allowed roots,
+// which may have changed since they were added, are refused with ErrPathNotAllowed.
+func (s *RepositoryStore) Get(id string) (*KnownRepository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repos, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	repo := findRepository(repos, id)
+	if repo == nil {
+		return nil, ErrUnknownRepository
+	}
+	if !s.allowed(repo.Path) {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotAllowed, repo.Path)
+	}
+	return repo, nil
+}
+
+// Add registers the directory at path, returning the existing entry when it is known already.
+// The name defaults to the directory name.
+func (s *RepositoryStore) Add(path, name string) (*KnownRepository, error) {
+	path, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repos, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		if repo.Path == path {
+			return repo, nil
+		}
+	}
+
+	id, err := newRepositoryID()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	repo := &KnownRepository{
+		ID:    id,
+		Name:  name,
+		Path:  path,
+		Added: time.Now().UTC(),
+	}
+	if err := s.write(append(repos, repo)); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// Rename changes the name a known repository is listed with
+func (s *RepositoryStore) Rename(id, name string) (*KnownRepository, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	return s.update(id, func(repo *KnownRepository) {
+		repo.Name = name
+	})
+}
+
+// Touch records that a known repository was opened
+func (s *RepositoryStore) Touch(id string) (*KnownRepository, error) {
+	return s.update(id, func(repo *KnownRepository) {
+		repo.LastOpened = time.Now().UTC()
+	})
+}
+
+// Forget removes a repository from the store, its files are left alone
+func (s *RepositoryStore) Forget(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repos, err := s.read()
+	if err != nil {
+		return err
+	}
+	kept := make([]*KnownRepository, 0, len(repos))
+	for _, repo := range repos {
+		if repo.ID != id {
+			kept = append(kept, repo)
+		}
+	}
+	if len(kept) == len(repos) {
+		return ErrUnknownRepository
+	}
+	return s.write(kept)
+}
+
+// update changes a known repository and saves the store
+func (s *RepositoryStore) update(id string, change func(repo *KnownRepository)) (*KnownRepository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repos, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	repo := findRepository(repos, id)
+	if repo == nil {
+		return nil, ErrUnknownRepository
+	}
+	change(repo)
+	if err := s.write(repos); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// resolve turns a path into the absolute path of an existing directory within the allowed roots
+func (s *RepositoryStore) resolve(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("repository path is required")
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid repository path: %w", err)
+	}
+	// Symbolic links could otherwise lead out of the allowed roots
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("repository directory does not exist")
+		}
+		return "", fmt.Errorf("invalid repository path: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid repository path: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("path must be a directory")
+	}
+	if !s.allowed(path) {
+		return "", fmt.Errorf("%w: %s", ErrPathNotAllowed, path)
+	}
+	return path, nil
+}
+
+// allowed reports whether a path is within one of the allowed roots, any path is without roots
+func (s *RepositoryStore) allowed(path string) bool {
+	if len(s.roots) == 0 {
+		return true
+	}
+	for _, root := range s.roots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultAllowedRoots returns the user's home directory, where repositories are allowed
+// unless the server is given other roots
+func DefaultAllowedRoots() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return []string{home}, nil
+}
+
+// read loads the known repositories, none when the file doesn't exist yet
+func (s *RepositoryStore) read() ([]*KnownRepository, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*KnownRepository{}, nil
+		}
+		return nil, fmt.Errorf("failed to read repository list: %w", err)
+	}
+
+	var repos []*KnownRepository
+	if err := json.Unmarshal(data, &repos); err != nil {
+		return nil, fmt.Errorf("failed to parse repository list: %w", err)
+	}
+	return repos, nil
+}
+
+// write saves the known repositories
+func (s *RepositoryStore) write(repos []*KnownRepository) error {
+	data, err := json.MarshalIndent(repos, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode repository list: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(s.path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write repository list: %w", err)
+	}
+	return nil
+}
+
+// findRepository returns the known repository with an ID, or nil
+func findRepository(repos []*KnownRepository, id string) *KnownRepository {
+	for _, repo := range repos {
+		if repo.ID == id {
+			return repo
+		}
+	}
+	return nil
+}
+
+// newRepositoryID returns a random ID that says nothing about where the repository is
+func newRepositoryID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate repository ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryStore(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "notes")
+	work := filepath.Join(root, "work")
+	outside := filepath.Join(dir, "elsewhere")
+	for _, d := range []string{work, outside} {
+		require.NoError(t, os.MkdirAll(d, 0755))
+	}
+
+	storePath := filepath.Join(dir, "config", "repositories.json")
+	store := NewRepositoryStore(storePath, []string{root})
+
+	// Only existing directories under the allowed roots are added
+	_, err := store.Add(outside, "")
+	assert.ErrorIs(t, err, ErrPathNotAllowed)
+	_, err = store.Add(filepath.Join(root, "missing"), "")
+	assert.Error(t, err)
+	_, err = store.Add(filepath.Join(work, ".."+string(filepath.Separator)+".."+string(filepath.Separator)+"elsewhere"), "")
+	assert.ErrorIs(t, err, ErrPathNotAllowed)
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link")))
+	_, err = store.Add(filepath.Join(root, "link"), "")
+	assert.ErrorIs(t, err, ErrPathNotAllowed)
+
+	work, err = filepath.EvalSymlinks(work)
+	require.NoError(t, err)
+	added, err := store.Add(work, "")
+	require.NoError(t, err)
+	assert.Equal(t, "work", added.Name)
+	assert.Equal(t, work, added.Path)
+	assert.Len(t, added.ID, 32)
+	assert.NotContains(t, added.ID, "work")
+
+	// Adding a known directory again returns it
+	again, err := store.Add(work+string(filepath.Separator), "Other")
+	require.NoError(t, err)
+	assert.Equal(t, added.ID, again.ID)
+
+	// IDs resolve to paths, in a fresh store as well
+	store = NewRepositoryStore(storePath, []string{root})
+	found, err := store.Get(added.ID)
+	require.NoError(t, err)
+	assert.Equal(t, work, found.Path)
+	_, err = store.Get(work)
+	assert.ErrorIs(t, err, ErrUnknownRepository)
+
+	renamed, err := store.Rename(added.ID, "Work notes")
+	require.NoError(t, err)
+	assert.Equal(t, "Work notes", renamed.Name)
+	_, err = store.Rename(added.ID, " ")
+	assert.Error(t, err)
+
+	// Most recently opened first
+	personal := filepath.Join(root, "personal")
+	require.NoError(t, os.MkdirAll(personal, 0755))
+	second, err := store.Add(personal, "")
+	require.NoError(t, err)
+	_, err = store.Touch(added.ID)
+	require.NoError(t, err)
+	repos, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, repos, 2)
+	assert.Equal(t, added.ID, repos[0].ID)
+	assert.Equal(t, "Work notes", repos[0].Name)
+
+	// Narrower roots refuse repositories added before
+	narrow := NewRepositoryStore(storePath, []string{personal})
+	_, err = narrow.Get(added.ID)
+	assert.ErrorIs(t, err, ErrPathNotAllowed)
+
+	// Forgetting leaves the files alone
+	require.NoError(t, store.Forget(added.ID))
+	assert.ErrorIs(t, store.Forget(added.ID), ErrUnknownRepository)
+	assert.DirExists(t, work)
+	repos, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	assert.Equal(t, second.ID, repos[0].ID)
+}
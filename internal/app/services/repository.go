@@ -2,25 +2,44 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
 	"vovere/internal/app/models"
 )
 
+// ErrConflict is returned when an item changed since the version an update was based on
+var ErrConflict = errors.New("item was modified since it was loaded")
+
 // Repository handles file operations for items
 type Repository struct {
 	basePath string
+	items    *itemCache
+	tags     *tagCache
+	// git commits saves when automatic commits are enabled, see Registry.Open
+	git *GitService
+	// keys holds the key of encrypted items while the repository is unlocked
+	keys *keySession
+	// types holds the item types of config.json, see types.go
+	types *typeRegistry
+	// search holds the search index in memory, see search.go
+	search *searchIndex
 }
 
-// NewRepository creates a new repository service
+// NewRepository creates a new repository service.
+// The server shares one per path through a Registry so its caches outlive a request.
 func NewRepository(basePath string) *Repository {
 	return &Repository{
 		basePath: basePath,
+		items:    newItemCache(),
+		tags:     newTagCache(),
+		keys:     &keySession{},
+		types:    &typeRegistry{},
+		search:   newSearchIndex(),
 	}
 }
 
@@ -31,6 +50,18 @@ func (r *Repository) BasePath() string {
 
 // DeleteItem deletes an item's metadata and content files
 func (r *Repository) DeleteItem(item *models.Item) error {
+	if err := r.ValidateItemRef(item.ID, item.Type); err != nil {
+		return err
+	}
+
+	unlock := r.lock()
+	defer unlock()
+
+	return r.deleteItem(item)
+}
+
+// deleteItem removes an item's files and index entries, the caller holds the lock
+func (r *Repository) deleteItem(item *models.Item) error {
 	// Delete metadata file
 	metaPath := r.getMetaPath(item)
 	if err := os.Remove(metaPath); err != nil && !os.IsNotExist(err) {
@@ -43,11 +74,39 @@ func (r *Repository) DeleteItem(item *models.Item) error {
 		return fmt.Errorf("failed to delete content file: %w", err)
 	}
 
+	// Delete the stored file of file items
+	if item.Type == models.TypeFile {
+		if err := os.RemoveAll(r.fileDir(item)); err != nil {
+			return fmt.Errorf("failed to delete stored file: %w", err)
+		}
+	}
+	r.items.remove(item.Type, item.ID)
+	r.recordChange("Delete", storedMetadata(item))
+
+	// Remove from link index
+	if err := NewLinkService(r).RemoveItem(item); err != nil {
+		return fmt.Errorf("failed to update link relationships: %w", err)
+	}
+
+	// Remove from search index
+	if err := NewSearchService(r).RemoveItem(item); err != nil {
+		return fmt.Errorf("failed to update search index: %w", err)
+	}
+
+	// Remove revisions
+	if err := NewHistoryService(r).RemoveItem(item); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// ListItems returns all items of a given type
+// ListItems returns all items of a given type, newest first.
+// Items come from the in-memory cache, which is filled from disk on first use.
 func (r *Repository) ListItems(itemType models.ItemType) ([]*models.Item, error) {
+	if err := r.ValidateItemType(itemType); err != nil {
+		return nil, err
+	}
 	metaDir := filepath.Join(r.basePath, ".meta", string(itemType)+"s")
 
 	// Create directory if it doesn't exist
@@ -55,40 +114,13 @@ func (r *Repository) ListItems(itemType models.ItemType) ([]*models.Item, error)
 		return nil, fmt.Errorf("failed to create metadata directory: %w", err)
 	}
 
-	entries, err := os.ReadDir(metaDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []*models.Item{}, nil
-		}
-		return nil, fmt.Errorf("failed to read metadata directory: %w", err)
-	}
-
-	var items []*models.Item
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
-			continue
-		}
-
-		id := strings.TrimSuffix(entry.Name(), ".json")
-		item, _, err := r.LoadItem(id, itemType)
-		if err != nil {
-			continue // Skip items that can't be loaded
-		}
-		items = append(items, item)
-	}
-
-	// Sort by modified time, newest first
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].Modified.After(items[j].Modified)
-	})
-
-	return items, nil
+	return r.items.list(r, itemType)
 }
 
 // SaveItem saves an item's metadata and content
 func (r *Repository) SaveItem(item *models.Item, content string) error {
-	// Create a tag service
-	tagService := NewTagService(r)
+	unlock := r.lock()
+	defer unlock()
 
 	// Store previous tags
 	previousTags := make([]string, len(item.Tags))
@@ -98,63 +130,181 @@ func (r *Repository) SaveItem(item *models.Item, content string) error {
 	// This ensures manually set tags are preserved
 	if content != "" && len(item.Tags) == 0 {
 		// Extract tags from content only if no tags were manually set
-		item.Tags = tagService.ExtractTags(content)
+		item.Tags = NewTagService(r).ExtractTags(content)
 	}
 
-	// Save metadata
-	metaPath := r.getMetaPath(item)
-	if err := os.MkdirAll(filepath.Dir(metaPath), 0755); err != nil {
-		return fmt.Errorf("failed to create metadata directory: %w", err)
+	if err := r.saveItem(item, content, content != "", previousTags); err != nil {
+		item.Tags = previousTags
+		return err
 	}
+	return nil
+}
 
-	metaFile, err := os.Create(metaPath)
-	if err != nil {
-		return fmt.Errorf("failed to create metadata file: %w", err)
+// saveItem writes content, metadata and tag index changes as one unit and then updates
+// the derived indexes. If any of the first three fails the files written so far are rolled back.
+// Without writeContent the content on disk is left alone and used for indexing.
+func (r *Repository) saveItem(item *models.Item, content string, writeContent bool, previousTags []string) error {
+	// Type and ID become file paths, so they never reach the disk unchecked
+	if err := r.ValidateItemRef(item.ID, item.Type); err != nil {
+		return err
 	}
-	defer metaFile.Close()
 
-	item.Modified = time.Now().UTC()
-	if err := json.NewEncoder(metaFile).Encode(item); err != nil {
-		return fmt.Errorf("failed to encode metadata: %w", err)
+	previousModified := item.Modified
+	batch := newFileBatch()
+	fail := func(err error) error {
+		item.Modified = previousModified
+		if rollbackErr := batch.rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	contentPath := r.getContentPath(item)
+	if !writeContent {
+		if contentBytes, err := os.ReadFile(contentPath); err == nil {
+			// Metadata-only save, index the content already on disk
+			content = string(contentBytes)
+		}
+	}
+
+	// Encrypted content on disk is opened for indexing. A sealed title and tags live in
+	// the content, so it's sealed again with the new ones.
+	if item.Encrypted && IsSealed(content) {
+		opened, ok := r.openContent(cloneItem(item), content)
+		if !ok && writeContent {
+			// Sealed content can't be checked or sealed again, writing it could corrupt the item
+			return ErrLocked
+		}
+		if ok {
+			content = opened
+			writeContent = writeContent || item.SealMetadata
+		}
+	}
+
+	// Front matter fills in the metadata, or overrides it when the repository prefers content
+	config, err := r.Config()
+	if err != nil {
+		return err
+	}
+	if !item.Encrypted {
+		applyFrontMatter(item, content, config.FrontMatter.contentWins())
+	}
+	if !item.Encrypted && config.FrontMatter.Write && (writeContent || content != "") {
+		synced, err := writeFrontMatter(item, content)
+		if err != nil {
+			return err
+		}
+		if synced != content {
+			content = synced
+			writeContent = true
+		}
 	}
 
 	// Save content if provided
-	if content != "" {
-		contentPath := r.getContentPath(item)
+	if writeContent {
 		if err := os.MkdirAll(filepath.Dir(contentPath), 0755); err != nil {
 			return fmt.Errorf("failed to create content directory: %w", err)
 		}
 
-		if err := os.WriteFile(contentPath, []byte(content), 0644); err != nil {
-			return fmt.Errorf("failed to write content file: %w", err)
+		stored := content
+		if item.Encrypted && !IsSealed(content) {
+			if stored, err = r.sealContent(item, content); err != nil {
+				return fail(err)
+			}
+		}
+		if err := batch.write(contentPath, []byte(stored), 0644); err != nil {
+			return fail(fmt.Errorf("failed to write content file: %w", err))
 		}
 	}
 
-	// Update tag relationships (moved outside the content conditional)
+	// Items written by older versions are upgraded as they are saved
+	if _, err := migrateItem(item, content); err != nil {
+		return fail(err)
+	}
+
+	// Save metadata
+	metaPath := r.getMetaPath(item)
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0755); err != nil {
+		return fail(fmt.Errorf("failed to create metadata directory: %w", err))
+	}
+
+	_, statErr := os.Stat(metaPath)
+	created := os.IsNotExist(statErr)
+
+	item.Modified = time.Now().UTC()
+	metadata := storedMetadata(item)
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fail(fmt.Errorf("failed to encode metadata: %w", err))
+	}
+	if err := batch.write(metaPath, append(data, '\n'), 0644); err != nil {
+		return fail(fmt.Errorf("failed to write metadata file: %w", err))
+	}
+
+	// Update tag relationships
+	tagService := NewTagService(r)
+	tagService.batch = batch
 	if err := tagService.UpdateItemTags(item, previousTags); err != nil {
-		return fmt.Errorf("failed to update tag relationships: %w", err)
+		return fail(fmt.Errorf("failed to update tag relationships: %w", err))
+	}
+	r.items.put(metadata)
+	if created {
+		r.recordChange("Add", metadata)
+	} else {
+		r.recordChange("Update", metadata)
+	}
+
+	// The remaining indexes are derived from the saved files and can be rebuilt
+
+	// Update wiki-link relationships
+	linkService := NewLinkService(r)
+	if err := linkService.UpdateItemLinks(item, content); err != nil {
+		return fmt.Errorf("failed to update link relationships: %w", err)
+	}
+	// Links made before the item existed or had its title now point to it
+	if err := linkService.ResolvePending(item); err != nil {
+		return fmt.Errorf("failed to update link relationships: %w", err)
+	}
+
+	// Update search index
+	if err := NewSearchService(r).IndexItem(item, content); err != nil {
+		return fmt.Errorf("failed to update search index: %w", err)
+	}
+
+	// Keep a revision of the content
+	if err := NewHistoryService(r).Record(item, content); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
 	}
 
 	return nil
 }
 
-// LoadItem loads an item's metadata and optionally its content
-func (r *Repository) LoadItem(id string, itemType models.ItemType) (*models.Item, string, error) {
-	item := &models.Item{
-		ID:   id,
-		Type: itemType,
+// reindexItem brings the derived indexes in line with an item as stored, without writing the
+// item itself. previousTags are the tags the tag index lists the item under. Items without
+// history get their first revision, so later edits made elsewhere can be told apart.
+// The caller must hold the save lock.
+func (r *Repository) reindexItem(item *models.Item, content string, previousTags []string) error {
+	if err := NewTagService(r).UpdateItemTags(item, previousTags); err != nil {
+		return fmt.Errorf("failed to update tag relationships: %w", err)
 	}
+	if err := NewLinkService(r).UpdateItemLinks(item, content); err != nil {
+		return fmt.Errorf("failed to update link relationships: %w", err)
+	}
+	if err := NewSearchService(r).IndexItem(item, content); err != nil {
+		return fmt.Errorf("failed to update search index: %w", err)
+	}
+	if err := NewHistoryService(r).Record(item, content); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
 
+// LoadItem loads an item's metadata and optionally its content
+func (r *Repository) LoadItem(id string, itemType models.ItemType) (*models.Item, string, error) {
 	// Load metadata
-	metaPath := r.getMetaPath(item)
-	metaFile, err := os.Open(metaPath)
+	item, err := r.loadMetadata(id, itemType)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to open metadata file: %w", err)
-	}
-	defer metaFile.Close()
-
-	if err := json.NewDecoder(metaFile).Decode(item); err != nil {
-		return nil, "", fmt.Errorf("failed to decode metadata: %w", err)
+		return nil, "", err
 	}
 
 	// Load content if it exists
@@ -166,43 +316,267 @@ func (r *Repository) LoadItem(id string, itemType models.ItemType) (*models.Item
 		return nil, "", fmt.Errorf("failed to read content file: %w", err)
 	}
 
+	// Encrypted content is opened while the repository is unlocked. Otherwise it's
+	// returned sealed, which is never an error so locked items still list and load.
+	if item.Encrypted {
+		if opened, ok := r.openContent(item, content); ok {
+			content = opened
+		}
+		return item, content, nil
+	}
+
+	// Front matter edited elsewhere shows up before the watcher saves it to the metadata
+	if content != "" {
+		if config, err := r.Config(); err == nil {
+			applyFrontMatter(item, content, config.FrontMatter.contentWins())
+		}
+	}
+
 	return item, content, nil
 }
 
 // UpdateContent updates an item's content
 func (r *Repository) UpdateContent(item *models.Item, content string) error {
-	// Create content directory if it doesn't exist
-	contentPath := r.getContentPath(item)
-	if err := os.MkdirAll(filepath.Dir(contentPath), 0755); err != nil {
-		return fmt.Errorf("failed to create content directory: %w", err)
+	unlock := r.lock()
+	defer unlock()
+
+	return r.updateContent(item, content)
+}
+
+// UpdateContentIfMatch updates an item's content only if the item on disk still matches
+// the If-Match value, a list of ETags from ItemETag or "*". Otherwise it returns ErrConflict.
+// It returns the ETag of the item as saved, which can differ from the one of the content
+// given, for instance when front matter is written.
+func (r *Repository) UpdateContentIfMatch(item *models.Item, content, ifMatch string) (string, error) {
+	unlock := r.lock()
+	defer unlock()
+
+	current, currentContent, err := r.LoadItem(item.ID, item.Type)
+	if err != nil {
+		return "", err
+	}
+	if !etagMatches(ifMatch, ItemETag(current, currentContent)) {
+		return "", fmt.Errorf("%w: %s %s", ErrConflict, item.Type, item.ID)
 	}
 
+	if err := r.updateContent(item, content); err != nil {
+		return "", err
+	}
+	saved, savedContent, err := r.LoadItem(item.ID, item.Type)
+	if err != nil {
+		return "", err
+	}
+	return ItemETag(saved, savedContent), nil
+}
+
+// updateContent replaces an item's content and tags, the caller must hold the save lock
+func (r *Repository) updateContent(item *models.Item, content string) error {
 	// Store previous tags
 	previousTags := make([]string, len(item.Tags))
 	copy(previousTags, item.Tags)
 
-	// Extract new tags from content
-	tagService := NewTagService(r)
-	extractedTags := tagService.ExtractTags(content)
-
-	// Replace the item's tags with the extracted ones
-	item.Tags = extractedTags
+	// Replace the item's tags with the ones extracted from content
+	item.Tags = NewTagService(r).ExtractTags(content)
 
-	// Write content to file
-	if err := os.WriteFile(contentPath, []byte(content), 0644); err != nil {
-		// Restore original tags if content write fails
+	if err := r.saveItem(item, content, true, previousTags); err != nil {
+		// Restore original tags if the save fails
 		item.Tags = previousTags
-		return fmt.Errorf("failed to write content file: %w", err)
+		return err
 	}
+	return nil
+}
 
-	// Update tag relationships
-	if err := tagService.UpdateItemTags(item, previousTags); err != nil {
+// ConvertItem changes the type of an item, moving its metadata and content
+// and updating the tag index, link index, search index, history and workstream references
+func (r *Repository) ConvertItem(item *models.Item, newType models.ItemType) error {
+	if item.Type == newType {
+		return nil
+	}
+	if item.Type == models.TypeFile || newType == models.TypeFile {
+		return ErrFileConversion
+	}
+	if err := r.ValidateItemType(newType); err != nil {
+		return err
+	}
+
+	converted := *item
+	converted.Type = newType
+	if _, err := os.Stat(r.getMetaPath(&converted)); err == nil {
+		return fmt.Errorf("a %s with ID %s already exists", newType, item.ID)
+	}
+
+	_, content, err := r.LoadItem(item.ID, item.Type)
+	if err != nil {
+		return err
+	}
+
+	if newType == models.TypeTask && converted.Status == "" {
+		converted.Status = models.TaskStatusTodo
+	}
+
+	// Carry the revisions over to the new type
+	if err := NewHistoryService(r).MoveItem(item, &converted); err != nil {
+		return err
+	}
+
+	// Save under the new type first so a failure never loses the item
+	if err := r.SaveItem(&converted, content); err != nil {
+		return err
+	}
+
+	// Remember who links to the item before its link files are removed
+	linkService := NewLinkService(r)
+	backlinks, err := linkService.Backlinks(item)
+	if err != nil {
+		return err
+	}
+
+	// Remove the old files and tag entries
+	if err := r.DeleteItem(item); err != nil {
+		return err
+	}
+	previous := *item
+	previous.Tags = []string{}
+	if err := NewTagService(r).UpdateItemTags(&previous, item.Tags); err != nil {
 		return fmt.Errorf("failed to update tag relationships: %w", err)
 	}
 
-	// Update modification time in metadata
-	item.Modified = time.Now().UTC()
-	return r.SaveItem(item, "")
+	// Point workstreams at the converted item
+	oldRef := ItemRef(item)
+	newRef := ItemRef(&converted)
+	workstreams, err := r.ListItems(models.TypeWorkstream)
+	if err != nil {
+		return err
+	}
+	for _, workstream := range workstreams {
+		changed := false
+		for i, ref := range workstream.Items {
+			if ref == oldRef {
+				workstream.Items[i] = newRef
+				changed = true
+			}
+		}
+		if changed {
+			if err := r.SaveItem(workstream, ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Re-index linking items so their backlinks point at the converted item
+	for _, backlink := range backlinks {
+		id, itemType, ok := parseItemRef(backlink.Source)
+		if !ok {
+			continue
+		}
+		source, sourceContent, err := r.LoadItem(id, itemType)
+		if err != nil {
+			continue
+		}
+		if err := linkService.UpdateItemLinks(source, sourceContent); err != nil {
+			return fmt.Errorf("failed to update link relationships: %w", err)
+		}
+	}
+
+	*item = converted
+	return nil
+}
+
+// adoptContent creates metadata for a content file that has none, taking the title and tags from the content
+func (r *Repository) adoptContent(itemType models.ItemType, id string) error {
+	unlock := r.lock()
+	defer unlock()
+
+	// A save may have written the metadata since the files were checked
+	item := models.NewItem(itemType, id)
+	if _, err := os.Stat(r.getMetaPath(item)); !os.IsNotExist(err) {
+		return nil
+	}
+	contentPath := r.getContentPath(item)
+	data, err := os.ReadFile(contentPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read content file: %w", err)
+	}
+	content := string(data)
+
+	if info, err := os.Stat(contentPath); err == nil {
+		item.Created = info.ModTime().UTC()
+	}
+	item.Title = ContentTitle(content, item.Type)
+	if item.Title == "" {
+		item.Title = item.ID
+	}
+	if item.Type == models.TypeTask {
+		item.Status = models.TaskStatusTodo
+	}
+	item.Tags = NewTagService(r).ExtractTags(content)
+
+	return r.saveItem(item, content, false, nil)
+}
+
+// forgetItem drops an item whose files were removed outside Vovere from every index.
+// indexedTags are the tags the tag index still holds for it.
+func (r *Repository) forgetItem(item *models.Item, indexedTags []string) error {
+	if err := r.ValidateItemRef(item.ID, item.Type); err != nil {
+		return err
+	}
+
+	unlock := r.lock()
+	defer unlock()
+
+	// A restore may have put the item back since the files were checked
+	for _, path := range []string{r.getMetaPath(item), r.getContentPath(item)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	previous := *item
+	previous.Tags = []string{}
+	if err := NewTagService(r).UpdateItemTags(&previous, indexedTags); err != nil {
+		return fmt.Errorf("failed to update tag relationships: %w", err)
+	}
+	return r.deleteItem(item)
+}
+
+// writeMetadata rewrites an item's metadata file as is, keeping its modification time
+func (r *Repository) writeMetadata(item *models.Item) error {
+	item = storedMetadata(item)
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	if err := writeFileAtomic(r.getMetaPath(item), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	r.items.put(item)
+	return nil
+}
+
+// ItemRef returns the combined "id:type" reference used by the tag index and workstreams
+func ItemRef(item *models.Item) string {
+	return fmt.Sprintf("%s:%s", item.ID, item.Type)
+}
+
+// ItemETag returns the entity tag of an item's current state, derived from its
+// modification time and a hash of its content
+func ItemETag(item *models.Item, content string) string {
+	return fmt.Sprintf(`"%x-%s"`, item.Modified.UnixNano(), contentHash(content)[:16])
+}
+
+// etagMatches reports whether an If-Match value matches an ETag.
+// Weak validators are compared by their opaque part.
+func etagMatches(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
 }
 
 // getMetaPath returns the metadata file path for an item
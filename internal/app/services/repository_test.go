@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -210,3 +211,34 @@ func TestDeleteItem(t *testing.T) {
 		t.Errorf("Item should no longer exist after deletion")
 	}
 }
+
+func TestUpdateContentIfMatch(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	item := models.NewItem(models.TypeNote, "etag-test")
+	if err := repo.SaveItem(item, "Version 1"); err != nil {
+		t.Fatalf("Failed to save item: %v", err)
+	}
+	etag := ItemETag(item, "Version 1")
+
+	if _, err := repo.UpdateContentIfMatch(item, "Version 2", `"other", `+etag); err != nil {
+		t.Fatalf("Expected matching ETag in a list to be accepted: %v", err)
+	}
+
+	stale, _, _ := repo.LoadItem("etag-test", models.TypeNote)
+	if _, err := repo.UpdateContentIfMatch(stale, "Version 3", etag); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for a stale ETag, got %v", err)
+	}
+	if _, err := repo.UpdateContentIfMatch(stale, "Version 3", "W/"+ItemETag(item, "Version 2")); err != nil {
+		t.Errorf("Expected weak ETag to match: %v", err)
+	}
+	if _, err := repo.UpdateContentIfMatch(stale, "Version 4", "*"); err != nil {
+		t.Errorf("Expected * to match: %v", err)
+	}
+
+	_, content, _ := repo.LoadItem("etag-test", models.TypeNote)
+	if content != "Version 4" {
+		t.Errorf("Expected 'Version 4', got %q", content)
+	}
+}
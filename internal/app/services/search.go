@@ -0,0 +1,678 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"vovere/internal/app/models"
+	"vovere/internal/markdown"
+)
+
+const (
+	// Field weights applied to term frequencies when indexing
+	titleWeight   = 3
+	tagWeight     = 2
+	typeWeight    = 1
+	contentWeight = 1
+
+	// BM25 tuning parameters
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// snippetLength is the approximate number of characters shown in a snippet
+	snippetLength = 160
+)
+
+// SearchService maintains the full-text index of a repository
+type SearchService struct {
+	repo *Repository
+}
+
+// searchIndex keeps the documents of a repository's search index in memory, with an inverted
+// index from each term to the documents holding it. It is read from .meta/index on the first
+// query and updated by every save, so queries never read the documents from disk.
+type searchIndex struct {
+	mu     sync.RWMutex
+	loaded bool
+	// docs maps item references, see ItemRef, to their documents
+	docs map[string]*IndexDocument
+	// postings maps each term to the weighted frequency of the term in the documents holding it
+	postings map[string]map[string]int
+	// length is the total length of the documents, for BM25 normalisation
+	length int
+}
+
+// corpusStats are the statistics of the searched documents that ranking needs
+type corpusStats struct {
+	docs    int
+	length  int
+	docFreq map[string]int
+}
+
+// NewSearchService creates a new search service
+func NewSearchService(repo *Repository) *SearchService {
+	return &SearchService{
+		repo: repo,
+	}
+}
+
+// IndexDocument is the indexed representation of an item stored under .meta/index
+type IndexDocument struct {
+	ID       string            `json:"id"`
+	Type     models.ItemType   `json:"type"`
+	Title    string            `json:"title"`
+	Tags     []string          `json:"tags"`
+	Content  string            `json:"content"`
+	Created  time.Time         `json:"created"`
+	Modified time.Time         `json:"modified"`
+	Status   models.TaskStatus `json:"status,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Filename string            `json:"filename,omitempty"`
+	Terms    map[string]int    `json:"terms"`
+	Length   int               `json:"length"`
+}
+
+// SearchResult represents a ranked match for a search query
+type SearchResult struct {
+	ID       string          `json:"id"`
+	Type     models.ItemType `json:"type"`
+	Title    string          `json:"title"`
+	Tags     []string        `json:"tags"`
+	Modified time.Time       `json:"modified"`
+	Score    float64         `json:"score"`
+	// Snippet is HTML-escaped text with matching terms wrapped in <mark>
+	Snippet string `json:"snippet"`
+}
+
+// IndexItem adds or replaces an item in the search index.
+// Nothing is written until the index exists; the first search builds it from scratch.
+func (s *SearchService) IndexItem(item *models.Item, content string) error {
+	// Encrypted items are searched in memory while the repository is unlocked, see Query
+	if item.Encrypted {
+		return s.RemoveItem(item)
+	}
+
+	doc := newIndexDocument(item, content)
+	s.repo.search.put(doc)
+
+	if _, err := os.Stat(s.indexDir()); os.IsNotExist(err) {
+		return nil
+	}
+	return s.saveDocument(doc)
+}
+
+// newIndexDocument builds the index document of an item
+func newIndexDocument(item *models.Item, content string) *IndexDocument {
+	// Front matter fields are indexed through the item, not as text
+	content = markdown.StripFrontMatter(content)
+
+	doc := &IndexDocument{
+		ID:       item.ID,
+		Type:     item.Type,
+		Title:    item.Title,
+		Tags:     item.Tags,
+		Content:  content,
+		Created:  item.Created,
+		Modified: item.Modified,
+		Status:   item.Status,
+		URL:      item.URL,
+		Filename: item.Filename,
+		Terms:    make(map[string]int),
+	}
+
+	addTerms := func(text string, weight int) {
+		for _, term := range Tokenize(text) {
+			doc.Terms[term] += weight
+			doc.Length += weight
+		}
+	}
+
+	addTerms(item.Title, titleWeight)
+	addTerms(strings.Join(item.Tags, " "), tagWeight)
+	addTerms(string(item.Type), typeWeight)
+	addTerms(content, contentWeight)
+
+	return doc
+}
+
+// RemoveItem removes an item from the search index
+func (s *SearchService) RemoveItem(item *models.Item) error {
+	s.repo.search.remove(ItemRef(item))
+
+	docPath := s.documentPath(item.ID, item.Type)
+	if err := os.Remove(docPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete index document: %w", err)
+	}
+	return nil
+}
+
+// Rebuild discards the search index and indexes every item again
+func (s *SearchService) Rebuild() error {
+	if err := os.RemoveAll(s.indexDir()); err != nil {
+		return fmt.Errorf("failed to clear search index: %w", err)
+	}
+
+	if err := os.MkdirAll(s.indexDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create search index directory: %w", err)
+	}
+	s.repo.search.clear()
+
+	for _, itemType := range s.repo.ItemTypes() {
+		items, err := s.repo.ListItems(itemType)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			_, content, err := s.repo.LoadItem(item.ID, item.Type)
+			if err != nil {
+				continue // Skip items that can't be loaded
+			}
+			if err := s.IndexItem(item, content); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Search parses a query string and returns the matching items, best matches first.
+// See Query for the supported syntax.
+func (s *SearchService) Search(query string, limit int) ([]SearchResult, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(q, limit)
+}
+
+// Query returns the items matching a parsed query, best matches first.
+// Queries without full-text terms are ordered by modification time.
+func (s *SearchService) Query(q *Query, limit int) ([]SearchResult, error) {
+	if q.IsEmpty() {
+		return []SearchResult{}, nil
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	// Encrypted items never reach the index, they are searched while unlocked
+	encrypted, contents, err := s.repo.unlockedItems()
+	if err != nil {
+		return nil, err
+	}
+	unlocked := make([]*IndexDocument, len(encrypted))
+	for i, item := range encrypted {
+		unlocked[i] = newIndexDocument(item, contents[i])
+	}
+
+	results := s.repo.search.query(q, unlocked)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// Tokenize splits text into lowercase terms for indexing and querying
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Private helper methods
+
+// indexDir returns the directory holding the search index
+func (s *SearchService) indexDir() string {
+	return filepath.Join(s.repo.BasePath(), ".meta", "index")
+}
+
+// documentPath returns the index document path for an item
+func (s *SearchService) documentPath(id string, itemType models.ItemType) string {
+	return filepath.Join(s.indexDir(), string(itemType)+"s", id+".json")
+}
+
+// document returns the indexed document of an item, nil when the index doesn't hold it.
+// indexed reports whether the repository has a search index at all.
+func (s *SearchService) document(item *models.Item) (doc *IndexDocument, indexed bool) {
+	if doc, loaded := s.repo.search.document(ItemRef(item)); loaded {
+		return doc, true
+	}
+
+	data, err := os.ReadFile(s.documentPath(item.ID, item.Type))
+	if err != nil {
+		_, statErr := os.Stat(s.indexDir())
+		return nil, statErr == nil
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, true
+	}
+	return doc, true
+}
+
+// saveDocument writes an index document to disk
+func (s *SearchService) saveDocument(doc *IndexDocument) error {
+	docPath := s.documentPath(doc.ID, doc.Type)
+	if err := os.MkdirAll(filepath.Dir(docPath), 0755); err != nil {
+		return fmt.Errorf("failed to create search index directory: %w", err)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index document: %w", err)
+	}
+
+	if err := writeFileAtomic(docPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write index document: %w", err)
+	}
+
+	return nil
+}
+
+// load reads the search index into memory on first use, building it if it doesn't exist
+func (s *SearchService) load() error {
+	index := s.repo.search
+	index.mu.RLock()
+	loaded := index.loaded
+	index.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	if _, err := os.Stat(s.indexDir()); os.IsNotExist(err) {
+		return s.Rebuild()
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.loaded {
+		return nil
+	}
+
+	docs, err := s.readDocuments()
+	if err != nil {
+		return err
+	}
+	index.reset()
+	for _, doc := range docs {
+		index.add(doc)
+	}
+	index.loaded = true
+	return nil
+}
+
+// readDocuments reads every index document from disk
+func (s *SearchService) readDocuments() ([]*IndexDocument, error) {
+	var docs []*IndexDocument
+	for _, itemType := range s.repo.ItemTypes() {
+		typeDir := filepath.Join(s.indexDir(), string(itemType)+"s")
+		entries, err := os.ReadDir(typeDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read search index directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+				continue
+			}
+
+			data, err := os.ReadFile(filepath.Join(typeDir, entry.Name()))
+			if err != nil {
+				continue // Skip documents that can't be read
+			}
+
+			var doc IndexDocument
+			if err := json.Unmarshal(data, &doc); err != nil {
+				continue // Skip documents that can't be decoded
+			}
+			docs = append(docs, &doc)
+		}
+	}
+
+	return docs, nil
+}
+
+// newSearchIndex creates an empty search index, loaded on first use
+func newSearchIndex() *searchIndex {
+	index := &searchIndex{}
+	index.reset()
+	return index
+}
+
+// reset empties the index
+func (idx *searchIndex) reset() {
+	idx.docs = make(map[string]*IndexDocument)
+	idx.postings = make(map[string]map[string]int)
+	idx.length = 0
+}
+
+// clear empties the index and marks it loaded, before every item is indexed again
+func (idx *searchIndex) clear() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.reset()
+	idx.loaded = true
+}
+
+// put adds or replaces a document. Until the index is loaded there is nothing to update.
+func (idx *searchIndex) put(doc *IndexDocument) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.loaded {
+		return
+	}
+	idx.drop(ItemRef(doc.item()))
+	idx.add(doc)
+}
+
+// remove drops the document of an item reference
+func (idx *searchIndex) remove(ref string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.loaded {
+		idx.drop(ref)
+	}
+}
+
+// document returns the document of an item reference, loaded reports whether the index is in memory
+func (idx *searchIndex) document(ref string) (doc *IndexDocument, loaded bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.docs[ref], idx.loaded
+}
+
+// add indexes a document, the caller holds the lock
+func (idx *searchIndex) add(doc *IndexDocument) {
+	ref := ItemRef(doc.item())
+	idx.docs[ref] = doc
+	idx.length += doc.Length
+	for term, count := range doc.Terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]int)
+		}
+		idx.postings[term][ref] = count
+	}
+}
+
+// drop removes the document of an item reference, the caller holds the lock
+func (idx *searchIndex) drop(ref string) {
+	doc, ok := idx.docs[ref]
+	if !ok {
+		return
+	}
+	delete(idx.docs, ref)
+	idx.length -= doc.Length
+	for term := range doc.Terms {
+		delete(idx.postings[term], ref)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+}
+
+// holding returns the references of the documents holding a term, or with prefix set,
+// any term starting with it. The result must not be changed.
+func (idx *searchIndex) holding(term string, prefix bool) map[string]int {
+	if !prefix {
+		return idx.postings[term]
+	}
+
+	refs := make(map[string]int)
+	for indexed, postings := range idx.postings {
+		if strings.HasPrefix(indexed, term) {
+			for ref, count := range postings {
+				refs[ref] += count
+			}
+		}
+	}
+	return refs
+}
+
+// query returns the documents matching a query, ranked, along with extra documents that
+// aren't kept in the index
+func (idx *searchIndex) query(q *Query, extra []*IndexDocument) []SearchResult {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms, prefix := q.Terms(), q.prefixTerm()
+
+	// Every positive full-text clause must match, so the documents holding the rarest of
+	// their terms are the only candidates. Without such clauses every document is one.
+	var candidates map[string]int
+	constrained := false
+	for _, clause := range q.Clauses {
+		if clause.Field != "" || clause.Negated {
+			continue
+		}
+		for _, term := range strings.Fields(clause.Values[0]) {
+			refs := idx.holding(term, !clause.Phrase && term == prefix)
+			if !constrained || len(refs) < len(candidates) {
+				candidates, constrained = refs, true
+			}
+		}
+	}
+
+	docs := make([]*IndexDocument, 0, len(extra))
+	if constrained {
+		for ref := range candidates {
+			docs = append(docs, idx.docs[ref])
+		}
+	} else {
+		for _, doc := range idx.docs {
+			docs = append(docs, doc)
+		}
+	}
+	docs = append(docs, extra...)
+
+	// Keep the documents satisfying every clause
+	matching := make([]*IndexDocument, 0)
+	for _, doc := range docs {
+		item := doc.item()
+		if q.matches(item, &matchText{item: item, content: doc.Content, terms: doc.Terms}) {
+			matching = append(matching, doc)
+		}
+	}
+
+	corpus := corpusStats{
+		docs:    len(idx.docs) + len(extra),
+		length:  idx.length,
+		docFreq: make(map[string]int),
+	}
+	for _, doc := range extra {
+		corpus.length += doc.Length
+	}
+	for _, term := range terms {
+		corpus.docFreq[term] = len(idx.holding(term, term == prefix))
+		for _, doc := range extra {
+			if termFrequency(doc, term, term == prefix) > 0 {
+				corpus.docFreq[term]++
+			}
+		}
+	}
+
+	return rankDocuments(corpus, matching, terms, prefix)
+}
+
+// rankDocuments scores matching documents against query terms using BM25
+func rankDocuments(corpus corpusStats, matching []*IndexDocument, terms []string, prefix string) []SearchResult {
+	results := make([]SearchResult, 0, len(matching))
+	if len(matching) == 0 {
+		return results
+	}
+
+	// Average document length for BM25 normalisation
+	avgLength := float64(corpus.length) / float64(corpus.docs)
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	for _, doc := range matching {
+		score := 0.0
+		for _, term := range terms {
+			tf := termFrequency(doc, term, term == prefix)
+			if tf == 0 {
+				continue
+			}
+
+			n := float64(corpus.docs)
+			df := float64(corpus.docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(doc.Length)/avgLength)
+			score += idf * (tf * (bm25K1 + 1)) / (tf + norm)
+		}
+
+		results = append(results, SearchResult{
+			ID:       doc.ID,
+			Type:     doc.Type,
+			Title:    doc.Title,
+			Tags:     append([]string{}, doc.Tags...),
+			Modified: doc.Modified,
+			Score:    score,
+			Snippet:  buildSnippet(doc.Content, terms),
+		})
+	}
+
+	// Best score first, most recently modified on ties
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Modified.After(results[j].Modified)
+	})
+
+	return results
+}
+
+// item returns the indexed fields of a document as an item
+func (doc *IndexDocument) item() *models.Item {
+	return &models.Item{
+		ID:       doc.ID,
+		Type:     doc.Type,
+		Title:    doc.Title,
+		Tags:     doc.Tags,
+		Created:  doc.Created,
+		Modified: doc.Modified,
+		Status:   doc.Status,
+		URL:      doc.URL,
+		Filename: doc.Filename,
+	}
+}
+
+// termFrequency returns the weighted frequency of a term in a document.
+// Prefix matches count at half weight.
+func termFrequency(doc *IndexDocument, term string, prefix bool) float64 {
+	tf := float64(doc.Terms[term])
+	if !prefix {
+		return tf
+	}
+
+	for indexed, count := range doc.Terms {
+		if indexed != term && strings.HasPrefix(indexed, term) {
+			tf += float64(count) / 2
+		}
+	}
+	return tf
+}
+
+// buildSnippet extracts a short excerpt around the first matching term and highlights matches
+func buildSnippet(content string, terms []string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if content == "" {
+		return ""
+	}
+
+	// Fall back to case-sensitive matching if lowercasing changes byte offsets
+	lower := strings.ToLower(content)
+	if len(lower) != len(content) {
+		lower = content
+	}
+
+	// Find the earliest occurrence of any query term
+	start := -1
+	for _, term := range terms {
+		if idx := strings.Index(lower, term); idx != -1 && (start == -1 || idx < start) {
+			start = idx
+		}
+	}
+
+	// Center the window on the match, aligned to rune boundaries
+	from := 0
+	if start > snippetLength/3 {
+		from = start - snippetLength/3
+	}
+	to := from + snippetLength
+	if to > len(content) {
+		to = len(content)
+	}
+	for from > 0 && !isRuneStart(content[from]) {
+		from--
+	}
+	for to < len(content) && !isRuneStart(content[to]) {
+		to++
+	}
+
+	excerpt := content[from:to]
+	lowerExcerpt := lower[from:to]
+
+	// Wrap every occurrence of a query term in <mark>
+	var buf strings.Builder
+	if from > 0 {
+		buf.WriteString("…")
+	}
+
+	i := 0
+	for i < len(excerpt) {
+		matched := 0
+		for _, term := range terms {
+			if strings.HasPrefix(lowerExcerpt[i:], term) && len(term) > matched {
+				matched = len(term)
+			}
+		}
+
+		if matched > 0 {
+			buf.WriteString("<mark>")
+			buf.WriteString(html.EscapeString(excerpt[i : i+matched]))
+			buf.WriteString("</mark>")
+			i += matched
+			continue
+		}
+
+		// Copy text up to the next possible match
+		next := i + 1
+		for next < len(excerpt) && !isRuneStart(excerpt[next]) {
+			next++
+		}
+		buf.WriteString(html.EscapeString(excerpt[i:next]))
+		i = next
+	}
+
+	if to < len(content) {
+		buf.WriteString("…")
+	}
+
+	return buf.String()
+}
+
+// isRuneStart reports whether a byte starts a UTF-8 encoded rune
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
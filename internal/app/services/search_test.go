@@ -0,0 +1,119 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"release", "notes", "v2", "café"}, Tokenize("Release-notes: v2, Café!"))
+	assert.Empty(t, Tokenize("  ...  "))
+}
+
+func TestSearch(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	searchService := NewSearchService(repo)
+
+	alpha := models.NewItem(models.TypeNote, "alpha")
+	alpha.Title = "Release notes"
+	require.NoError(t, repo.SaveItem(alpha, "# Release notes\n\nWhat changed in the latest release of the #project."))
+
+	beta := models.NewItem(models.TypeNote, "beta")
+	beta.Title = "Meeting"
+	require.NoError(t, repo.SaveItem(beta, "Discussed the release schedule with the team."))
+
+	gamma := models.NewItem(models.TypeTask, "gamma")
+	gamma.Title = "Groceries"
+	require.NoError(t, repo.SaveItem(gamma, "Buy milk and bread."))
+
+	t.Run("IndexIsBuiltOnFirstSearch", func(t *testing.T) {
+		results, err := searchService.Search("release", 0)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		// The title match ranks first
+		assert.Equal(t, "alpha", results[0].ID)
+		assert.Equal(t, "beta", results[1].ID)
+
+		_, err = os.Stat(filepath.Join(tempDir, ".meta", "index", "notes", "alpha.json"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("SnippetHighlightsMatches", func(t *testing.T) {
+		results, err := searchService.Search("schedule", 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Contains(t, results[0].Snippet, "<mark>schedule</mark>")
+	})
+
+	t.Run("AllTermsMustMatch", func(t *testing.T) {
+		results, err := searchService.Search("release milk", 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("LastTermMatchesAsPrefix", func(t *testing.T) {
+		results, err := searchService.Search("groc", 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "gamma", results[0].ID)
+	})
+
+	t.Run("MatchesTagsAndType", func(t *testing.T) {
+		results, err := searchService.Search("project", 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "alpha", results[0].ID)
+
+		results, err = searchService.Search("task", 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "gamma", results[0].ID)
+	})
+
+	t.Run("UpdatesIncrementally", func(t *testing.T) {
+		require.NoError(t, repo.UpdateContent(gamma, "Buy coffee beans."))
+
+		results, err := searchService.Search("milk", 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		results, err = searchService.Search("coffee", 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.True(t, strings.Contains(results[0].Snippet, "<mark>coffee</mark>"))
+	})
+
+	t.Run("RemovesDeletedItems", func(t *testing.T) {
+		require.NoError(t, repo.DeleteItem(beta))
+
+		results, err := searchService.Search("release", 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "alpha", results[0].ID)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		results, err := searchService.Search("note", 1)
+		require.NoError(t, err)
+		assert.Len(t, results, 1)
+	})
+
+	t.Run("QueriesDontReadDocuments", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(filepath.Join(tempDir, ".meta", "index", "notes")))
+
+		results, err := searchService.Search("release", 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "alpha", results[0].ID)
+	})
+}
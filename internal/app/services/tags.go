@@ -6,32 +6,57 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
-	"sync"
+
 	"vovere/internal/app/models"
+	"vovere/internal/markdown"
 )
 
 // TagService handles operations related to tags
 type TagService struct {
-	repo      *Repository
-	cacheLock sync.RWMutex
-	tagCache  map[string][]string // map[tagName][]itemIDs
+	repo *Repository
+	// cache is shared by every tag service of the repository
+	cache *tagCache
+	// batch, when set, records tag file changes so a failed save can undo them
+	batch *fileBatch
 }
 
 // NewTagService creates a new tag service
 func NewTagService(repo *Repository) *TagService {
-	return &TagService{
-		repo:     repo,
-		tagCache: make(map[string][]string),
+	service := &TagService{
+		repo: repo,
+	}
+	// Extracting tags doesn't need a repository
+	if repo != nil {
+		service.cache = repo.tags
 	}
+	return service
 }
 
-// ExtractTags extracts hashtags from content
+// ExtractTags extracts the tags of content: hashtags in the body and tags listed in the front matter
 func (s *TagService) ExtractTags(content string) []string {
 	if content == "" {
 		return nil
 	}
 
+	tags := make([]string, 0)
+	candidates := append(extractHashtags(markdown.StripFrontMatter(content)), frontMatterTags(content)...)
+	for _, tag := range candidates {
+		// Tags that can't be stored, like front matter tags with spaces, are left out
+		if ValidateTag(tag) == nil && !contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// extractHashtags extracts hashtags from markdown without front matter
+func extractHashtags(content string) []string {
+	if content == "" {
+		return nil
+	}
+
 	// Match hashtags with a much broader range of characters
 	// Rules:
 	// 1. Must start with # preceded by space or beginning of line
@@ -75,14 +100,14 @@ func (s *TagService) UpdateItemTags(item *models.Item, previousTags []string) er
 	currentTags := make([]string, len(item.Tags))
 	copy(currentTags, item.Tags)
 
+	// Encrypted items stay out of the tag index
+	if item.Encrypted {
+		currentTags = nil
+	}
+
 	// Create combined ID
 	combinedID := fmt.Sprintf("%s:%s", item.ID, item.Type)
 
-	// Clear tag cache outside the lock
-	s.cacheLock.Lock()
-	s.tagCache = make(map[string][]string)
-	s.cacheLock.Unlock()
-
 	// First, remove item from all previous tags that are no longer present
 	for _, oldTag := range previousTags {
 		if !contains(currentTags, oldTag) {
@@ -122,9 +147,20 @@ func (s *TagService) GetItemsByTag(tag string) ([]*models.Item, error) {
 		itemID := parts[0]
 		itemType := models.ItemType(parts[1])
 
-		// Load item
-		item, _, err := s.repo.LoadItem(itemID, itemType)
-		if err == nil {
+		// Load item from the cache
+		item, found, err := s.repo.items.get(s.repo, itemID, itemType)
+		if err == nil && found {
+			items = append(items, item)
+		}
+	}
+
+	// Encrypted items aren't in the index, they are matched while the repository is unlocked
+	encrypted, _, err := s.repo.unlockedItems()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range encrypted {
+		if contains(item.Tags, tag) {
 			items = append(items, item)
 		}
 	}
@@ -154,7 +190,7 @@ func (s *TagService) GetAllTags() ([]string, error) {
 			// Remove the .json extension
 			name := entry.Name()
 			if filepath.Ext(name) == ".json" {
-				tags = append(tags, name[:len(name)-5])
+				tags = append(tags, tagFromFileName(name[:len(name)-5]))
 			}
 		}
 	}
@@ -162,6 +198,28 @@ func (s *TagService) GetAllTags() ([]string, error) {
 	return tags, nil
 }
 
+// GetTagsForItem returns the tags whose index lists the item
+func (s *TagService) GetTagsForItem(item *models.Item) ([]string, error) {
+	tags, err := s.GetAllTags()
+	if err != nil {
+		return nil, err
+	}
+
+	combinedID := fmt.Sprintf("%s:%s", item.ID, item.Type)
+	var itemTags []string
+	for _, tag := range tags {
+		itemIDs, err := s.getItemIDsByTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if contains(itemIDs, combinedID) {
+			itemTags = append(itemTags, tag)
+		}
+	}
+
+	return itemTags, nil
+}
+
 // GetItemsByMultipleTags returns items that have all the specified tags
 func (s *TagService) GetItemsByMultipleTags(tags []string) ([]*models.Item, error) {
 	if len(tags) == 0 {
@@ -260,24 +318,90 @@ func (s *TagService) SearchTags(prefix string) ([]string, error) {
 	return matchingTags, nil
 }
 
+// Rebuild recreates the tag index from the hashtags in every item's content.
+// Metadata whose tags disagree with the content is updated, keeping its modification time.
+func (s *TagService) Rebuild() error {
+	unlock := s.repo.lock()
+	defer unlock()
+
+	// Start from the files on disk
+	s.repo.items.reset()
+	s.cache.reset()
+
+	index := make(map[string][]string)
+	for _, itemType := range s.repo.ItemTypes() {
+		items, err := s.repo.ListItems(itemType)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if item.Encrypted {
+				continue
+			}
+			_, content, err := s.repo.LoadItem(item.ID, item.Type)
+			if err != nil {
+				return err
+			}
+
+			tags := s.ExtractTags(content)
+			if tags == nil {
+				tags = []string{}
+			}
+			if !sameTags(item.Tags, tags) {
+				item.Tags = tags
+				if err := s.repo.writeMetadata(item); err != nil {
+					return err
+				}
+			}
+
+			for _, tag := range tags {
+				index[tag] = append(index[tag], ItemRef(item))
+			}
+		}
+	}
+
+	// Write the new index before removing tags that are gone, so the index is never empty
+	for tag, refs := range index {
+		sort.Strings(refs)
+		if err := s.saveTagFile(tag, refs); err != nil {
+			return err
+		}
+	}
+
+	existing, err := s.GetAllTags()
+	if err != nil {
+		return err
+	}
+	for _, tag := range existing {
+		if _, ok := index[tag]; ok {
+			continue
+		}
+		if err := s.removeFile(s.tagPath(tag)); err != nil {
+			return fmt.Errorf("failed to delete tag file: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // Private helper methods
 
 // getItemIDsByTag returns all item IDs for a specific tag
 func (s *TagService) getItemIDsByTag(tag string) ([]string, error) {
-	s.cacheLock.RLock()
-	if cachedIDs, found := s.tagCache[tag]; found {
-		s.cacheLock.RUnlock()
-		return cachedIDs, nil
-	}
-	s.cacheLock.RUnlock()
-
 	// Path to the tag file
-	tagPath := filepath.Join(s.repo.BasePath(), ".meta", "tags", tag+".json")
+	tagPath := s.tagPath(tag)
 
 	// Check if the file exists
-	if _, err := os.Stat(tagPath); os.IsNotExist(err) {
+	info, err := os.Stat(tagPath)
+	if os.IsNotExist(err) {
 		return []string{}, nil
 	}
+	if err == nil {
+		if cachedIDs, found := s.cache.get(tag, info); found {
+			return cachedIDs, nil
+		}
+	}
 
 	// Read the tag file
 	data, err := os.ReadFile(tagPath)
@@ -292,9 +416,9 @@ func (s *TagService) getItemIDsByTag(tag string) ([]string, error) {
 	}
 
 	// Update cache
-	s.cacheLock.Lock()
-	s.tagCache[tag] = itemIDs
-	s.cacheLock.Unlock()
+	if info != nil {
+		s.cache.set(tag, itemIDs, info)
+	}
 
 	return itemIDs, nil
 }
@@ -342,15 +466,12 @@ func (s *TagService) removeItemFromTag(itemID, tag string) error {
 
 	// If no items left, delete the tag file
 	if len(newItemIDs) == 0 {
-		tagPath := filepath.Join(s.repo.BasePath(), ".meta", "tags", tag+".json")
-		if err := os.Remove(tagPath); err != nil && !os.IsNotExist(err) {
+		if err := s.removeFile(s.tagPath(tag)); err != nil {
 			return fmt.Errorf("failed to delete empty tag file: %w", err)
 		}
 
 		// Clear from cache too
-		s.cacheLock.Lock()
-		delete(s.tagCache, tag)
-		s.cacheLock.Unlock()
+		s.cache.remove(tag)
 
 		return nil
 	}
@@ -362,8 +483,11 @@ func (s *TagService) removeItemFromTag(itemID, tag string) error {
 // saveTagFile saves a list of item IDs to a tag file
 func (s *TagService) saveTagFile(tag string, itemIDs []string) error {
 	// Path to the tag file
+	if err := ValidateTag(tag); err != nil {
+		return err
+	}
 	tagsDir := filepath.Join(s.repo.BasePath(), ".meta", "tags")
-	tagPath := filepath.Join(tagsDir, tag+".json")
+	tagPath := s.tagPath(tag)
 
 	// Ensure directory exists
 	if err := os.MkdirAll(tagsDir, 0755); err != nil {
@@ -377,18 +501,42 @@ func (s *TagService) saveTagFile(tag string, itemIDs []string) error {
 	}
 
 	// Write to file
-	if err := os.WriteFile(tagPath, data, 0644); err != nil {
+	if err := s.writeFile(tagPath, data); err != nil {
 		return fmt.Errorf("failed to write tag file: %w", err)
 	}
 
 	// Update cache
-	s.cacheLock.Lock()
-	s.tagCache[tag] = itemIDs
-	s.cacheLock.Unlock()
+	if info, err := os.Stat(tagPath); err == nil {
+		s.cache.set(tag, itemIDs, info)
+	}
 
 	return nil
 }
 
+// tagPath returns the path of a tag's index file
+func (s *TagService) tagPath(tag string) string {
+	return filepath.Join(s.repo.BasePath(), ".meta", "tags", tagFileName(tag)+".json")
+}
+
+// writeFile atomically writes a tag file, through the batch if there is one
+func (s *TagService) writeFile(path string, data []byte) error {
+	if s.batch != nil {
+		return s.batch.write(path, data, 0644)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// removeFile deletes a tag file, through the batch if there is one
+func (s *TagService) removeFile(path string) error {
+	if s.batch != nil {
+		return s.batch.remove(path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // Helper function to check if a slice contains a string
 func contains(slice []string, str string) bool {
 	for _, s := range slice {
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"vovere/internal/app/models"
+)
+
+var (
+	// ErrNotInTrash is returned when a trashed item doesn't exist
+	ErrNotInTrash = errors.New("item not found in trash")
+	// ErrItemExists is returned when restoring would overwrite an existing item
+	ErrItemExists = errors.New("item already exists")
+)
+
+// TrashEntry describes an item in the trash and everything needed to put it back
+type TrashEntry struct {
+	Item    *models.Item `json:"item"`
+	Deleted time.Time    `json:"deleted"`
+	// Tags are the tags whose index listed the item when it was trashed
+	Tags []string `json:"tags"`
+	// Workstreams are the IDs of the workstreams the item belonged to
+	Workstreams []string `json:"workstreams"`
+	// Backlinks are the combined "id:type" references of items linking to it
+	Backlinks []string `json:"backlinks"`
+}
+
+// TrashService moves deleted items to .meta/trash/{type}s/{id}/ so they can be restored.
+// Each entry holds entry.json, the content as content.md and the item's revisions under history/.
+type TrashService struct {
+	repo *Repository
+}
+
+// NewTrashService creates a new trash service
+func NewTrashService(repo *Repository) *TrashService {
+	return &TrashService{
+		repo: repo,
+	}
+}
+
+// Trash moves an item to the trash, removing it from the tag index, link index and workstreams
+func (s *TrashService) Trash(item *models.Item) error {
+	_, content, err := s.repo.LoadItem(item.ID, item.Type)
+	if err != nil {
+		return err
+	}
+
+	entry := TrashEntry{
+		Item:    item,
+		Deleted: time.Now().UTC(),
+	}
+
+	// Encrypted items are trashed as stored, sealed and without a sealed title or tags
+	if item.Encrypted {
+		data, err := os.ReadFile(s.repo.getContentPath(item))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read content file: %w", err)
+		}
+		content = string(data)
+		entry.Item = storedMetadata(item)
+	}
+
+	// Record memberships before they are removed
+	tagService := NewTagService(s.repo)
+	entry.Tags, err = tagService.GetTagsForItem(item)
+	if err != nil {
+		return err
+	}
+	for _, tag := range entry.Item.Tags {
+		if !contains(entry.Tags, tag) {
+			entry.Tags = append(entry.Tags, tag)
+		}
+	}
+
+	backlinks, err := NewLinkService(s.repo).Backlinks(item)
+	if err != nil {
+		return err
+	}
+	for _, backlink := range backlinks {
+		entry.Backlinks = append(entry.Backlinks, backlink.Source)
+	}
+
+	workstreams, err := s.repo.ListItems(models.TypeWorkstream)
+	if err != nil {
+		return err
+	}
+	ref := ItemRef(item)
+	for _, workstream := range workstreams {
+		if contains(workstream.Items, ref) {
+			entry.Workstreams = append(entry.Workstreams, workstream.ID)
+		}
+	}
+
+	// Write the entry first so a failure never loses the item
+	dir := s.entryDir(item.Type, item.ID)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear trash entry: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "content.md"), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write trashed content: %w", err)
+	}
+	if err := s.writeEntry(dir, &entry); err != nil {
+		return err
+	}
+
+	history := NewHistoryService(s.repo)
+	if err := history.moveDir(history.historyDir(item), filepath.Join(dir, "history")); err != nil {
+		return err
+	}
+	if err := history.moveDir(s.repo.fileDir(item), filepath.Join(dir, "file")); err != nil {
+		return err
+	}
+
+	// Remove the item from workstreams
+	for _, workstream := range workstreams {
+		if !contains(workstream.Items, ref) {
+			continue
+		}
+		workstream.Items = removeString(workstream.Items, ref)
+		if err := s.repo.SaveItem(workstream, ""); err != nil {
+			return err
+		}
+	}
+
+	// Delete the item and its index entries
+	if err := s.repo.DeleteItem(item); err != nil {
+		return err
+	}
+	removed := *item
+	removed.Tags = []string{}
+	if err := tagService.UpdateItemTags(&removed, entry.Tags); err != nil {
+		return fmt.Errorf("failed to update tag relationships: %w", err)
+	}
+
+	// Trashing is a good moment to drop expired entries
+	_, err = s.PurgeExpired()
+	return err
+}
+
+// List returns the items in the trash, most recently deleted first.
+// Expired entries are purged before listing.
+func (s *TrashService) List() ([]*TrashEntry, error) {
+	if _, err := s.PurgeExpired(); err != nil {
+		return nil, err
+	}
+	return s.entries()
+}
+
+// Restore puts a trashed item back with its content, history, tags, workstreams and backlinks
+func (s *TrashService) Restore(itemType models.ItemType, id string) (*models.Item, error) {
+	if err := s.repo.ValidateItemRef(id, itemType); err != nil {
+		return nil, err
+	}
+	dir := s.entryDir(itemType, id)
+	entry, err := s.readEntry(dir)
+	if err != nil {
+		return nil, err
+	}
+	item := entry.Item
+
+	if _, err := os.Stat(s.repo.getMetaPath(item)); err == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrItemExists, itemType, id)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "content.md"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read trashed content: %w", err)
+	}
+
+	// Put the history back first so restoring doesn't start a new one
+	history := NewHistoryService(s.repo)
+	if err := history.moveDir(filepath.Join(dir, "history"), history.historyDir(item)); err != nil {
+		return nil, err
+	}
+	if err := history.moveDir(filepath.Join(dir, "file"), s.repo.fileDir(item)); err != nil {
+		return nil, err
+	}
+
+	// Saving with the former tags re-adds the item to every tag it belonged to
+	item.Tags = entry.Tags
+	if err := s.repo.SaveItem(item, string(content)); err != nil {
+		return nil, err
+	}
+
+	ref := ItemRef(item)
+	for _, workstreamID := range entry.Workstreams {
+		workstream, _, err := s.repo.LoadItem(workstreamID, models.TypeWorkstream)
+		if err != nil || contains(workstream.Items, ref) {
+			continue // Workstream was deleted meanwhile
+		}
+		workstream.Items = append(workstream.Items, ref)
+		if err := s.repo.SaveItem(workstream, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// Re-index linking items so their backlinks come back
+	linkService := NewLinkService(s.repo)
+	for _, source := range entry.Backlinks {
+		sourceID, sourceType, ok := parseItemRef(source)
+		if !ok {
+			continue
+		}
+		sourceItem, sourceContent, err := s.repo.LoadItem(sourceID, sourceType)
+		if err != nil {
+			continue
+		}
+		if err := linkService.UpdateItemLinks(sourceItem, sourceContent); err != nil {
+			return nil, fmt.Errorf("failed to update link relationships: %w", err)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to remove trash entry: %w", err)
+	}
+
+	return item, nil
+}
+
+// Purge permanently deletes a trashed item
+func (s *TrashService) Purge(itemType models.ItemType, id string) error {
+	if err := s.repo.ValidateItemRef(id, itemType); err != nil {
+		return err
+	}
+	dir := s.entryDir(itemType, id)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s %s", ErrNotInTrash, itemType, id)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to purge trash entry: %w", err)
+	}
+	return nil
+}
+
+// Empty permanently deletes every trashed item
+func (s *TrashService) Empty() error {
+	if err := os.RemoveAll(s.trashDir()); err != nil {
+		return fmt.Errorf("failed to empty trash: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired deletes entries older than the retention period from config.json
+// and returns how many were purged
+func (s *TrashService) PurgeExpired() (int, error) {
+	config, err := s.repo.Config()
+	if err != nil {
+		return 0, err
+	}
+
+	retention := config.Trash.retention()
+	if retention == 0 {
+		return 0, nil
+	}
+
+	entries, err := s.entries()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().UTC().Add(-retention)
+	purged := 0
+	for _, entry := range entries {
+		if entry.Deleted.Before(cutoff) {
+			if err := s.Purge(entry.Item.Type, entry.Item.ID); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// entries reads every trash entry, most recently deleted first
+func (s *TrashService) entries() ([]*TrashEntry, error) {
+	var entries []*TrashEntry
+
+	for _, itemType := range s.repo.ItemTypes() {
+		typeDir := filepath.Join(s.trashDir(), string(itemType)+"s")
+		dirs, err := os.ReadDir(typeDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read trash directory: %w", err)
+		}
+
+		for _, dir := range dirs {
+			if !dir.IsDir() {
+				continue
+			}
+			entry, err := s.readEntry(filepath.Join(typeDir, dir.Name()))
+			if err != nil {
+				continue // Skip entries that can't be read
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Deleted.After(entries[j].Deleted)
+	})
+
+	return entries, nil
+}
+
+// readEntry loads the entry.json of a trash entry directory
+func (s *TrashService) readEntry(dir string) (*TrashEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "entry.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotInTrash, filepath.Base(dir))
+		}
+		return nil, fmt.Errorf("failed to read trash entry: %w", err)
+	}
+
+	var entry TrashEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse trash entry: %w", err)
+	}
+	if entry.Item == nil {
+		return nil, fmt.Errorf("trash entry in %s has no item", dir)
+	}
+	return &entry, nil
+}
+
+// writeEntry saves the entry.json of a trash entry directory
+func (s *TrashService) writeEntry(dir string, entry *TrashEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trash entry: %w", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "entry.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write trash entry: %w", err)
+	}
+	return nil
+}
+
+// entryDir returns the directory of a trashed item
+func (s *TrashService) entryDir(itemType models.ItemType, id string) string {
+	return filepath.Join(s.trashDir(), string(itemType)+"s", id)
+}
+
+// trashDir returns the root directory of the trash
+func (s *TrashService) trashDir() string {
+	return filepath.Join(s.repo.BasePath(), ".meta", "trash")
+}
+
+// removeString returns a copy of slice without any occurrence of str
+func removeString(slice []string, str string) []string {
+	result := make([]string, 0, len(slice))
+	for _, s := range slice {
+		if s != str {
+			result = append(result, s)
+		}
+	}
+	return result
+}
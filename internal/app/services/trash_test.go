@@ -0,0 +1,149 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	trashService := NewTrashService(repo)
+	tagService := NewTagService(repo)
+	linkService := NewLinkService(repo)
+
+	note := models.NewItem(models.TypeNote, "note1")
+	note.Title = "Target"
+	require.NoError(t, repo.UpdateContent(note, "First #keep"))
+	require.NoError(t, repo.UpdateContent(note, "Second #keep"))
+
+	source := models.NewItem(models.TypeNote, "note2")
+	require.NoError(t, repo.SaveItem(source, "See [[note1]]."))
+
+	workstream := models.NewItem(models.TypeWorkstream, "ws1")
+	workstream.Items = []string{"note1:note"}
+	require.NoError(t, repo.SaveItem(workstream, ""))
+
+	require.NoError(t, trashService.Trash(note))
+
+	// The item is gone from the repository and every index
+	_, _, err := repo.LoadItem("note1", models.TypeNote)
+	assert.Error(t, err)
+	items, err := tagService.GetItemsByTag("keep")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	backlinks, err := linkService.Backlinks(note)
+	require.NoError(t, err)
+	assert.Empty(t, backlinks)
+	workstream, _, err = repo.LoadItem("ws1", models.TypeWorkstream)
+	require.NoError(t, err)
+	assert.Empty(t, workstream.Items)
+	assert.NoDirExists(t, filepath.Join(tempDir, ".meta", "history", "note", "note1"))
+
+	entries, err := trashService.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Target", entries[0].Item.Title)
+	assert.Equal(t, []string{"keep"}, entries[0].Tags)
+	assert.Equal(t, []string{"ws1"}, entries[0].Workstreams)
+	assert.Equal(t, []string{"note2:note"}, entries[0].Backlinks)
+
+	restored, err := trashService.Restore(models.TypeNote, "note1")
+	require.NoError(t, err)
+	assert.Equal(t, "Target", restored.Title)
+
+	// Content, tags, workstreams, backlinks and history are back
+	_, content, err := repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Second #keep", content)
+	items, err = tagService.GetItemsByTag("keep")
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	workstream, _, err = repo.LoadItem("ws1", models.TypeWorkstream)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"note1:note"}, workstream.Items)
+	backlinks, err = linkService.Backlinks(restored)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, "note2:note", backlinks[0].Source)
+	revisions, err := NewHistoryService(repo).Revisions(restored)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	entries, err = trashService.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = trashService.Restore(models.TypeNote, "note1")
+	assert.ErrorIs(t, err, ErrNotInTrash)
+}
+
+func TestTrashRestoreConflict(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	trashService := NewTrashService(repo)
+
+	note := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(note, "Original"))
+	require.NoError(t, trashService.Trash(note))
+
+	// A new item took the ID meanwhile
+	replacement := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(replacement, "Replacement"))
+
+	_, err := trashService.Restore(models.TypeNote, "note1")
+	assert.ErrorIs(t, err, ErrItemExists)
+
+	_, content, err := repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Replacement", content)
+}
+
+func TestTrashPurge(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	require.NoError(t, repo.SaveConfig(&RepositoryConfig{
+		Name:  "test",
+		Trash: TrashConfig{RetentionDays: 7},
+	}))
+
+	trashService := NewTrashService(repo)
+
+	for _, id := range []string{"note1", "note2", "note3"} {
+		item := models.NewItem(models.TypeNote, id)
+		require.NoError(t, repo.SaveItem(item, "Content of "+id))
+		require.NoError(t, trashService.Trash(item))
+	}
+
+	require.NoError(t, trashService.Purge(models.TypeNote, "note1"))
+	assert.ErrorIs(t, trashService.Purge(models.TypeNote, "note1"), ErrNotInTrash)
+
+	// Backdate an entry past the retention period
+	dir := filepath.Join(tempDir, ".meta", "trash", "notes", "note2")
+	entry, err := trashService.readEntry(dir)
+	require.NoError(t, err)
+	entry.Deleted = time.Now().UTC().AddDate(0, 0, -8)
+	require.NoError(t, trashService.writeEntry(dir, entry))
+
+	entries, err := trashService.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "note3", entries[0].Item.ID)
+
+	require.NoError(t, trashService.Empty())
+	entries, err = trashService.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	_, err = os.Stat(filepath.Join(tempDir, ".meta", "trash"))
+	assert.True(t, os.IsNotExist(err))
+}
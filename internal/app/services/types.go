@@ -0,0 +1,378 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vovere/internal/app/models"
+)
+
+// Field types of FieldConfig
+const (
+	FieldText   = "text"
+	FieldNumber = "number"
+	FieldDate   = "date"
+	FieldURL    = "url"
+	FieldBool   = "bool"
+	FieldSelect = "select"
+)
+
+// ErrInvalidField is returned for a field value that doesn't fit the field's type
+var ErrInvalidField = errors.New("invalid field value")
+
+// defaultTypeColor is the badge color of types that don't set one
+const defaultTypeColor = "indigo"
+
+// typeNamePattern is the grammar of item type names, which become directory names and URLs
+var typeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
+// fieldNamePattern is the grammar of field names, which become front matter keys
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// reservedTypeNames would clash with the /tags and /items pages or the .meta/tags and .meta/links indexes
+var reservedTypeNames = []string{"tag", "link", "item"}
+
+// reservedFieldNames are metadata that fields can't take over
+var reservedFieldNames = []string{"id", "type", "title", "tags", "created", "modified", "items"}
+
+// typeColors are the Tailwind colors types can use, with their 500 shade for pages that draw outside Tailwind
+var typeColors = map[string]string{
+	"slate": "#64748b", "gray": "#6b7280", "zinc": "#71717a", "neutral": "#737373", "stone": "#78716c",
+	"red": "#ef4444", "orange": "#f97316", "amber": "#f59e0b", "yellow": "#eab308", "lime": "#84cc16",
+	"green": "#22c55e", "emerald": "#10b981", "teal": "#14b8a6", "cyan": "#06b6d4", "sky": "#0ea5e9",
+	"blue": "#3b82f6", "indigo": "#6366f1", "violet": "#8b5cf6", "purple": "#a855f7", "fuchsia": "#d946ef",
+	"pink": "#ec4899", "rose": "#f43f5e",
+}
+
+// builtinTypes returns the types every repository has, before the types of config.json
+func builtinTypes() []TypeConfig {
+	return []TypeConfig{
+		{Name: models.TypeNote, Label: "Note", Plural: "Notes", Icon: "📝", Color: "blue"},
+		{Name: models.TypeBookmark, Label: "Bookmark", Plural: "Bookmarks", Icon: "🔖", Color: "purple", Hidden: true,
+			Fields: []FieldConfig{{Name: "url", Label: "URL", Type: FieldURL}}},
+		{Name: models.TypeTask, Label: "Task", Plural: "Tasks", Icon: "✅", Color: "green", Hidden: true,
+			Fields: []FieldConfig{{Name: "status", Label: "Status", Type: FieldSelect, Options: []string{string(models.TaskStatusTodo), string(models.TaskStatusDone)}}}},
+		{Name: models.TypeWorkstream, Label: "Workstream", Plural: "Workstreams", Icon: "🧭", Color: "yellow", Hidden: true},
+		{Name: models.TypeFile, Label: "File", Plural: "Files", Icon: "📎", Color: "gray",
+			Fields: []FieldConfig{{Name: "filename", Label: "Filename", Type: FieldText, ReadOnly: true}}},
+	}
+}
+
+// typeRegistry holds the types of a repository until config.json changes
+type typeRegistry struct {
+	mu      sync.Mutex
+	loaded  bool
+	modTime time.Time
+	size    int64
+	types   []TypeConfig
+}
+
+// reset makes the next lookup read config.json again
+func (c *typeRegistry) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded = false
+}
+
+// Types returns the built-in item types followed by the types declared in config.json.
+// Declarations that aren't valid are logged and left out. The result must not be changed.
+func (r *Repository) Types() []TypeConfig {
+	var modTime time.Time
+	var size int64
+	if info, err := os.Stat(r.configPath()); err == nil {
+		modTime, size = info.ModTime(), info.Size()
+	}
+
+	r.types.mu.Lock()
+	defer r.types.mu.Unlock()
+
+	if r.types.loaded && r.types.modTime.Equal(modTime) && r.types.size == size {
+		return r.types.types
+	}
+
+	types := builtinTypes()
+	config, err := r.Config()
+	if err != nil {
+		log.Printf("Error reading item types of %s: %v", r.basePath, err)
+	} else {
+		for _, declared := range config.Types {
+			def, err := declared.definition()
+			if err == nil && findType(types, def.Name) != nil {
+				err = fmt.Errorf("type %q is declared twice", def.Name)
+			}
+			if err != nil {
+				log.Printf("Ignoring item type of %s: %v", r.basePath, err)
+				continue
+			}
+			types = append(types, def)
+		}
+	}
+
+	r.types.loaded = true
+	r.types.modTime = modTime
+	r.types.size = size
+	r.types.types = types
+	return types
+}
+
+// ItemTypes returns the names of the repository's item types, built-in ones first
+func (r *Repository) ItemTypes() []models.ItemType {
+	types := r.Types()
+	names := make([]models.ItemType, len(types))
+	for i, def := range types {
+		names[i] = def.Name
+	}
+	return names
+}
+
+// Type returns the definition of an item type, or ErrInvalidItemType if the repository doesn't have it
+func (r *Repository) Type(itemType models.ItemType) (TypeConfig, error) {
+	if def := findType(r.Types(), itemType); def != nil {
+		return *def, nil
+	}
+	return TypeConfig{}, fmt.Errorf("%w: %q", ErrInvalidItemType, itemType)
+}
+
+// ValidateItemType returns ErrInvalidItemType unless an item type is built in or declared in config.json
+func (r *Repository) ValidateItemType(itemType models.ItemType) error {
+	_, err := r.Type(itemType)
+	return err
+}
+
+// ValidateItemRef validates the type and ID that locate an item's files in the repository
+func (r *Repository) ValidateItemRef(id string, itemType models.ItemType) error {
+	if err := r.ValidateItemType(itemType); err != nil {
+		return err
+	}
+	return ValidateItemID(id)
+}
+
+// SetFields sets the values of an item's fields from their text form, as sent by the editor.
+// Fields missing from values are left alone, empty values clear the field.
+func (r *Repository) SetFields(item *models.Item, values map[string]string) error {
+	def, err := r.Type(item.Type)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range def.Fields {
+		text, ok := values[field.Name]
+		if !ok || field.ReadOnly {
+			continue
+		}
+		value, err := field.parse(text)
+		if err != nil {
+			return err
+		}
+		setFieldValue(item, field.Name, value)
+	}
+	return nil
+}
+
+// NewContent returns the content of a new item of the type
+func (t TypeConfig) NewContent(now time.Time) string {
+	return strings.NewReplacer(
+		"{{date}}", now.Format("2006-01-02"),
+		"{{time}}", now.Format("15:04"),
+	).Replace(t.Template)
+}
+
+// HexColor returns the type's color as a CSS hex value, such as the graph's node colors
+func (t TypeConfig) HexColor() string {
+	if hex, ok := typeColors[t.Color]; ok {
+		return hex
+	}
+	return typeColors[defaultTypeColor]
+}
+
+// FieldValue returns the value of an item's field as text, empty when it isn't set
+func FieldValue(item *models.Item, field FieldConfig) string {
+	switch value := fieldValue(item, field.Name).(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case time.Time:
+		return value.Format("2006-01-02")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// fieldValue returns the stored value of an item's field, nil when it isn't set
+func fieldValue(item *models.Item, name string) interface{} {
+	switch name {
+	case "url":
+		return nonEmpty(item.URL)
+	case "status":
+		return nonEmpty(string(item.Status))
+	case "filename":
+		return nonEmpty(item.Filename)
+	case "description":
+		return nonEmpty(item.Description)
+	}
+	return item.Properties[name]
+}
+
+// setFieldValue stores the value of an item's field, nil clears it
+func setFieldValue(item *models.Item, name string, value interface{}) {
+	text, _ := value.(string)
+	switch name {
+	case "url":
+		item.URL = text
+	case "status":
+		item.Status = models.TaskStatus(text)
+	case "filename":
+		item.Filename = text
+	case "description":
+		item.Description = text
+	default:
+		if value == nil {
+			delete(item.Properties, name)
+			return
+		}
+		if item.Properties == nil {
+			item.Properties = make(map[string]interface{})
+		}
+		item.Properties[name] = value
+	}
+}
+
+// nonEmpty returns nil for an empty string, so unset fields look the same wherever they are kept
+func nonEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// parse converts the text form of a field value, nil for an empty one
+func (f FieldConfig) parse(text string) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	switch f.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidField, f.Label)
+		}
+		return n, nil
+	case FieldDate:
+		if _, err := time.Parse("2006-01-02", text); err != nil {
+			return nil, fmt.Errorf("%w: %s must be a date like 2006-01-02", ErrInvalidField, f.Label)
+		}
+		return text, nil
+	case FieldURL:
+		u, err := url.Parse(text)
+		if err != nil || u.Scheme == "" {
+			return nil, fmt.Errorf("%w: %s must be an absolute URL", ErrInvalidField, f.Label)
+		}
+		return text, nil
+	case FieldBool:
+		// Checkboxes send "on"
+		if text == "on" {
+			return true, nil
+		}
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be true or false", ErrInvalidField, f.Label)
+		}
+		return b, nil
+	case FieldSelect:
+		for _, option := range f.Options {
+			if text == option {
+				return text, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s must be one of %s", ErrInvalidField, f.Label, strings.Join(f.Options, ", "))
+	default:
+		return text, nil
+	}
+}
+
+// definition checks a declared type and fills in its defaults
+func (t TypeConfig) definition() (TypeConfig, error) {
+	name := string(t.Name)
+	if len(name) > 32 || !typeNamePattern.MatchString(name) {
+		return t, fmt.Errorf("%w: %q", ErrInvalidItemType, name)
+	}
+	for _, reserved := range reservedTypeNames {
+		if name == reserved {
+			return t, fmt.Errorf("%w: %q is reserved", ErrInvalidItemType, name)
+		}
+	}
+
+	if t.Label == "" {
+		t.Label = displayName(name)
+	}
+	if t.Plural == "" {
+		t.Plural = t.Label + "s"
+	}
+	if t.Color == "" {
+		t.Color = defaultTypeColor
+	}
+	if _, ok := typeColors[t.Color]; !ok {
+		return t, fmt.Errorf("type %q: unknown color %q", name, t.Color)
+	}
+
+	fields := make([]FieldConfig, 0, len(t.Fields))
+	for _, field := range t.Fields {
+		if !fieldNamePattern.MatchString(field.Name) || contains(reservedFieldNames, field.Name) {
+			return t, fmt.Errorf("type %q: invalid field name %q", name, field.Name)
+		}
+		for _, other := range fields {
+			if other.Name == field.Name {
+				return t, fmt.Errorf("type %q: field %q is declared twice", name, field.Name)
+			}
+		}
+		if field.Label == "" {
+			field.Label = displayName(field.Name)
+		}
+		switch field.Type {
+		case "":
+			field.Type = FieldText
+		case FieldText, FieldNumber, FieldDate, FieldURL, FieldBool:
+		case FieldSelect:
+			if len(field.Options) == 0 {
+				return t, fmt.Errorf("type %q: select field %q has no options", name, field.Name)
+			}
+		default:
+			return t, fmt.Errorf("type %q: field %q has unknown type %q", name, field.Name, field.Type)
+		}
+		fields = append(fields, field)
+	}
+	t.Fields = fields
+
+	return t, nil
+}
+
+// displayName turns a name like "due-date" into "Due date"
+func displayName(name string) string {
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// findType returns the type with a name, or nil
+func findType(types []TypeConfig, itemType models.ItemType) *TypeConfig {
+	for i := range types {
+		if types[i].Name == itemType {
+			return &types[i]
+		}
+	}
+	return nil
+}
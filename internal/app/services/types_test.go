@@ -0,0 +1,150 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestDeclaredTypes(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// Before config.json declares anything only the built-in types are known
+	assert.Equal(t, models.ItemTypes, repo.ItemTypes())
+	assert.ErrorIs(t, repo.ValidateItemType("meeting"), ErrInvalidItemType)
+
+	config, err := repo.Config()
+	require.NoError(t, err)
+	config.Types = []TypeConfig{
+		{
+			Name:     "meeting",
+			Icon:     "🗓",
+			Color:    "emerald",
+			Template: "# Meeting {{date}}\n\n## Decisions\n",
+			Fields: []FieldConfig{
+				{Name: "date", Type: FieldDate},
+				{Name: "attendees"},
+				{Name: "decided", Type: FieldBool},
+				{Name: "duration", Label: "Minutes", Type: FieldNumber},
+				{Name: "kind", Type: FieldSelect, Options: []string{"standup", "review"}},
+			},
+		},
+		{Name: "decision-record", Plural: "Decision log"},
+		// Declarations that can't be used are left out
+		{Name: "note"},
+		{Name: "tag"},
+		{Name: "../escape"},
+		{Name: "recipe", Color: "chartreuse"},
+		{Name: "recipe-card", Fields: []FieldConfig{{Name: "title"}}},
+		{Name: "poll", Fields: []FieldConfig{{Name: "choice", Type: FieldSelect}}},
+	}
+	require.NoError(t, repo.SaveConfig(config))
+
+	assert.Equal(t, append(models.ItemTypes, "meeting", "decision-record"), repo.ItemTypes())
+
+	meeting, err := repo.Type("meeting")
+	require.NoError(t, err)
+	assert.Equal(t, "Meeting", meeting.Label)
+	assert.Equal(t, "Meetings", meeting.Plural)
+	assert.Equal(t, "Date", meeting.Fields[0].Label)
+	assert.Equal(t, FieldText, meeting.Fields[1].Type)
+	assert.Equal(t, "Minutes", meeting.Fields[3].Label)
+
+	record, err := repo.Type("decision-record")
+	require.NoError(t, err)
+	assert.Equal(t, "Decision record", record.Label)
+	assert.Equal(t, "Decision log", record.Plural)
+	assert.Equal(t, defaultTypeColor, record.Color)
+	assert.Equal(t, "#10b981", meeting.HexColor())
+	assert.Equal(t, "#6366f1", record.HexColor())
+
+	now := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	assert.Equal(t, "# Meeting 2024-03-05\n\n## Decisions\n", meeting.NewContent(now))
+
+	// Items of declared types are stored like any other
+	item := models.NewItem("meeting", "20240305093000")
+	require.NoError(t, repo.SaveItem(item, meeting.NewContent(now)))
+	assert.FileExists(t, filepath.Join(tempDir, "meetings", "20240305093000.md"))
+	assert.FileExists(t, filepath.Join(tempDir, ".meta", "meetings", "20240305093000.json"))
+
+	items, err := repo.ListItems("meeting")
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	for _, itemType := range []models.ItemType{"tag", "recipe", "recipe-card", "poll"} {
+		assert.ErrorIs(t, repo.SaveItem(models.NewItem(itemType, "item1"), "content"), ErrInvalidItemType, itemType)
+	}
+}
+
+func TestSetFields(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	config, err := repo.Config()
+	require.NoError(t, err)
+	config.Types = []TypeConfig{{
+		Name: "meeting",
+		Fields: []FieldConfig{
+			{Name: "date", Type: FieldDate},
+			{Name: "decided", Type: FieldBool},
+			{Name: "duration", Type: FieldNumber},
+			{Name: "kind", Type: FieldSelect, Options: []string{"standup", "review"}},
+			{Name: "url", Type: FieldURL},
+		},
+	}}
+	require.NoError(t, repo.SaveConfig(config))
+
+	item := models.NewItem("meeting", "meeting1")
+	require.NoError(t, repo.SetFields(item, map[string]string{
+		"date":     "2024-03-05",
+		"decided":  "on",
+		"duration": "45",
+		"kind":     "review",
+		"url":      "https://example.com/minutes",
+		"unknown":  "ignored",
+	}))
+	require.NoError(t, repo.SaveItem(item, "Minutes"))
+
+	loaded, _, err := repo.LoadItem("meeting1", "meeting")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"date": "2024-03-05", "decided": true, "duration": 45.0, "kind": "review"}, loaded.Properties)
+	assert.Equal(t, "https://example.com/minutes", loaded.URL)
+
+	meeting, err := repo.Type("meeting")
+	require.NoError(t, err)
+	assert.Equal(t, "45", FieldValue(loaded, meeting.Fields[2]))
+	assert.Equal(t, "true", FieldValue(loaded, meeting.Fields[1]))
+
+	// Empty values clear a field, missing ones leave it alone
+	require.NoError(t, repo.SetFields(loaded, map[string]string{"kind": "", "decided": "false"}))
+	assert.Equal(t, map[string]interface{}{"date": "2024-03-05", "decided": false, "duration": 45.0}, loaded.Properties)
+
+	invalid := []map[string]string{
+		{"date": "5 March"},
+		{"decided": "maybe"},
+		{"duration": "long"},
+		{"kind": "retro"},
+		{"url": "example.com"},
+	}
+	for _, values := range invalid {
+		assert.ErrorIs(t, repo.SetFields(loaded, values), ErrInvalidField, values)
+	}
+
+	// Built-in fields are kept in the metadata they always used
+	task := models.NewItem(models.TypeTask, "task1")
+	require.NoError(t, repo.SetFields(task, map[string]string{"status": "done"}))
+	assert.Equal(t, models.TaskStatusDone, task.Status)
+	assert.Nil(t, task.Properties)
+
+	// Read-only fields are never changed from a form
+	file := models.NewItem(models.TypeFile, "file1")
+	file.Filename = "report.pdf"
+	require.NoError(t, repo.SetFields(file, map[string]string{"filename": "../../config.json"}))
+	assert.Equal(t, "report.pdf", file.Filename)
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"vovere/internal/app/models"
+)
+
+var (
+	// ErrInvalidItemType is returned for an item type that isn't known
+	ErrInvalidItemType = errors.New("invalid item type")
+	// ErrInvalidItemID is returned for an item ID outside the ID grammar
+	ErrInvalidItemID = errors.New("invalid item ID")
+	// ErrInvalidTag is returned for a tag that can't be stored
+	ErrInvalidTag = errors.New("invalid tag")
+)
+
+// itemIDPattern is the grammar of item IDs: letters, digits, dashes and underscores, starting
+// with a letter or digit. Allocated IDs like 20240102150405-001 and hand-named ones both fit,
+// path separators, dots and the ":" of item references never do.
+var itemIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,127}$`)
+
+// maxTagLength keeps tag file names within what filesystems accept
+const maxTagLength = 200
+
+// ValidateItemType returns ErrInvalidItemType unless an item type is built in.
+// Repository.ValidateItemType also accepts the types declared in config.json.
+func ValidateItemType(itemType models.ItemType) error {
+	for _, known := range models.ItemTypes {
+		if itemType == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidItemType, itemType)
+}
+
+// ValidateItemID returns ErrInvalidItemID unless an ID follows the ID grammar
+func ValidateItemID(id string) error {
+	if !itemIDPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidItemID, id)
+	}
+	return nil
+}
+
+// ValidateItemRef validates the built-in type and ID that locate an item's files,
+// see Repository.ValidateItemRef for items of declared types
+func ValidateItemRef(id string, itemType models.ItemType) error {
+	if err := ValidateItemType(itemType); err != nil {
+		return err
+	}
+	return ValidateItemID(id)
+}
+
+// ValidateTag returns ErrInvalidTag for empty or overly long tags and tags with spaces
+// or control characters. Any other character is safe, see tagFileName.
+func ValidateTag(tag string) error {
+	if tag == "" || len(tag) > maxTagLength {
+		return fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+	}
+	for _, r := range tag {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+		}
+	}
+	return nil
+}
+
+// tagFileName returns the file name of a tag's index file, without extension. Characters that
+// would leave the tags directory, hide the file or clash with the encoding are percent-encoded;
+// other tags keep their plain name, as written by earlier versions.
+func tagFileName(tag string) string {
+	var name strings.Builder
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case c == '%', c == '/', c == '\\', c < 0x20, c == 0x7f, i == 0 && c == '.':
+			fmt.Fprintf(&name, "%%%02X", c)
+		default:
+			name.WriteByte(c)
+		}
+	}
+	return name.String()
+}
+
+// tagFromFileName returns the tag of an index file name without extension
+func tagFromFileName(name string) string {
+	if !strings.Contains(name, "%") {
+		return name
+	}
+	tag, err := url.PathUnescape(name)
+	if err != nil {
+		return name // A plain name from before tags were encoded
+	}
+	return tag
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestValidateItemRef(t *testing.T) {
+	valid := []string{"20240102150405", "20240102150405-001", "note1", "test_update"}
+	for _, id := range valid {
+		assert.NoError(t, ValidateItemRef(id, models.TypeNote), id)
+	}
+
+	invalid := []string{"", "..", "../config", "a/b", `a\b`, "a.b", "id:note", "-leading", "with space", string(make([]byte, 129))}
+	for _, id := range invalid {
+		assert.ErrorIs(t, ValidateItemRef(id, models.TypeNote), ErrInvalidItemID, id)
+	}
+
+	assert.ErrorIs(t, ValidateItemRef("note1", "foo"), ErrInvalidItemType)
+	assert.ErrorIs(t, ValidateItemRef("note1", "../note"), ErrInvalidItemType)
+}
+
+func TestInvalidItemsNeverReachTheDisk(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	assert.ErrorIs(t, repo.SaveItem(models.NewItem(models.TypeNote, "../escape"), "content"), ErrInvalidItemID)
+	assert.ErrorIs(t, repo.SaveItem(models.NewItem("foo", "note1"), "content"), ErrInvalidItemType)
+	_, err := repo.ListItems("foo")
+	assert.ErrorIs(t, err, ErrInvalidItemType)
+	_, _, err = repo.LoadItem("../config", models.TypeNote)
+	assert.ErrorIs(t, err, ErrInvalidItemID)
+
+	assert.NoFileExists(t, filepath.Join(tempDir, "escape.md"))
+	assert.NoDirExists(t, filepath.Join(tempDir, ".meta", "foos"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "foos"))
+}
+
+func TestTagFileNames(t *testing.T) {
+	tests := map[string]string{
+		"project":      "project",
+		"status:done":  "status:done",
+		"v1.2":         "v1.2",
+		"area/work":    "area%2Fwork",
+		"../../config": "%2E.%2F..%2Fconfig",
+		".hidden":      "%2Ehidden",
+		"100%":         "100%25",
+		`back\slash`:   "back%5Cslash",
+	}
+	for tag, name := range tests {
+		assert.Equal(t, name, tagFileName(tag), tag)
+		assert.Equal(t, tag, tagFromFileName(name), name)
+	}
+	// Plain names written before tags were encoded read as they are
+	assert.Equal(t, "50%off", tagFromFileName("50%off"))
+
+	assert.NoError(t, ValidateTag("área/work"))
+	for _, tag := range []string{"", "two words", "tab\there", "nul\x00"} {
+		assert.ErrorIs(t, ValidateTag(tag), ErrInvalidTag, tag)
+	}
+}
+
+func TestTagsStayInTheTagsDirectory(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	item := models.NewItem(models.TypeNote, "note1")
+	content := "---\ntags: [\"../../escape\", \"two words\"]\n---\nFiled under #area/work"
+	require.NoError(t, repo.SaveItem(item, content))
+
+	assert.ElementsMatch(t, []string{"area/work", "../../escape"}, item.Tags)
+	assert.NoFileExists(t, filepath.Join(tempDir, "escape.json"))
+	entries, err := os.ReadDir(filepath.Join(tempDir, ".meta", "tags"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	tags := NewTagService(repo)
+	all, err := tags.GetAllTags()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"area/work", "../../escape"}, all)
+	tagged, err := tags.GetItemsByTag("area/work")
+	require.NoError(t, err)
+	assert.Len(t, tagged, 1)
+
+	report, err := NewFsckService(repo).Check()
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues)
+}
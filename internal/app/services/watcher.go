@@ -0,0 +1,394 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"vovere/internal/app/models"
+)
+
+// ChangeOp is the kind of change the watcher found for a file
+type ChangeOp string
+
+const (
+	ChangeCreated  ChangeOp = "created"
+	ChangeModified ChangeOp = "modified"
+	ChangeRemoved  ChangeOp = "removed"
+)
+
+// Change is a content or metadata file that changed between two scans
+type Change struct {
+	Path string          `json:"path"`
+	Op   ChangeOp        `json:"op"`
+	Type models.ItemType `json:"type"`
+	ID   string          `json:"id"`
+}
+
+// fileState is what the watcher compares between scans
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// itemKey identifies the item a watched file belongs to
+type itemKey struct {
+	itemType models.ItemType
+	id       string
+}
+
+// WatcherService polls a repository for files changed outside Vovere and reconciles
+// the indexes with them. Content files without metadata are adopted as new items.
+type WatcherService struct {
+	repo     *Repository
+	interval time.Duration
+
+	mu       sync.Mutex
+	snapshot map[string]fileState
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWatcherService creates a watcher polling the repository every interval
+func NewWatcherService(repo *Repository, interval time.Duration) *WatcherService {
+	return &WatcherService{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+// Start polls the repository in the background until Stop is called.
+// The first scan reconciles changes made while Vovere wasn't running.
+func (s *WatcherService) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Scan(); err != nil {
+				log.Printf("Error watching repository %s: %v", s.repo.BasePath(), err)
+			}
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops polling and waits for a running scan to finish
+func (s *WatcherService) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+// Scan compares the repository with the previous scan and reconciles every changed item.
+// It returns the file changes that were found.
+func (s *WatcherService) Scan() ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.walk()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffSnapshots(s.snapshot, current)
+	if len(changes) == 0 {
+		s.snapshot = current
+		return nil, nil
+	}
+
+	// Group file changes by item, a save usually touches both files
+	byItem := make(map[itemKey][]Change)
+	var keys []itemKey
+	for _, change := range changes {
+		key := itemKey{change.Type, change.ID}
+		if _, ok := byItem[key]; !ok {
+			keys = append(keys, key)
+		}
+		byItem[key] = append(byItem[key], change)
+	}
+
+	indexedTags, err := s.indexedTags()
+	if err != nil {
+		return changes, err
+	}
+
+	// Items that fail to reconcile keep their previous state, so the next scan tries them again
+	previous := s.snapshot
+	s.snapshot = current
+	var errs []error
+	membershipChanged := false
+	for _, key := range keys {
+		changed, err := s.reconcile(key, byItem[key], indexedTags)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to reconcile %s %s: %w", key.itemType, key.id, err))
+			for _, change := range byItem[key] {
+				if state, ok := previous[change.Path]; ok {
+					s.snapshot[change.Path] = state
+				} else {
+					delete(s.snapshot, change.Path)
+				}
+			}
+			continue
+		}
+		membershipChanged = membershipChanged || changed
+
+		// Reconciling may not have saved the item, so read its metadata again
+		s.repo.refreshItem(key.itemType, key.id)
+
+		// Our own writes shouldn't show up as changes in the next scan
+		for _, path := range []string{s.repo.getContentPath(&models.Item{ID: key.id, Type: key.itemType}), s.repo.getMetaPath(&models.Item{ID: key.id, Type: key.itemType})} {
+			if info, err := os.Stat(path); err == nil {
+				s.snapshot[path] = fileState{info.ModTime(), info.Size()}
+			} else {
+				delete(s.snapshot, path)
+			}
+		}
+	}
+
+	// Wiki links resolve by title, so new and removed items affect other items' links
+	if membershipChanged {
+		if err := NewLinkService(s.repo).Rebuild(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+// reconcile brings the indexes in line with an item's files on disk.
+// It reports whether the item appeared or disappeared.
+func (s *WatcherService) reconcile(key itemKey, changes []Change, indexedTags map[string][]string) (bool, error) {
+	item := &models.Item{ID: key.id, Type: key.itemType}
+	_, metaErr := os.Stat(s.repo.getMetaPath(item))
+	_, contentErr := os.Stat(s.repo.getContentPath(item))
+	metaExists := metaErr == nil
+	contentExists := contentErr == nil
+
+	switch {
+	case !metaExists && contentExists:
+		return true, s.repo.adoptContent(key.itemType, key.id)
+
+	case !metaExists:
+		// Both files are gone, drop the item from every index
+		return true, s.repo.forgetItem(item, indexedTags[ItemRef(item)])
+
+	case !contentExists && contentRemoved(changes):
+		// The content was deleted by hand, keep the item recoverable
+		existing, _, err := s.repo.LoadItem(key.id, key.itemType)
+		if err != nil {
+			return false, err
+		}
+		return true, NewTrashService(s.repo).Trash(existing)
+
+	default:
+		return false, s.reindex(key, indexedTags[ItemRef(item)])
+	}
+}
+
+// reindex updates an item whose files changed, unless the change was made through
+// the repository and the indexes already agree with it
+func (s *WatcherService) reindex(key itemKey, indexedTags []string) error {
+	unlock := s.repo.lock()
+	defer unlock()
+
+	item, content, err := s.repo.LoadItem(key.id, key.itemType)
+	if err != nil {
+		return err
+	}
+	// Encrypted items have no hashtags, links or revisions to keep in line
+	if item.Encrypted {
+		return nil
+	}
+
+	history := NewHistoryService(s.repo)
+	revisions, err := history.readRevisions(item)
+	if err != nil {
+		return err
+	}
+
+	// Every save records a revision, so content differing from the latest one was edited by hand.
+	// Items saved before history existed have none, their indexes tell whether they are behind.
+	var previousContent string
+	contentChanged := false
+	if len(revisions) > 0 {
+		latest := revisions[len(revisions)-1]
+		contentChanged = latest.Hash != contentHash(content)
+		if contentChanged {
+			_, previousContent, _ = history.Revision(item, latest.ID)
+		}
+	}
+
+	if !contentChanged {
+		if sameTags(item.Tags, indexedTags) && !s.searchIndexStale(item) {
+			return nil
+		}
+		// Only the indexes are behind, the item itself and its modification time are left alone
+		return s.repo.reindexItem(item, content, indexedTags)
+	}
+
+	// Content is the source of tags, and of the title when it was taken from the content
+	item.Tags = NewTagService(s.repo).ExtractTags(content)
+	previousTitle := ContentTitle(previousContent, item.Type)
+	if item.Title == "" || item.Title == item.ID || item.Title == previousTitle {
+		if title := ContentTitle(content, item.Type); title != "" {
+			item.Title = title
+		}
+	}
+
+	return s.repo.saveItem(item, content, false, indexedTags)
+}
+
+// searchIndexStale reports whether the search index holds an older version of the item
+func (s *WatcherService) searchIndexStale(item *models.Item) bool {
+	doc, indexed := NewSearchService(s.repo).document(item)
+	if !indexed {
+		// Without an index there is nothing to update
+		return false
+	}
+	// A missing document is stale
+	return doc == nil || !doc.Modified.Equal(item.Modified)
+}
+
+// indexedTags maps each item reference to the tags whose index lists it
+func (s *WatcherService) indexedTags() (map[string][]string, error) {
+	tagService := NewTagService(s.repo)
+	tags, err := tagService.GetAllTags()
+	if err != nil {
+		return nil, err
+	}
+
+	indexed := make(map[string][]string)
+	for _, tag := range tags {
+		refs, err := tagService.getItemIDsByTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			indexed[ref] = append(indexed[ref], tag)
+		}
+	}
+	return indexed, nil
+}
+
+// walk collects the state of every content and metadata file in the repository
+func (s *WatcherService) walk() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+
+	for _, itemType := range s.repo.ItemTypes() {
+		dirs := []struct {
+			path string
+			ext  string
+		}{
+			{filepath.Join(s.repo.BasePath(), string(itemType)+"s"), ".md"},
+			{filepath.Join(s.repo.BasePath(), ".meta", string(itemType)+"s"), ".json"},
+		}
+
+		for _, dir := range dirs {
+			entries, err := os.ReadDir(dir.path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, fmt.Errorf("failed to read %s: %w", dir.path, err)
+			}
+
+			for _, entry := range entries {
+				name := entry.Name()
+				// Skip directories, other files and temporary files of atomic writes
+				if entry.IsDir() || filepath.Ext(name) != dir.ext || strings.HasPrefix(name, ".") {
+					continue
+				}
+				// Files named outside the ID grammar can't become items
+				if ValidateItemID(strings.TrimSuffix(name, dir.ext)) != nil {
+					continue
+				}
+				info, err := entry.Info()
+				if err != nil {
+					continue // Removed while reading
+				}
+				files[filepath.Join(dir.path, name)] = fileState{info.ModTime(), info.Size()}
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// diffSnapshots returns the changes between two scans, sorted by path
+func diffSnapshots(previous, current map[string]fileState) []Change {
+	var changes []Change
+	for path, state := range current {
+		before, ok := previous[path]
+		switch {
+		case !ok:
+			changes = append(changes, newChange(path, ChangeCreated))
+		case !before.modTime.Equal(state.modTime) || before.size != state.size:
+			changes = append(changes, newChange(path, ChangeModified))
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changes = append(changes, newChange(path, ChangeRemoved))
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// newChange describes a change to a content or metadata file, deriving the item from its path
+func newChange(path string, op ChangeOp) Change {
+	name := filepath.Base(path)
+	return Change{
+		Path: path,
+		Op:   op,
+		Type: models.ItemType(strings.TrimSuffix(filepath.Base(filepath.Dir(path)), "s")),
+		ID:   strings.TrimSuffix(name, filepath.Ext(name)),
+	}
+}
+
+// contentRemoved reports whether the changes include the removal of a content file
+func contentRemoved(changes []Change) bool {
+	for _, change := range changes {
+		if change.Op == ChangeRemoved && filepath.Ext(change.Path) == ".md" {
+			return true
+		}
+	}
+	return false
+}
+
+// sameTags reports whether two tag lists hold the same tags, ignoring order
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, tag := range a {
+		if !contains(b, tag) {
+			return false
+		}
+	}
+	return true
+}
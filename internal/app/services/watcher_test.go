@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vovere/internal/app/models"
+)
+
+func TestWatcherIgnoresOwnWrites(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	watcher := NewWatcherService(repo, 0)
+	_, err := watcher.Scan()
+	require.NoError(t, err)
+
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "# Note\n\nSaved through the app #app"))
+	modified := item.Modified
+
+	changes, err := watcher.Scan()
+	require.NoError(t, err)
+	assert.NotEmpty(t, changes)
+
+	// The indexes already agree, so the item isn't saved again
+	loaded, _, err := repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.True(t, modified.Equal(loaded.Modified))
+
+	changes, err = watcher.Scan()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestWatcherReconcilesExternalEdits(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	tagService := NewTagService(repo)
+
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "# Old title\n\nFirst version #old"))
+	item.Title = "Old title"
+	require.NoError(t, repo.SaveItem(item, ""))
+
+	watcher := NewWatcherService(repo, 0)
+	_, err := watcher.Scan()
+	require.NoError(t, err)
+
+	// Edit the content in another editor
+	contentPath := filepath.Join(tempDir, "notes", "note1.md")
+	require.NoError(t, os.WriteFile(contentPath, []byte("# New title\n\nEdited elsewhere #new"), 0644))
+
+	changes, err := watcher.Scan()
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, ChangeModified, changes[0].Op)
+	assert.Equal(t, "note1", changes[0].ID)
+
+	loaded, _, err := repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "New title", loaded.Title, "titles taken from the content follow it")
+	assert.Equal(t, []string{"new"}, loaded.Tags)
+	assert.True(t, loaded.Modified.After(item.Modified))
+
+	items, err := tagService.GetItemsByTag("old")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	items, err = tagService.GetItemsByTag("new")
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	// The edit is kept in the history
+	revisions, err := NewHistoryService(repo).Revisions(loaded)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	// Writing the metadata doesn't trigger another reconcile
+	changes, err = watcher.Scan()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestWatcherAdoptsOrphanContent(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	linking := models.NewItem(models.TypeNote, "linking")
+	require.NoError(t, repo.SaveItem(linking, "See [[orphan]]."))
+
+	watcher := NewWatcherService(repo, 0)
+	_, err := watcher.Scan()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "orphan.md"), []byte("# Found\n\nDropped in #synced"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "tasks", "todo.md"), []byte("Call back"), 0644))
+
+	_, err = watcher.Scan()
+	require.NoError(t, err)
+
+	orphan, content, err := repo.LoadItem("orphan", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Found", orphan.Title)
+	assert.Equal(t, []string{"synced"}, orphan.Tags)
+	assert.Equal(t, "# Found\n\nDropped in #synced", content)
+
+	task, _, err := repo.LoadItem("todo", models.TypeTask)
+	require.NoError(t, err)
+	assert.Equal(t, "Call back", task.Title)
+	assert.Equal(t, models.TaskStatusTodo, task.Status)
+
+	// Links written before the item existed now resolve to it
+	backlinks, err := NewLinkService(repo).Backlinks(orphan)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, "linking:note", backlinks[0].Source)
+}
+
+func TestWatcherHandlesDeletedFiles(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	tagService := NewTagService(repo)
+
+	kept := models.NewItem(models.TypeNote, "trashed")
+	require.NoError(t, repo.SaveItem(kept, "Deleted in an editor #gone"))
+	removed := models.NewItem(models.TypeNote, "removed")
+	require.NoError(t, repo.SaveItem(removed, "Deleted by a sync tool #gone"))
+
+	watcher := NewWatcherService(repo, 0)
+	_, err := watcher.Scan()
+	require.NoError(t, err)
+
+	// Deleting only the content moves the item to the trash
+	require.NoError(t, os.Remove(filepath.Join(tempDir, "notes", "trashed.md")))
+	// Deleting both files removes the item
+	require.NoError(t, os.Remove(filepath.Join(tempDir, "notes", "removed.md")))
+	require.NoError(t, os.Remove(filepath.Join(tempDir, ".meta", "notes", "removed.json")))
+
+	_, err = watcher.Scan()
+	require.NoError(t, err)
+
+	_, _, err = repo.LoadItem("trashed", models.TypeNote)
+	assert.Error(t, err)
+	entries, err := NewTrashService(repo).List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "trashed", entries[0].Item.ID)
+
+	items, err := tagService.GetItemsByTag("gone")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestWatcherKeepsItemsWithoutHistory(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	tagService := NewTagService(repo)
+
+	item := models.NewItem(models.TypeNote, "note1")
+	require.NoError(t, repo.SaveItem(item, "# Note\n\nWritten before history existed #legacy"))
+	modified := item.Modified
+
+	// The repository predates history, and its tag index is gone too
+	require.NoError(t, os.RemoveAll(filepath.Join(tempDir, ".meta", "history")))
+	require.NoError(t, os.RemoveAll(filepath.Join(tempDir, ".meta", "tags")))
+
+	watcher := NewWatcherService(repo, 0)
+	_, err := watcher.Scan()
+	require.NoError(t, err)
+
+	// The indexes are rebuilt without touching the item
+	loaded, _, err := repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.True(t, modified.Equal(loaded.Modified))
+	ids, err := tagService.getItemIDsByTag("legacy")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"note1:note"}, ids)
+
+	// Edits made elsewhere afterwards are picked up
+	contentPath := filepath.Join(tempDir, "notes", "note1.md")
+	require.NoError(t, os.WriteFile(contentPath, []byte("# Note\n\nEdited elsewhere #edited"), 0644))
+	_, err = watcher.Scan()
+	require.NoError(t, err)
+
+	loaded, _, err = repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"edited"}, loaded.Tags)
+}
+
+func TestWatcherLeavesItemsSavedMeanwhile(t *testing.T) {
+	_, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	item := models.NewItem(models.TypeNote, "note1")
+	item.Title = "Chosen title"
+	item.Tags = []string{"picked"}
+	require.NoError(t, repo.SaveItem(item, "# Heading\n\nBody #hashtag"))
+
+	// A scan that saw the files before the save finished finds them complete under the lock
+	require.NoError(t, repo.adoptContent(models.TypeNote, "note1"))
+	require.NoError(t, repo.forgetItem(&models.Item{ID: "note1", Type: models.TypeNote}, []string{"picked"}))
+
+	loaded, content, err := repo.LoadItem("note1", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, "Chosen title", loaded.Title)
+	assert.Equal(t, []string{"picked"}, loaded.Tags)
+	assert.Equal(t, "# Heading\n\nBody #hashtag", content)
+	ids, err := NewTagService(repo).getItemIDsByTag("picked")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"note1:note"}, ids)
+}
+
+func TestWatcherRetriesFailedItems(t *testing.T) {
+	tempDir, repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	broken := models.NewItem(models.TypeNote, "broken")
+	require.NoError(t, repo.SaveItem(broken, "First #one"))
+	fine := models.NewItem(models.TypeNote, "fine")
+	require.NoError(t, repo.SaveItem(fine, "First #one"))
+
+	watcher := NewWatcherService(repo, 0)
+	_, err := watcher.Scan()
+	require.NoError(t, err)
+
+	// One item's metadata can't be read while both items are edited elsewhere
+	metaPath := filepath.Join(tempDir, ".meta", "notes", "broken.json")
+	metadata, err := os.ReadFile(metaPath)
+	require.NoError(t, err)
+	info, err := os.Stat(metaPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(metaPath, bytes.Repeat([]byte("x"), len(metadata)), 0644))
+	require.NoError(t, os.Chtimes(metaPath, info.ModTime(), info.ModTime()))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "broken.md"), []byte("Second #two"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "fine.md"), []byte("Second #two"), 0644))
+
+	_, err = watcher.Scan()
+	assert.Error(t, err)
+	loaded, _, err := repo.LoadItem("fine", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two"}, loaded.Tags)
+
+	// Once the metadata is readable again the missed edit is picked up
+	require.NoError(t, os.WriteFile(metaPath, metadata, 0644))
+	require.NoError(t, os.Chtimes(metaPath, info.ModTime(), info.ModTime()))
+	_, err = watcher.Scan()
+	require.NoError(t, err)
+	loaded, _, err = repo.LoadItem("broken", models.TypeNote)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two"}, loaded.Tags)
+}
@@ -0,0 +1,66 @@
+package markdown
+
+import "strings"
+
+// frontMatterDelimiter opens and closes a YAML front matter block
+const frontMatterDelimiter = "---"
+
+// SplitFrontMatter separates a leading YAML front matter block, delimited by --- lines,
+// from the rest of the content. It returns the YAML without delimiters and the body.
+// Content without front matter is returned unchanged as the body.
+func SplitFrontMatter(content string) (frontMatter, body string, ok bool) {
+	rest, found := cutLine(content, frontMatterDelimiter)
+	if !found {
+		return "", content, false
+	}
+
+	// Find the closing delimiter on a line of its own
+	offset := 0
+	for offset <= len(rest) {
+		line := rest[offset:]
+		end := strings.IndexByte(line, '\n')
+		if end >= 0 {
+			line = line[:end]
+		}
+		if strings.TrimRight(line, " \t\r") == frontMatterDelimiter {
+			body := ""
+			if end >= 0 {
+				body = rest[offset+end+1:]
+			}
+			return rest[:offset], body, true
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+
+	return "", content, false
+}
+
+// StripFrontMatter returns the content without its YAML front matter
+func StripFrontMatter(content string) string {
+	_, body, _ := SplitFrontMatter(content)
+	return body
+}
+
+// JoinFrontMatter puts a YAML front matter block in front of a body.
+// Empty front matter returns the body alone.
+func JoinFrontMatter(frontMatter, body string) string {
+	if strings.TrimSpace(frontMatter) == "" {
+		return body
+	}
+	if !strings.HasSuffix(frontMatter, "\n") {
+		frontMatter += "\n"
+	}
+	return frontMatterDelimiter + "\n" + frontMatter + frontMatterDelimiter + "\n" + body
+}
+
+// cutLine removes a first line equal to delimiter, ignoring trailing spaces
+func cutLine(content, delimiter string) (string, bool) {
+	line, rest, found := strings.Cut(content, "\n")
+	if !found || strings.TrimRight(line, " \t\r") != delimiter {
+		return "", false
+	}
+	return rest, true
+}
@@ -0,0 +1,78 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		frontMatter string
+		body        string
+		ok          bool
+	}{
+		{
+			name:        "front matter",
+			content:     "---\ntitle: Hello\ntags: [a, b]\n---\n# Hello\n",
+			frontMatter: "title: Hello\ntags: [a, b]\n",
+			body:        "# Hello\n",
+			ok:          true,
+		},
+		{
+			name:        "empty front matter",
+			content:     "---\n---\nBody",
+			frontMatter: "",
+			body:        "Body",
+			ok:          true,
+		},
+		{
+			name:        "front matter without body",
+			content:     "---\ntitle: Only\n---",
+			frontMatter: "title: Only\n",
+			body:        "",
+			ok:          true,
+		},
+		{
+			name:    "no front matter",
+			content: "# Title\n\n---\n\nAfter a rule",
+			body:    "# Title\n\n---\n\nAfter a rule",
+		},
+		{
+			name:    "unclosed front matter",
+			content: "---\ntitle: Open\n",
+			body:    "---\ntitle: Open\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frontMatter, body, ok := SplitFrontMatter(tt.content)
+			if ok != tt.ok || frontMatter != tt.frontMatter || body != tt.body {
+				t.Errorf("SplitFrontMatter() = %q, %q, %v; want %q, %q, %v", frontMatter, body, ok, tt.frontMatter, tt.body, tt.ok)
+			}
+		})
+	}
+}
+
+func TestJoinFrontMatter(t *testing.T) {
+	content := JoinFrontMatter("title: Hello", "Body")
+	if content != "---\ntitle: Hello\n---\nBody" {
+		t.Errorf("JoinFrontMatter() = %q", content)
+	}
+	if JoinFrontMatter("", "Body") != "Body" {
+		t.Errorf("JoinFrontMatter() with empty front matter should return the body")
+	}
+}
+
+func TestRenderStripsFrontMatter(t *testing.T) {
+	html := Render("---\ntitle: Hidden\n---\n# Shown\n")
+	if strings.Contains(html, "Hidden") || !strings.Contains(html, "Shown") {
+		t.Errorf("Render() should skip the front matter, got %q", html)
+	}
+
+	if title := ExtractTitleFromContent("---\ntitle: Hidden\n---\nFirst line", "task"); title != "First line" {
+		t.Errorf("ExtractTitleFromContent() = %q, want %q", title, "First line")
+	}
+}
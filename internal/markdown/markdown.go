@@ -13,8 +13,14 @@ import (
 
 // defaultTransformers creates the default set of transformations
 func defaultTransformers() []Transformer {
+	return linkTransformers(nil)
+}
+
+// linkTransformers creates the transformations with wiki links resolved by resolver
+func linkTransformers(resolver LinkResolver) []Transformer {
 	return []Transformer{
-		NewHashtagTransformer(),
+		// Hashtags are handled around wiki links so both can share a text node
+		NewWikiLinkTransformer(resolver, NewHashtagTransformer()),
 	}
 }
 
@@ -36,14 +42,31 @@ func Render(md string) string {
 	return RenderWithOptions(md, DefaultRenderOptions())
 }
 
+// RenderWithResolver converts markdown to HTML, resolving wiki links with resolver
+func RenderWithResolver(md string, resolver LinkResolver) string {
+	return RenderWithOptions(md, RenderOptions{
+		Transformers: linkTransformers(resolver),
+	})
+}
+
+// RenderWithLinks converts markdown to HTML, resolving wiki links with resolver and
+// linking hashtags to tagURL, for pages served outside the app
+func RenderWithLinks(md string, resolver LinkResolver, tagURL func(tag string) string) string {
+	hashtags := NewHashtagTransformer()
+	hashtags.TagURL = tagURL
+	return RenderWithOptions(md, RenderOptions{
+		Transformers: []Transformer{NewWikiLinkTransformer(resolver, hashtags)},
+	})
+}
+
 // RenderWithOptions converts markdown to HTML using specified options
 func RenderWithOptions(md string, opts RenderOptions) string {
 	// Create markdown parser with extensions
 	extensions := parser.CommonExtensions | parser.AutoHeadingIDs
 	p := parser.NewWithExtensions(extensions)
 
-	// Parse the markdown document
-	doc := p.Parse([]byte(md))
+	// Parse the markdown document, front matter is metadata and isn't shown
+	doc := p.Parse([]byte(StripFrontMatter(md)))
 
 	// Set up custom HTML renderer with options
 	htmlFlags := html.CommonFlags | html.HrefTargetBlank
@@ -76,6 +99,7 @@ func RenderWithOptions(md string, opts RenderOptions) string {
 
 // ExtractTitleFromContent extracts the title from content
 func ExtractTitleFromContent(content string, itemType string) string {
+	content = StripFrontMatter(content)
 	if content == "" {
 		return ""
 	}
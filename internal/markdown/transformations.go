@@ -2,6 +2,7 @@ package markdown
 
 import (
 	"fmt"
+	"html"
 	"io"
 	"regexp"
 	"strings"
@@ -22,6 +23,8 @@ type Transformer interface {
 type HashtagTransformer struct {
 	// Regular expression for matching hashtags without trailing punctuation
 	TagRegex *regexp.Regexp
+	// TagURL returns the link of a tag, nil links to the tag pages of the app
+	TagURL func(tag string) string
 }
 
 // NewHashtagTransformer creates a new hashtag transformer
@@ -109,7 +112,7 @@ func (t *HashtagTransformer) Transform(w io.Writer, node ast.Node, text string)
 		tag := hashtag[1:] // Remove the # prefix
 
 		// Create the link for the hashtag
-		result.WriteString(fmt.Sprintf(`<a href="/tags/%s" class="tag-link">%s</a>`, tag, hashtag))
+		result.WriteString(fmt.Sprintf(`<a href="%s" class="tag-link">%s</a>`, t.tagURL(tag), hashtag))
 		transformed = true
 	}
 
@@ -121,6 +124,14 @@ func (t *HashtagTransformer) Transform(w io.Writer, node ast.Node, text string)
 	return false, ast.GoToNext
 }
 
+// tagURL returns the link of a tag
+func (t *HashtagTransformer) tagURL(tag string) string {
+	if t.TagURL != nil {
+		return html.EscapeString(t.TagURL(tag))
+	}
+	return "/tags/" + tag
+}
+
 // isPartOfUrlOrEmail checks if a hashtag at the given position is part of a URL or email
 func isPartOfUrlOrEmail(text string, position int) bool {
 	// Check if there's a @ or // or : before the hashtag without whitespace
@@ -138,3 +149,163 @@ func isPartOfUrlOrEmail(text string, position int) bool {
 	}
 	return false
 }
+
+// LinkResolver resolves wiki-link targets to items
+type LinkResolver interface {
+	// ResolveLink looks up an item by ID or title and returns its URL and title
+	ResolveLink(target string) (url string, title string, ok bool)
+}
+
+// WikiLink is a [[target|label]] link found in text
+type WikiLink struct {
+	// Target is the item ID or title being linked
+	Target string
+	// Label is the optional text after the pipe
+	Label string
+	// Start and End are the byte offsets of the link in the text, brackets included
+	Start int
+	End   int
+}
+
+// ParseWikiLinks finds all [[target]] and [[target|label]] links in text
+func ParseWikiLinks(text string) []WikiLink {
+	var links []WikiLink
+
+	i := 0
+	for {
+		open := strings.Index(text[i:], "[[")
+		if open == -1 {
+			break
+		}
+		start := i + open
+
+		end := strings.Index(text[start+2:], "]]")
+		if end == -1 {
+			break
+		}
+		inner := text[start+2 : start+2+end]
+
+		// A nested opening bracket means the first [[ wasn't a link
+		if nested := strings.LastIndex(inner, "[["); nested != -1 {
+			i = start + 2 + nested
+			continue
+		}
+
+		i = start + 2 + end + 2
+		if strings.Contains(inner, "\n") {
+			continue
+		}
+
+		target, label, _ := strings.Cut(inner, "|")
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+
+		links = append(links, WikiLink{
+			Target: target,
+			Label:  strings.TrimSpace(label),
+			Start:  start,
+			End:    i,
+		})
+	}
+
+	return links
+}
+
+// WikiLinkTransformer transforms [[id|title]] links into links to items
+type WikiLinkTransformer struct {
+	// Resolver looks up link targets, a nil resolver leaves every link unresolved
+	Resolver LinkResolver
+	// Fallback transformers are applied to the text around wiki links
+	Fallback []Transformer
+}
+
+// NewWikiLinkTransformer creates a new wiki-link transformer
+func NewWikiLinkTransformer(resolver LinkResolver, fallback ...Transformer) *WikiLinkTransformer {
+	return &WikiLinkTransformer{
+		Resolver: resolver,
+		Fallback: fallback,
+	}
+}
+
+// CanTransform determines if this transformer can handle the given node
+func (t *WikiLinkTransformer) CanTransform(node ast.Node) bool {
+	// Wiki links follow the same rules as hashtags: never inside code or links
+	parent := node.GetParent()
+	for parent != nil {
+		switch parent.(type) {
+		case *ast.CodeBlock, *ast.Code, *ast.Link:
+			return false
+		default:
+			parent = parent.GetParent()
+		}
+	}
+	return true
+}
+
+// Transform processes text to convert wiki links to item links
+func (t *WikiLinkTransformer) Transform(w io.Writer, node ast.Node, text string) (bool, ast.WalkStatus) {
+	links := ParseWikiLinks(text)
+	if len(links) == 0 {
+		return t.transformFallback(w, node, text)
+	}
+
+	var result strings.Builder
+	i := 0
+	for _, link := range links {
+		t.writeFallback(&result, node, text[i:link.Start])
+		result.WriteString(t.renderLink(link))
+		i = link.End
+	}
+	t.writeFallback(&result, node, text[i:])
+
+	io.WriteString(w, result.String())
+	return true, ast.GoToNext
+}
+
+// renderLink renders a single wiki link, marking it when the target doesn't exist
+func (t *WikiLinkTransformer) renderLink(link WikiLink) string {
+	label := link.Label
+
+	if t.Resolver != nil {
+		if url, title, ok := t.Resolver.ResolveLink(link.Target); ok {
+			if label == "" {
+				label = title
+			}
+			if label == "" {
+				label = link.Target
+			}
+			return fmt.Sprintf(`<a href="%s" class="wiki-link">%s</a>`,
+				html.EscapeString(url), html.EscapeString(label))
+		}
+	}
+
+	if label == "" {
+		label = link.Target
+	}
+	return fmt.Sprintf(`<span class="wiki-link wiki-link-unresolved" title="%s">%s</span>`,
+		html.EscapeString("No item found for "+link.Target), html.EscapeString(label))
+}
+
+// transformFallback hands the whole text to the first fallback transformer that handles it
+func (t *WikiLinkTransformer) transformFallback(w io.Writer, node ast.Node, text string) (bool, ast.WalkStatus) {
+	for _, transformer := range t.Fallback {
+		if transformer.CanTransform(node) {
+			if handled, status := transformer.Transform(w, node, text); handled {
+				return true, status
+			}
+		}
+	}
+	return false, ast.GoToNext
+}
+
+// writeFallback writes a text segment through the fallback transformers, escaping it if none handles it
+func (t *WikiLinkTransformer) writeFallback(w io.Writer, node ast.Node, text string) {
+	if text == "" {
+		return
+	}
+	if handled, _ := t.transformFallback(w, node, text); !handled {
+		io.WriteString(w, html.EscapeString(text))
+	}
+}
@@ -177,3 +177,145 @@ func TestPeriodAfterHashtag(t *testing.T) {
 		t.Errorf("Period handling failed.\nExpected: %s\nGot: %s", expected, result)
 	}
 }
+
+// TestParseWikiLinks tests finding wiki links in text
+func TestParseWikiLinks(t *testing.T) {
+	testCases := []struct {
+		input   string
+		targets []string
+		labels  []string
+	}{
+		{"See [[20240222095100|Note Title]] here", []string{"20240222095100"}, []string{"Note Title"}},
+		{"[[Title only]] and [[ spaced | label ]]", []string{"Title only", "spaced"}, []string{"", "label"}},
+		{"Nested [[open [[inner]] text", []string{"inner"}, []string{""}},
+		{"Empty [[]] and [[|label]]", nil, nil},
+		{"Unclosed [[link", nil, nil},
+		{"Single [brackets] only", nil, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			links := ParseWikiLinks(tc.input)
+			if len(links) != len(tc.targets) {
+				t.Fatalf("Expected %d links, got %d: %+v", len(tc.targets), len(links), links)
+			}
+			for i, link := range links {
+				if link.Target != tc.targets[i] || link.Label != tc.labels[i] {
+					t.Errorf("Expected %q|%q, got %q|%q", tc.targets[i], tc.labels[i], link.Target, link.Label)
+				}
+				if !strings.HasPrefix(tc.input[link.Start:link.End], "[[") || !strings.HasSuffix(tc.input[link.Start:link.End], "]]") {
+					t.Errorf("Offsets don't cover the link: %q", tc.input[link.Start:link.End])
+				}
+			}
+		})
+	}
+}
+
+// testResolver resolves a fixed set of targets
+type testResolver map[string][2]string
+
+func (r testResolver) ResolveLink(target string) (string, string, bool) {
+	item, ok := r[target]
+	return item[0], item[1], ok
+}
+
+// TestWikiLinkRendering tests wiki links through the full rendering process
+func TestWikiLinkRendering(t *testing.T) {
+	resolver := testResolver{
+		"20240222095100": {"/items/note/20240222095100", "Note Title"},
+		"Weekly plan":    {"/items/task/20240101000000", "Weekly plan"},
+	}
+
+	testCases := []struct {
+		name          string
+		markdown      string
+		expectedParts []string
+		notExpected   []string
+	}{
+		{
+			name:     "Link by ID uses the item title",
+			markdown: "See [[20240222095100]].",
+			expectedParts: []string{
+				`See <a href="/items/note/20240222095100" class="wiki-link">Note Title</a>.`,
+			},
+		},
+		{
+			name:     "Label overrides the title",
+			markdown: "See [[20240222095100|my note]]",
+			expectedParts: []string{
+				`<a href="/items/note/20240222095100" class="wiki-link">my note</a>`,
+			},
+		},
+		{
+			name:     "Link by title",
+			markdown: "Check the [[Weekly plan]] #planning",
+			expectedParts: []string{
+				`<a href="/items/task/20240101000000" class="wiki-link">Weekly plan</a>`,
+				`<a href="/tags/planning" class="tag-link">#planning</a>`,
+			},
+		},
+		{
+			name:     "Unresolved links are marked",
+			markdown: "Missing [[Tom & Jerry]]",
+			expectedParts: []string{
+				`Missing <span class="wiki-link wiki-link-unresolved" title="No item found for Tom &amp; Jerry">Tom &amp; Jerry</span>`,
+			},
+		},
+		{
+			name:     "Code is left alone",
+			markdown: "`[[20240222095100]]`",
+			expectedParts: []string{
+				`<code>[[20240222095100]]</code>`,
+			},
+			notExpected: []string{
+				`wiki-link`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := RenderWithResolver(tc.markdown, resolver)
+
+			for _, expected := range tc.expectedParts {
+				if !strings.Contains(result, expected) {
+					t.Errorf("Expected result to contain '%s' but it didn't.\nResult: %s",
+						expected, result)
+				}
+			}
+
+			for _, notExpected := range tc.notExpected {
+				if strings.Contains(result, notExpected) {
+					t.Errorf("Expected result NOT to contain '%s' but it did.\nResult: %s",
+						notExpected, result)
+				}
+			}
+		})
+	}
+
+	// Without a resolver every link is unresolved
+	result := Render("[[20240222095100]]")
+	if !strings.Contains(result, "wiki-link-unresolved") {
+		t.Errorf("Expected unresolved link without a resolver.\nResult: %s", result)
+	}
+}
+
+// TestRenderWithLinks tests rendering with custom tag links, as used by the site export
+func TestRenderWithLinks(t *testing.T) {
+	resolver := testResolver{
+		"20240222095100": {"../notes/20240222095100.html", "Note Title"},
+	}
+	tagURL := func(tag string) string {
+		return "../tags/" + tag + ".html"
+	}
+
+	result := RenderWithLinks("See [[20240222095100]] #public", resolver, tagURL)
+	for _, expected := range []string{
+		`<a href="../notes/20240222095100.html" class="wiki-link">Note Title</a>`,
+		`<a href="../tags/public.html" class="tag-link">#public</a>`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected result to contain '%s' but it didn't.\nResult: %s", expected, result)
+		}
+	}
+}